@@ -0,0 +1,9 @@
+#!/bin/sh
+# Creates the role services connect as on a fresh database. Privileges are
+# granted by the migrations; see 20250425090000_add_app_role.sql.
+set -e
+
+psql -v ON_ERROR_STOP=1 --username "$POSTGRES_USER" --dbname "$POSTGRES_DB" \
+    -v app_password="$APP_DB_PASSWORD" <<'EOSQL'
+CREATE ROLE vote_app LOGIN NOSUPERUSER NOBYPASSRLS PASSWORD :'app_password';
+EOSQL
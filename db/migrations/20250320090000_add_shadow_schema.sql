@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Copies of the tables written by the worker, used by workers running in
+-- shadow mode (SHADOW_MODE=true) to validate a release against live traffic.
+CREATE SCHEMA IF NOT EXISTS shadow;
+
+CREATE TABLE IF NOT EXISTS shadow.votes (LIKE public.votes INCLUDING ALL);
+CREATE TABLE IF NOT EXISTS shadow.totals_sharded (LIKE public.totals_sharded INCLUDING ALL);
+CREATE TABLE IF NOT EXISTS shadow.processed_events (LIKE public.processed_events INCLUDING ALL);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP SCHEMA IF EXISTS shadow CASCADE;
+-- +goose StatementEnd
@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Per-candidate vote counts bucketed by voted_at. resolution is 'minute' or
+-- 'hour'; bucket_start is the UTC start of the bucket.
+CREATE TABLE IF NOT EXISTS totals_timeseries (
+    resolution TEXT NOT NULL CHECK (resolution IN ('minute', 'hour')),
+    candidate_id BIGINT NOT NULL,
+    bucket_start TIMESTAMPTZ NOT NULL,
+    cnt BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (resolution, candidate_id, bucket_start)
+);
+
+CREATE INDEX IF NOT EXISTS totals_timeseries_bucket_idx ON totals_timeseries (resolution, bucket_start);
+
+CREATE TABLE IF NOT EXISTS shadow.totals_timeseries (LIKE public.totals_timeseries INCLUDING ALL);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS shadow.totals_timeseries;
+DROP TABLE IF EXISTS totals_timeseries;
+-- +goose StatementEnd
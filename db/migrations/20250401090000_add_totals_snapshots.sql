@@ -0,0 +1,42 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Immutable point-in-time copies of totals. reason is 'periodic' for
+-- snapshots taken by the worker on a timer, 'close' for election close and
+-- 'manual' for other on-demand snapshots.
+CREATE TABLE IF NOT EXISTS totals_snapshots (
+    id BIGSERIAL PRIMARY KEY,
+    taken_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    reason TEXT NOT NULL CHECK (reason IN ('periodic', 'close', 'manual')),
+    label TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS totals_snapshots_taken_at_idx ON totals_snapshots (taken_at);
+
+CREATE TABLE IF NOT EXISTS totals_snapshot_items (
+    snapshot_id BIGINT NOT NULL REFERENCES totals_snapshots (id),
+    candidate_id BIGINT NOT NULL,
+    count BIGINT NOT NULL,
+    PRIMARY KEY (snapshot_id, candidate_id)
+);
+
+CREATE OR REPLACE FUNCTION forbid_snapshot_mutation() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'totals snapshots are immutable';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER totals_snapshots_immutable
+    BEFORE UPDATE OR DELETE ON totals_snapshots
+    FOR EACH ROW EXECUTE FUNCTION forbid_snapshot_mutation();
+
+CREATE TRIGGER totals_snapshot_items_immutable
+    BEFORE UPDATE OR DELETE ON totals_snapshot_items
+    FOR EACH ROW EXECUTE FUNCTION forbid_snapshot_mutation();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS totals_snapshot_items;
+DROP TABLE IF EXISTS totals_snapshots;
+DROP FUNCTION IF EXISTS forbid_snapshot_mutation();
+-- +goose StatementEnd
@@ -0,0 +1,132 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Tenants are registered by the worker the first time one of their votes is
+-- applied. The table itself is not tenant scoped.
+CREATE TABLE IF NOT EXISTS tenants (
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+INSERT INTO tenants (id) VALUES ('default') ON CONFLICT DO NOTHING;
+
+ALTER TABLE votes ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE votes DROP CONSTRAINT IF EXISTS votes_unique_user_candidate;
+ALTER TABLE votes ADD CONSTRAINT votes_unique_tenant_user_candidate UNIQUE (tenant_id, user_id, candidate_id);
+
+ALTER TABLE totals_sharded ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE totals_sharded DROP CONSTRAINT IF EXISTS totals_sharded_pkey;
+ALTER TABLE totals_sharded ADD PRIMARY KEY (tenant_id, candidate_id, bucket);
+
+ALTER TABLE totals_timeseries ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE totals_timeseries DROP CONSTRAINT IF EXISTS totals_timeseries_pkey;
+ALTER TABLE totals_timeseries ADD PRIMARY KEY (tenant_id, resolution, candidate_id, bucket_start);
+
+ALTER TABLE totals_snapshot_items ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE totals_snapshot_items DROP CONSTRAINT IF EXISTS totals_snapshot_items_pkey;
+ALTER TABLE totals_snapshot_items ADD PRIMARY KEY (snapshot_id, tenant_id, candidate_id);
+
+DROP VIEW IF EXISTS totals;
+CREATE VIEW totals AS
+SELECT
+    tenant_id,
+    candidate_id,
+    SUM(cnt) AS count
+FROM totals_sharded
+GROUP BY tenant_id, candidate_id
+ORDER BY tenant_id, candidate_id;
+
+-- Row-level security: sessions only see rows of the tenant named by the
+-- app.tenant setting (set per transaction with set_config). Without it no
+-- rows are visible. FORCE applies the policies to the table owner as well;
+-- superusers and BYPASSRLS roles still bypass them, so services connect as
+-- the ordinary vote_app role (20250425090000_add_app_role.sql).
+ALTER TABLE votes ENABLE ROW LEVEL SECURITY;
+ALTER TABLE votes FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON votes
+    USING (tenant_id = current_setting('app.tenant', true))
+    WITH CHECK (tenant_id = current_setting('app.tenant', true));
+
+ALTER TABLE totals_sharded ENABLE ROW LEVEL SECURITY;
+ALTER TABLE totals_sharded FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON totals_sharded
+    USING (tenant_id = current_setting('app.tenant', true))
+    WITH CHECK (tenant_id = current_setting('app.tenant', true));
+
+ALTER TABLE totals_timeseries ENABLE ROW LEVEL SECURITY;
+ALTER TABLE totals_timeseries FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON totals_timeseries
+    USING (tenant_id = current_setting('app.tenant', true))
+    WITH CHECK (tenant_id = current_setting('app.tenant', true));
+
+ALTER TABLE totals_snapshot_items ENABLE ROW LEVEL SECURITY;
+ALTER TABLE totals_snapshot_items FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON totals_snapshot_items
+    USING (tenant_id = current_setting('app.tenant', true))
+    WITH CHECK (tenant_id = current_setting('app.tenant', true));
+
+-- Shadow copies follow the same shape; they are only written by shadow
+-- workers and are not tenant isolated.
+ALTER TABLE shadow.votes ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE shadow.votes DROP CONSTRAINT IF EXISTS votes_user_id_candidate_id_key;
+ALTER TABLE shadow.votes ADD CONSTRAINT votes_unique_tenant_user_candidate UNIQUE (tenant_id, user_id, candidate_id);
+ALTER TABLE shadow.totals_sharded ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE shadow.totals_sharded DROP CONSTRAINT IF EXISTS totals_sharded_pkey;
+ALTER TABLE shadow.totals_sharded ADD PRIMARY KEY (tenant_id, candidate_id, bucket);
+ALTER TABLE shadow.totals_timeseries ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE shadow.totals_timeseries DROP CONSTRAINT IF EXISTS totals_timeseries_pkey;
+ALTER TABLE shadow.totals_timeseries ADD PRIMARY KEY (tenant_id, resolution, candidate_id, bucket_start);
+CREATE TABLE IF NOT EXISTS shadow.tenants (LIKE public.tenants INCLUDING ALL);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS shadow.tenants;
+ALTER TABLE shadow.totals_timeseries DROP CONSTRAINT IF EXISTS totals_timeseries_pkey;
+ALTER TABLE shadow.totals_timeseries DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE shadow.totals_timeseries ADD PRIMARY KEY (resolution, candidate_id, bucket_start);
+ALTER TABLE shadow.totals_sharded DROP CONSTRAINT IF EXISTS totals_sharded_pkey;
+ALTER TABLE shadow.totals_sharded DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE shadow.totals_sharded ADD PRIMARY KEY (candidate_id, bucket);
+ALTER TABLE shadow.votes DROP CONSTRAINT IF EXISTS votes_unique_tenant_user_candidate;
+ALTER TABLE shadow.votes DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE shadow.votes ADD CONSTRAINT votes_user_id_candidate_id_key UNIQUE (user_id, candidate_id);
+
+DROP POLICY IF EXISTS tenant_isolation ON totals_snapshot_items;
+ALTER TABLE totals_snapshot_items NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE totals_snapshot_items DISABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON totals_timeseries;
+ALTER TABLE totals_timeseries NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE totals_timeseries DISABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON totals_sharded;
+ALTER TABLE totals_sharded NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE totals_sharded DISABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON votes;
+ALTER TABLE votes NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE votes DISABLE ROW LEVEL SECURITY;
+
+DROP VIEW IF EXISTS totals;
+CREATE VIEW totals AS
+SELECT
+    candidate_id,
+    SUM(cnt) AS count
+FROM totals_sharded
+GROUP BY candidate_id
+ORDER BY candidate_id;
+
+ALTER TABLE totals_snapshot_items DROP CONSTRAINT IF EXISTS totals_snapshot_items_pkey;
+ALTER TABLE totals_snapshot_items DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE totals_snapshot_items ADD PRIMARY KEY (snapshot_id, candidate_id);
+
+ALTER TABLE totals_timeseries DROP CONSTRAINT IF EXISTS totals_timeseries_pkey;
+ALTER TABLE totals_timeseries DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE totals_timeseries ADD PRIMARY KEY (resolution, candidate_id, bucket_start);
+
+ALTER TABLE totals_sharded DROP CONSTRAINT IF EXISTS totals_sharded_pkey;
+ALTER TABLE totals_sharded DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE totals_sharded ADD PRIMARY KEY (candidate_id, bucket);
+
+ALTER TABLE votes DROP CONSTRAINT IF EXISTS votes_unique_tenant_user_candidate;
+ALTER TABLE votes DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE votes ADD CONSTRAINT votes_unique_user_candidate UNIQUE (user_id, candidate_id);
+
+DROP TABLE IF EXISTS tenants;
+-- +goose StatementEnd
@@ -0,0 +1,89 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Votes and totals are kept per election. Rows written before elections were
+-- tracked belong to the "default" election, which is also what vote-api
+-- assigns to votes without an election_id.
+ALTER TABLE votes ADD COLUMN IF NOT EXISTS election_id TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE votes DROP CONSTRAINT IF EXISTS votes_unique_tenant_user_candidate;
+ALTER TABLE votes ADD CONSTRAINT votes_unique_tenant_election_user_candidate
+    UNIQUE (tenant_id, election_id, user_id, candidate_id);
+
+DROP VIEW IF EXISTS totals;
+
+ALTER TABLE totals_sharded ADD COLUMN IF NOT EXISTS election_id TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE totals_sharded DROP CONSTRAINT IF EXISTS totals_sharded_pkey;
+ALTER TABLE totals_sharded ADD PRIMARY KEY (tenant_id, election_id, candidate_id, bucket);
+
+CREATE VIEW totals AS
+SELECT
+    tenant_id,
+    election_id,
+    candidate_id,
+    SUM(cnt) AS count
+FROM totals_sharded
+GROUP BY tenant_id, election_id, candidate_id
+ORDER BY tenant_id, election_id, candidate_id;
+
+ALTER TABLE totals_snapshot_items ADD COLUMN IF NOT EXISTS election_id TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE totals_snapshot_items DROP CONSTRAINT IF EXISTS totals_snapshot_items_pkey;
+ALTER TABLE totals_snapshot_items ADD PRIMARY KEY (snapshot_id, tenant_id, election_id, candidate_id);
+
+-- Candidate metadata shown alongside totals. Candidates without a row are
+-- still reported, unnamed and after the named ones.
+CREATE TABLE IF NOT EXISTS candidates (
+    tenant_id TEXT NOT NULL,
+    election_id TEXT NOT NULL,
+    candidate_id BIGINT NOT NULL,
+    name TEXT NOT NULL,
+    display_order INT NOT NULL DEFAULT 0,
+    PRIMARY KEY (tenant_id, election_id, candidate_id)
+);
+ALTER TABLE candidates ENABLE ROW LEVEL SECURITY;
+ALTER TABLE candidates FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON candidates
+    USING (tenant_id = current_setting('app.tenant', true))
+    WITH CHECK (tenant_id = current_setting('app.tenant', true));
+
+ALTER TABLE shadow.votes ADD COLUMN IF NOT EXISTS election_id TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE shadow.votes DROP CONSTRAINT IF EXISTS votes_unique_tenant_user_candidate;
+ALTER TABLE shadow.votes ADD CONSTRAINT votes_unique_tenant_election_user_candidate
+    UNIQUE (tenant_id, election_id, user_id, candidate_id);
+ALTER TABLE shadow.totals_sharded ADD COLUMN IF NOT EXISTS election_id TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE shadow.totals_sharded DROP CONSTRAINT IF EXISTS totals_sharded_pkey;
+ALTER TABLE shadow.totals_sharded ADD PRIMARY KEY (tenant_id, election_id, candidate_id, bucket);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE shadow.totals_sharded DROP CONSTRAINT IF EXISTS totals_sharded_pkey;
+ALTER TABLE shadow.totals_sharded DROP COLUMN IF EXISTS election_id;
+ALTER TABLE shadow.totals_sharded ADD PRIMARY KEY (tenant_id, candidate_id, bucket);
+ALTER TABLE shadow.votes DROP CONSTRAINT IF EXISTS votes_unique_tenant_election_user_candidate;
+ALTER TABLE shadow.votes DROP COLUMN IF EXISTS election_id;
+ALTER TABLE shadow.votes ADD CONSTRAINT votes_unique_tenant_user_candidate UNIQUE (tenant_id, user_id, candidate_id);
+
+DROP TABLE IF EXISTS candidates;
+
+ALTER TABLE totals_snapshot_items DROP CONSTRAINT IF EXISTS totals_snapshot_items_pkey;
+ALTER TABLE totals_snapshot_items DROP COLUMN IF EXISTS election_id;
+ALTER TABLE totals_snapshot_items ADD PRIMARY KEY (snapshot_id, tenant_id, candidate_id);
+
+DROP VIEW IF EXISTS totals;
+
+ALTER TABLE totals_sharded DROP CONSTRAINT IF EXISTS totals_sharded_pkey;
+ALTER TABLE totals_sharded DROP COLUMN IF EXISTS election_id;
+ALTER TABLE totals_sharded ADD PRIMARY KEY (tenant_id, candidate_id, bucket);
+
+CREATE VIEW totals AS
+SELECT
+    tenant_id,
+    candidate_id,
+    SUM(cnt) AS count
+FROM totals_sharded
+GROUP BY tenant_id, candidate_id
+ORDER BY tenant_id, candidate_id;
+
+ALTER TABLE votes DROP CONSTRAINT IF EXISTS votes_unique_tenant_election_user_candidate;
+ALTER TABLE votes DROP COLUMN IF EXISTS election_id;
+ALTER TABLE votes ADD CONSTRAINT votes_unique_tenant_user_candidate UNIQUE (tenant_id, user_id, candidate_id);
+-- +goose StatementEnd
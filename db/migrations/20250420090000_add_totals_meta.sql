@@ -0,0 +1,40 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Per-election summary maintained by the worker in the same transaction as
+-- the totals, so readers need not scan votes. version increases with every
+-- batch that changes the election.
+CREATE TABLE IF NOT EXISTS totals_meta (
+    tenant_id TEXT NOT NULL,
+    election_id TEXT NOT NULL,
+    last_updated_at TIMESTAMPTZ NOT NULL,
+    total_votes BIGINT NOT NULL DEFAULT 0,
+    total_ballots BIGINT NOT NULL DEFAULT 0,
+    last_stream_id TEXT NOT NULL DEFAULT '',
+    version BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (tenant_id, election_id)
+);
+
+-- Backfill from existing votes. FORCE is lifted for the duration so the
+-- owner running the migration sees every tenant.
+ALTER TABLE votes NO FORCE ROW LEVEL SECURITY;
+INSERT INTO totals_meta (tenant_id, election_id, last_updated_at, total_votes, total_ballots, version)
+SELECT tenant_id, election_id, MAX(voted_at), COUNT(*), COUNT(DISTINCT user_id), 1
+FROM votes
+GROUP BY tenant_id, election_id
+ON CONFLICT DO NOTHING;
+ALTER TABLE votes FORCE ROW LEVEL SECURITY;
+
+ALTER TABLE totals_meta ENABLE ROW LEVEL SECURITY;
+ALTER TABLE totals_meta FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON totals_meta
+    USING (tenant_id = current_setting('app.tenant', true))
+    WITH CHECK (tenant_id = current_setting('app.tenant', true));
+
+CREATE TABLE IF NOT EXISTS shadow.totals_meta (LIKE public.totals_meta INCLUDING ALL);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS shadow.totals_meta;
+DROP TABLE IF EXISTS totals_meta;
+-- +goose StatementEnd
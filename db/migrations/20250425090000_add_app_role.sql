@@ -0,0 +1,42 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Services connect as vote_app. Superusers and BYPASSRLS roles skip
+-- row-level security, so the role that owns the schema and runs migrations
+-- must not be used by services. vote_app is created without a password when
+-- missing; deployments set one (compose does so in db/init).
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_roles WHERE rolname = 'vote_app') THEN
+        CREATE ROLE vote_app LOGIN;
+    END IF;
+END
+$$;
+ALTER ROLE vote_app NOSUPERUSER NOBYPASSRLS NOCREATEDB NOCREATEROLE;
+
+GRANT USAGE ON SCHEMA public, shadow TO vote_app;
+GRANT SELECT, INSERT, UPDATE, DELETE ON ALL TABLES IN SCHEMA public, shadow TO vote_app;
+GRANT USAGE, SELECT ON ALL SEQUENCES IN SCHEMA public, shadow TO vote_app;
+REVOKE ALL ON goose_db_version FROM vote_app;
+ALTER DEFAULT PRIVILEGES IN SCHEMA public, shadow
+    GRANT SELECT, INSERT, UPDATE, DELETE ON TABLES TO vote_app;
+ALTER DEFAULT PRIVILEGES IN SCHEMA public, shadow
+    GRANT USAGE, SELECT ON SEQUENCES TO vote_app;
+
+-- A view reads its tables with the view owner's privileges unless
+-- security_invoker is set, and the owner here bypasses row-level security.
+-- Recreating the view must keep this option.
+ALTER VIEW totals SET (security_invoker = true);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER VIEW totals RESET (security_invoker);
+ALTER DEFAULT PRIVILEGES IN SCHEMA public, shadow
+    REVOKE USAGE, SELECT ON SEQUENCES FROM vote_app;
+ALTER DEFAULT PRIVILEGES IN SCHEMA public, shadow
+    REVOKE SELECT, INSERT, UPDATE, DELETE ON TABLES FROM vote_app;
+REVOKE ALL ON ALL SEQUENCES IN SCHEMA public, shadow FROM vote_app;
+REVOKE ALL ON ALL TABLES IN SCHEMA public, shadow FROM vote_app;
+REVOKE USAGE ON SCHEMA public, shadow FROM vote_app;
+DROP ROLE IF EXISTS vote_app;
+-- +goose StatementEnd
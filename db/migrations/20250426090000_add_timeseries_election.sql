@@ -0,0 +1,48 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Time series are kept per election like the totals. Existing buckets cannot
+-- be split by election, so they are rebuilt from votes. FORCE is lifted for
+-- the duration so the owner running the migration sees every tenant.
+ALTER TABLE totals_timeseries ADD COLUMN IF NOT EXISTS election_id TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE totals_timeseries DROP CONSTRAINT IF EXISTS totals_timeseries_pkey;
+ALTER TABLE totals_timeseries ADD PRIMARY KEY (tenant_id, election_id, resolution, candidate_id, bucket_start);
+
+ALTER TABLE votes NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE totals_timeseries NO FORCE ROW LEVEL SECURITY;
+DELETE FROM totals_timeseries;
+INSERT INTO totals_timeseries (tenant_id, election_id, resolution, candidate_id, bucket_start, cnt)
+SELECT tenant_id, election_id, r.resolution, candidate_id, date_trunc(r.resolution, voted_at, 'UTC'), COUNT(*)
+FROM votes
+CROSS JOIN (VALUES ('minute'), ('hour')) AS r (resolution)
+GROUP BY 1, 2, 3, 4, 5;
+ALTER TABLE totals_timeseries FORCE ROW LEVEL SECURITY;
+ALTER TABLE votes FORCE ROW LEVEL SECURITY;
+
+-- Shadow series are rebuilt by shadow workers.
+DELETE FROM shadow.totals_timeseries;
+ALTER TABLE shadow.totals_timeseries ADD COLUMN IF NOT EXISTS election_id TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE shadow.totals_timeseries DROP CONSTRAINT IF EXISTS totals_timeseries_pkey;
+ALTER TABLE shadow.totals_timeseries ADD PRIMARY KEY (tenant_id, election_id, resolution, candidate_id, bucket_start);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM shadow.totals_timeseries;
+ALTER TABLE shadow.totals_timeseries DROP CONSTRAINT IF EXISTS totals_timeseries_pkey;
+ALTER TABLE shadow.totals_timeseries DROP COLUMN IF EXISTS election_id;
+ALTER TABLE shadow.totals_timeseries ADD PRIMARY KEY (tenant_id, resolution, candidate_id, bucket_start);
+
+-- Merge the elections back into one bucket per candidate.
+ALTER TABLE totals_timeseries NO FORCE ROW LEVEL SECURITY;
+CREATE TEMP TABLE totals_timeseries_merged ON COMMIT DROP AS
+SELECT tenant_id, resolution, candidate_id, bucket_start, SUM(cnt)::BIGINT AS cnt
+FROM totals_timeseries
+GROUP BY 1, 2, 3, 4;
+DELETE FROM totals_timeseries;
+ALTER TABLE totals_timeseries DROP CONSTRAINT IF EXISTS totals_timeseries_pkey;
+ALTER TABLE totals_timeseries DROP COLUMN IF EXISTS election_id;
+ALTER TABLE totals_timeseries ADD PRIMARY KEY (tenant_id, resolution, candidate_id, bucket_start);
+INSERT INTO totals_timeseries (tenant_id, resolution, candidate_id, bucket_start, cnt)
+SELECT tenant_id, resolution, candidate_id, bucket_start, cnt FROM totals_timeseries_merged;
+ALTER TABLE totals_timeseries FORCE ROW LEVEL SECURITY;
+-- +goose StatementEnd
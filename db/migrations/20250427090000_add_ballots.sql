@@ -0,0 +1,50 @@
+-- +goose Up
+-- +goose StatementBegin
+-- One row per voter and election. The worker counts a ballot in
+-- totals_meta.total_ballots only when its insert here succeeds, so
+-- concurrent batches cannot both count the same voter.
+CREATE TABLE IF NOT EXISTS ballots (
+    tenant_id TEXT NOT NULL,
+    election_id TEXT NOT NULL,
+    user_id BIGINT NOT NULL,
+    PRIMARY KEY (tenant_id, election_id, user_id)
+);
+
+-- Backfill from existing votes and recount total_ballots, which may have
+-- been counted twice by concurrent batches. FORCE is lifted for the duration
+-- so the owner running the migration sees every tenant.
+ALTER TABLE votes NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE totals_meta NO FORCE ROW LEVEL SECURITY;
+INSERT INTO ballots (tenant_id, election_id, user_id)
+SELECT DISTINCT tenant_id, election_id, user_id
+FROM votes
+ON CONFLICT DO NOTHING;
+UPDATE totals_meta AS m
+SET total_ballots = b.n
+FROM (
+    SELECT tenant_id, election_id, COUNT(*) AS n
+    FROM ballots
+    GROUP BY tenant_id, election_id
+) AS b
+WHERE m.tenant_id = b.tenant_id AND m.election_id = b.election_id;
+ALTER TABLE totals_meta FORCE ROW LEVEL SECURITY;
+ALTER TABLE votes FORCE ROW LEVEL SECURITY;
+
+ALTER TABLE ballots ENABLE ROW LEVEL SECURITY;
+ALTER TABLE ballots FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON ballots
+    USING (tenant_id = current_setting('app.tenant', true))
+    WITH CHECK (tenant_id = current_setting('app.tenant', true));
+
+CREATE TABLE IF NOT EXISTS shadow.ballots (LIKE public.ballots INCLUDING ALL);
+INSERT INTO shadow.ballots (tenant_id, election_id, user_id)
+SELECT DISTINCT tenant_id, election_id, user_id
+FROM shadow.votes
+ON CONFLICT DO NOTHING;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS shadow.ballots;
+DROP TABLE IF EXISTS ballots;
+-- +goose StatementEnd
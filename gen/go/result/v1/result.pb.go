@@ -101,8 +101,15 @@ func (x *PingResponse) GetMessage() string {
 	return ""
 }
 
+// GetTotalsRequest returns live totals unless as_of (RFC3339) is set, in
+// which case totals at that instant are returned.
+// GetTotalsRequest.tenant selects the organisation and election_id the
+// election within it; empty means "default" for both.
 type GetTotalsRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
+	AsOf          string                 `protobuf:"bytes,1,opt,name=as_of,json=asOf,proto3" json:"as_of,omitempty"`
+	Tenant        string                 `protobuf:"bytes,2,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	ElectionId    string                 `protobuf:"bytes,3,opt,name=election_id,json=electionId,proto3" json:"election_id,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -137,10 +144,36 @@ func (*GetTotalsRequest) Descriptor() ([]byte, []int) {
 	return file_result_v1_result_proto_rawDescGZIP(), []int{2}
 }
 
+func (x *GetTotalsRequest) GetAsOf() string {
+	if x != nil {
+		return x.AsOf
+	}
+	return ""
+}
+
+func (x *GetTotalsRequest) GetTenant() string {
+	if x != nil {
+		return x.Tenant
+	}
+	return ""
+}
+
+func (x *GetTotalsRequest) GetElectionId() string {
+	if x != nil {
+		return x.ElectionId
+	}
+	return ""
+}
+
+// Totals are ordered by the candidate's display order, then ID. name is empty
+// for candidates without metadata.
 type Totals struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	CandidateId   uint64                 `protobuf:"varint,1,opt,name=candidate_id,json=candidateId,proto3" json:"candidate_id,omitempty"`
-	Count         uint64                 `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	CandidateId uint64                 `protobuf:"varint,1,opt,name=candidate_id,json=candidateId,proto3" json:"candidate_id,omitempty"`
+	Count       uint64                 `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
+	Name        string                 `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`
+	// percentage is count as a share of all votes in the election (0-100).
+	Percentage    float64 `protobuf:"fixed64,4,opt,name=percentage,proto3" json:"percentage,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -189,10 +222,32 @@ func (x *Totals) GetCount() uint64 {
 	return 0
 }
 
+func (x *Totals) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Totals) GetPercentage() float64 {
+	if x != nil {
+		return x.Percentage
+	}
+	return 0
+}
+
 type GetTotalsResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Totals        []*Totals              `protobuf:"bytes,1,rep,name=totals,proto3" json:"totals,omitempty"`
-	UpdatedAt     string                 `protobuf:"bytes,2,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Totals []*Totals              `protobuf:"bytes,1,rep,name=totals,proto3" json:"totals,omitempty"`
+	// updated_at is the time of the latest vote, empty if there is none.
+	UpdatedAt string `protobuf:"bytes,2,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	// source is "live", "snapshot" or "votes" (recomputed from votes.voted_at).
+	Source string `protobuf:"bytes,3,opt,name=source,proto3" json:"source,omitempty"`
+	// snapshot_id is set when source is "snapshot".
+	SnapshotId uint64 `protobuf:"varint,4,opt,name=snapshot_id,json=snapshotId,proto3" json:"snapshot_id,omitempty"`
+	ElectionId string `protobuf:"bytes,5,opt,name=election_id,json=electionId,proto3" json:"election_id,omitempty"`
+	// total_ballots is the number of distinct voters in the election.
+	TotalBallots  uint64 `protobuf:"varint,6,opt,name=total_ballots,json=totalBallots,proto3" json:"total_ballots,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -241,16 +296,315 @@ func (x *GetTotalsResponse) GetUpdatedAt() string {
 	return ""
 }
 
+func (x *GetTotalsResponse) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *GetTotalsResponse) GetSnapshotId() uint64 {
+	if x != nil {
+		return x.SnapshotId
+	}
+	return 0
+}
+
+func (x *GetTotalsResponse) GetElectionId() string {
+	if x != nil {
+		return x.ElectionId
+	}
+	return ""
+}
+
+func (x *GetTotalsResponse) GetTotalBallots() uint64 {
+	if x != nil {
+		return x.TotalBallots
+	}
+	return 0
+}
+
+// GetTimeSeriesRequest selects bucketed counts. resolution is "minute"
+// (default) or "hour"; from and to are RFC3339 and bound bucket starts as
+// [from, to). election_id is empty for "default", as in GetTotalsRequest.
+type GetTimeSeriesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Resolution    string                 `protobuf:"bytes,1,opt,name=resolution,proto3" json:"resolution,omitempty"`
+	From          string                 `protobuf:"bytes,2,opt,name=from,proto3" json:"from,omitempty"`
+	To            string                 `protobuf:"bytes,3,opt,name=to,proto3" json:"to,omitempty"`
+	CandidateIds  []uint64               `protobuf:"varint,4,rep,packed,name=candidate_ids,json=candidateIds,proto3" json:"candidate_ids,omitempty"`
+	Tenant        string                 `protobuf:"bytes,5,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	ElectionId    string                 `protobuf:"bytes,6,opt,name=election_id,json=electionId,proto3" json:"election_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetTimeSeriesRequest) Reset() {
+	*x = GetTimeSeriesRequest{}
+	mi := &file_result_v1_result_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetTimeSeriesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetTimeSeriesRequest) ProtoMessage() {}
+
+func (x *GetTimeSeriesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_result_v1_result_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetTimeSeriesRequest.ProtoReflect.Descriptor instead.
+func (*GetTimeSeriesRequest) Descriptor() ([]byte, []int) {
+	return file_result_v1_result_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *GetTimeSeriesRequest) GetResolution() string {
+	if x != nil {
+		return x.Resolution
+	}
+	return ""
+}
+
+func (x *GetTimeSeriesRequest) GetFrom() string {
+	if x != nil {
+		return x.From
+	}
+	return ""
+}
+
+func (x *GetTimeSeriesRequest) GetTo() string {
+	if x != nil {
+		return x.To
+	}
+	return ""
+}
+
+func (x *GetTimeSeriesRequest) GetCandidateIds() []uint64 {
+	if x != nil {
+		return x.CandidateIds
+	}
+	return nil
+}
+
+func (x *GetTimeSeriesRequest) GetTenant() string {
+	if x != nil {
+		return x.Tenant
+	}
+	return ""
+}
+
+func (x *GetTimeSeriesRequest) GetElectionId() string {
+	if x != nil {
+		return x.ElectionId
+	}
+	return ""
+}
+
+type TimeSeriesPoint struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	BucketStart   string                 `protobuf:"bytes,1,opt,name=bucket_start,json=bucketStart,proto3" json:"bucket_start,omitempty"`
+	Count         uint64                 `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TimeSeriesPoint) Reset() {
+	*x = TimeSeriesPoint{}
+	mi := &file_result_v1_result_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TimeSeriesPoint) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TimeSeriesPoint) ProtoMessage() {}
+
+func (x *TimeSeriesPoint) ProtoReflect() protoreflect.Message {
+	mi := &file_result_v1_result_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TimeSeriesPoint.ProtoReflect.Descriptor instead.
+func (*TimeSeriesPoint) Descriptor() ([]byte, []int) {
+	return file_result_v1_result_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *TimeSeriesPoint) GetBucketStart() string {
+	if x != nil {
+		return x.BucketStart
+	}
+	return ""
+}
+
+func (x *TimeSeriesPoint) GetCount() uint64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+type CandidateSeries struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	CandidateId   uint64                 `protobuf:"varint,1,opt,name=candidate_id,json=candidateId,proto3" json:"candidate_id,omitempty"`
+	Points        []*TimeSeriesPoint     `protobuf:"bytes,2,rep,name=points,proto3" json:"points,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CandidateSeries) Reset() {
+	*x = CandidateSeries{}
+	mi := &file_result_v1_result_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CandidateSeries) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CandidateSeries) ProtoMessage() {}
+
+func (x *CandidateSeries) ProtoReflect() protoreflect.Message {
+	mi := &file_result_v1_result_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CandidateSeries.ProtoReflect.Descriptor instead.
+func (*CandidateSeries) Descriptor() ([]byte, []int) {
+	return file_result_v1_result_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *CandidateSeries) GetCandidateId() uint64 {
+	if x != nil {
+		return x.CandidateId
+	}
+	return 0
+}
+
+func (x *CandidateSeries) GetPoints() []*TimeSeriesPoint {
+	if x != nil {
+		return x.Points
+	}
+	return nil
+}
+
+type GetTimeSeriesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Resolution    string                 `protobuf:"bytes,1,opt,name=resolution,proto3" json:"resolution,omitempty"`
+	From          string                 `protobuf:"bytes,2,opt,name=from,proto3" json:"from,omitempty"`
+	To            string                 `protobuf:"bytes,3,opt,name=to,proto3" json:"to,omitempty"`
+	Series        []*CandidateSeries     `protobuf:"bytes,4,rep,name=series,proto3" json:"series,omitempty"`
+	ElectionId    string                 `protobuf:"bytes,5,opt,name=election_id,json=electionId,proto3" json:"election_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetTimeSeriesResponse) Reset() {
+	*x = GetTimeSeriesResponse{}
+	mi := &file_result_v1_result_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetTimeSeriesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetTimeSeriesResponse) ProtoMessage() {}
+
+func (x *GetTimeSeriesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_result_v1_result_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetTimeSeriesResponse.ProtoReflect.Descriptor instead.
+func (*GetTimeSeriesResponse) Descriptor() ([]byte, []int) {
+	return file_result_v1_result_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *GetTimeSeriesResponse) GetResolution() string {
+	if x != nil {
+		return x.Resolution
+	}
+	return ""
+}
+
+func (x *GetTimeSeriesResponse) GetFrom() string {
+	if x != nil {
+		return x.From
+	}
+	return ""
+}
+
+func (x *GetTimeSeriesResponse) GetTo() string {
+	if x != nil {
+		return x.To
+	}
+	return ""
+}
+
+func (x *GetTimeSeriesResponse) GetSeries() []*CandidateSeries {
+	if x != nil {
+		return x.Series
+	}
+	return nil
+}
+
+func (x *GetTimeSeriesResponse) GetElectionId() string {
+	if x != nil {
+		return x.ElectionId
+	}
+	return ""
+}
+
+// SubscribeTotalsRequest.resume_after is the sequence of the last update the
+// client has seen; the initial update is skipped unless it is newer.
 type SubscribeTotalsRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Tenant        string                 `protobuf:"bytes,1,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	ElectionId    string                 `protobuf:"bytes,2,opt,name=election_id,json=electionId,proto3" json:"election_id,omitempty"`
+	ResumeAfter   uint64                 `protobuf:"varint,3,opt,name=resume_after,json=resumeAfter,proto3" json:"resume_after,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *SubscribeTotalsRequest) Reset() {
 	*x = SubscribeTotalsRequest{}
-	mi := &file_result_v1_result_proto_msgTypes[5]
+	mi := &file_result_v1_result_proto_msgTypes[9]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -262,7 +616,7 @@ func (x *SubscribeTotalsRequest) String() string {
 func (*SubscribeTotalsRequest) ProtoMessage() {}
 
 func (x *SubscribeTotalsRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_result_v1_result_proto_msgTypes[5]
+	mi := &file_result_v1_result_proto_msgTypes[9]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -275,7 +629,7 @@ func (x *SubscribeTotalsRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use SubscribeTotalsRequest.ProtoReflect.Descriptor instead.
 func (*SubscribeTotalsRequest) Descriptor() ([]byte, []int) {
-	return file_result_v1_result_proto_rawDescGZIP(), []int{5}
+	return file_result_v1_result_proto_rawDescGZIP(), []int{9}
 }
 
 func (x *SubscribeTotalsRequest) GetTenant() string {
@@ -285,17 +639,37 @@ func (x *SubscribeTotalsRequest) GetTenant() string {
 	return ""
 }
 
+func (x *SubscribeTotalsRequest) GetElectionId() string {
+	if x != nil {
+		return x.ElectionId
+	}
+	return ""
+}
+
+func (x *SubscribeTotalsRequest) GetResumeAfter() uint64 {
+	if x != nil {
+		return x.ResumeAfter
+	}
+	return 0
+}
+
+// Updates are only sent when sequence increases. sequence is the election's
+// totals version, so it is stable across result-query restarts and replicas;
+// it is 0 before the first vote.
 type SubscribeTotalsResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Totals        []*Totals              `protobuf:"bytes,1,rep,name=totals,proto3" json:"totals,omitempty"`
 	UpdatedAt     string                 `protobuf:"bytes,2,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	ElectionId    string                 `protobuf:"bytes,3,opt,name=election_id,json=electionId,proto3" json:"election_id,omitempty"`
+	TotalBallots  uint64                 `protobuf:"varint,4,opt,name=total_ballots,json=totalBallots,proto3" json:"total_ballots,omitempty"`
+	Sequence      uint64                 `protobuf:"varint,5,opt,name=sequence,proto3" json:"sequence,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *SubscribeTotalsResponse) Reset() {
 	*x = SubscribeTotalsResponse{}
-	mi := &file_result_v1_result_proto_msgTypes[6]
+	mi := &file_result_v1_result_proto_msgTypes[10]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -307,7 +681,7 @@ func (x *SubscribeTotalsResponse) String() string {
 func (*SubscribeTotalsResponse) ProtoMessage() {}
 
 func (x *SubscribeTotalsResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_result_v1_result_proto_msgTypes[6]
+	mi := &file_result_v1_result_proto_msgTypes[10]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -320,7 +694,7 @@ func (x *SubscribeTotalsResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use SubscribeTotalsResponse.ProtoReflect.Descriptor instead.
 func (*SubscribeTotalsResponse) Descriptor() ([]byte, []int) {
-	return file_result_v1_result_proto_rawDescGZIP(), []int{6}
+	return file_result_v1_result_proto_rawDescGZIP(), []int{10}
 }
 
 func (x *SubscribeTotalsResponse) GetTotals() []*Totals {
@@ -337,6 +711,81 @@ func (x *SubscribeTotalsResponse) GetUpdatedAt() string {
 	return ""
 }
 
+func (x *SubscribeTotalsResponse) GetElectionId() string {
+	if x != nil {
+		return x.ElectionId
+	}
+	return ""
+}
+
+func (x *SubscribeTotalsResponse) GetTotalBallots() uint64 {
+	if x != nil {
+		return x.TotalBallots
+	}
+	return 0
+}
+
+func (x *SubscribeTotalsResponse) GetSequence() uint64 {
+	if x != nil {
+		return x.Sequence
+	}
+	return 0
+}
+
+// TotalsChanged is published as JSON by the worker on the results channel
+// after committing a batch, naming the elections whose totals changed.
+type TotalsChanged struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Tenant        string                 `protobuf:"bytes,1,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	ElectionIds   []string               `protobuf:"bytes,2,rep,name=election_ids,json=electionIds,proto3" json:"election_ids,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TotalsChanged) Reset() {
+	*x = TotalsChanged{}
+	mi := &file_result_v1_result_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TotalsChanged) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TotalsChanged) ProtoMessage() {}
+
+func (x *TotalsChanged) ProtoReflect() protoreflect.Message {
+	mi := &file_result_v1_result_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TotalsChanged.ProtoReflect.Descriptor instead.
+func (*TotalsChanged) Descriptor() ([]byte, []int) {
+	return file_result_v1_result_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *TotalsChanged) GetTenant() string {
+	if x != nil {
+		return x.Tenant
+	}
+	return ""
+}
+
+func (x *TotalsChanged) GetElectionIds() []string {
+	if x != nil {
+		return x.ElectionIds
+	}
+	return nil
+}
+
 var File_result_v1_result_proto protoreflect.FileDescriptor
 
 const file_result_v1_result_proto_rawDesc = "" +
@@ -344,25 +793,75 @@ const file_result_v1_result_proto_rawDesc = "" +
 	"\x16result/v1/result.proto\x12\tresult.v1\"\r\n" +
 	"\vPingRequest\"(\n" +
 	"\fPingResponse\x12\x18\n" +
-	"\amessage\x18\x01 \x01(\tR\amessage\"\x12\n" +
-	"\x10GetTotalsRequest\"A\n" +
+	"\amessage\x18\x01 \x01(\tR\amessage\"`\n" +
+	"\x10GetTotalsRequest\x12\x13\n" +
+	"\x05as_of\x18\x01 \x01(\tR\x04asOf\x12\x16\n" +
+	"\x06tenant\x18\x02 \x01(\tR\x06tenant\x12\x1f\n" +
+	"\velection_id\x18\x03 \x01(\tR\n" +
+	"electionId\"u\n" +
 	"\x06Totals\x12!\n" +
 	"\fcandidate_id\x18\x01 \x01(\x04R\vcandidateId\x12\x14\n" +
-	"\x05count\x18\x02 \x01(\x04R\x05count\"]\n" +
+	"\x05count\x18\x02 \x01(\x04R\x05count\x12\x12\n" +
+	"\x04name\x18\x03 \x01(\tR\x04name\x12\x1e\n" +
+	"\n" +
+	"percentage\x18\x04 \x01(\x01R\n" +
+	"percentage\"\xdc\x01\n" +
 	"\x11GetTotalsResponse\x12)\n" +
 	"\x06totals\x18\x01 \x03(\v2\x11.result.v1.TotalsR\x06totals\x12\x1d\n" +
 	"\n" +
-	"updated_at\x18\x02 \x01(\tR\tupdatedAt\"0\n" +
+	"updated_at\x18\x02 \x01(\tR\tupdatedAt\x12\x16\n" +
+	"\x06source\x18\x03 \x01(\tR\x06source\x12\x1f\n" +
+	"\vsnapshot_id\x18\x04 \x01(\x04R\n" +
+	"snapshotId\x12\x1f\n" +
+	"\velection_id\x18\x05 \x01(\tR\n" +
+	"electionId\x12#\n" +
+	"\rtotal_ballots\x18\x06 \x01(\x04R\ftotalBallots\"\xb8\x01\n" +
+	"\x14GetTimeSeriesRequest\x12\x1e\n" +
+	"\n" +
+	"resolution\x18\x01 \x01(\tR\n" +
+	"resolution\x12\x12\n" +
+	"\x04from\x18\x02 \x01(\tR\x04from\x12\x0e\n" +
+	"\x02to\x18\x03 \x01(\tR\x02to\x12#\n" +
+	"\rcandidate_ids\x18\x04 \x03(\x04R\fcandidateIds\x12\x16\n" +
+	"\x06tenant\x18\x05 \x01(\tR\x06tenant\x12\x1f\n" +
+	"\velection_id\x18\x06 \x01(\tR\n" +
+	"electionId\"J\n" +
+	"\x0fTimeSeriesPoint\x12!\n" +
+	"\fbucket_start\x18\x01 \x01(\tR\vbucketStart\x12\x14\n" +
+	"\x05count\x18\x02 \x01(\x04R\x05count\"h\n" +
+	"\x0fCandidateSeries\x12!\n" +
+	"\fcandidate_id\x18\x01 \x01(\x04R\vcandidateId\x122\n" +
+	"\x06points\x18\x02 \x03(\v2\x1a.result.v1.TimeSeriesPointR\x06points\"\xb0\x01\n" +
+	"\x15GetTimeSeriesResponse\x12\x1e\n" +
+	"\n" +
+	"resolution\x18\x01 \x01(\tR\n" +
+	"resolution\x12\x12\n" +
+	"\x04from\x18\x02 \x01(\tR\x04from\x12\x0e\n" +
+	"\x02to\x18\x03 \x01(\tR\x02to\x122\n" +
+	"\x06series\x18\x04 \x03(\v2\x1a.result.v1.CandidateSeriesR\x06series\x12\x1f\n" +
+	"\velection_id\x18\x05 \x01(\tR\n" +
+	"electionId\"t\n" +
 	"\x16SubscribeTotalsRequest\x12\x16\n" +
-	"\x06tenant\x18\x01 \x01(\tR\x06tenant\"c\n" +
+	"\x06tenant\x18\x01 \x01(\tR\x06tenant\x12\x1f\n" +
+	"\velection_id\x18\x02 \x01(\tR\n" +
+	"electionId\x12!\n" +
+	"\fresume_after\x18\x03 \x01(\x04R\vresumeAfter\"\xc5\x01\n" +
 	"\x17SubscribeTotalsResponse\x12)\n" +
 	"\x06totals\x18\x01 \x03(\v2\x11.result.v1.TotalsR\x06totals\x12\x1d\n" +
 	"\n" +
-	"updated_at\x18\x02 \x01(\tR\tupdatedAt2\xec\x01\n" +
+	"updated_at\x18\x02 \x01(\tR\tupdatedAt\x12\x1f\n" +
+	"\velection_id\x18\x03 \x01(\tR\n" +
+	"electionId\x12#\n" +
+	"\rtotal_ballots\x18\x04 \x01(\x04R\ftotalBallots\x12\x1a\n" +
+	"\bsequence\x18\x05 \x01(\x04R\bsequence\"J\n" +
+	"\rTotalsChanged\x12\x16\n" +
+	"\x06tenant\x18\x01 \x01(\tR\x06tenant\x12!\n" +
+	"\felection_ids\x18\x02 \x03(\tR\velectionIds2\xc0\x02\n" +
 	"\rResultService\x127\n" +
 	"\x04Ping\x12\x16.result.v1.PingRequest\x1a\x17.result.v1.PingResponse\x12F\n" +
 	"\tGetTotals\x12\x1b.result.v1.GetTotalsRequest\x1a\x1c.result.v1.GetTotalsResponse\x12Z\n" +
-	"\x0fSubscribeTotals\x12!.result.v1.SubscribeTotalsRequest\x1a\".result.v1.SubscribeTotalsResponse0\x01BAZ?github.com/yoyo1025/k8s-vote-platform/gen/go/result/v1;resultv1b\x06proto3"
+	"\x0fSubscribeTotals\x12!.result.v1.SubscribeTotalsRequest\x1a\".result.v1.SubscribeTotalsResponse0\x01\x12R\n" +
+	"\rGetTimeSeries\x12\x1f.result.v1.GetTimeSeriesRequest\x1a .result.v1.GetTimeSeriesResponseBAZ?github.com/yoyo1025/k8s-vote-platform/gen/go/result/v1;resultv1b\x06proto3"
 
 var (
 	file_result_v1_result_proto_rawDescOnce sync.Once
@@ -376,30 +875,39 @@ func file_result_v1_result_proto_rawDescGZIP() []byte {
 	return file_result_v1_result_proto_rawDescData
 }
 
-var file_result_v1_result_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
+var file_result_v1_result_proto_msgTypes = make([]protoimpl.MessageInfo, 12)
 var file_result_v1_result_proto_goTypes = []any{
 	(*PingRequest)(nil),             // 0: result.v1.PingRequest
 	(*PingResponse)(nil),            // 1: result.v1.PingResponse
 	(*GetTotalsRequest)(nil),        // 2: result.v1.GetTotalsRequest
 	(*Totals)(nil),                  // 3: result.v1.Totals
 	(*GetTotalsResponse)(nil),       // 4: result.v1.GetTotalsResponse
-	(*SubscribeTotalsRequest)(nil),  // 5: result.v1.SubscribeTotalsRequest
-	(*SubscribeTotalsResponse)(nil), // 6: result.v1.SubscribeTotalsResponse
+	(*GetTimeSeriesRequest)(nil),    // 5: result.v1.GetTimeSeriesRequest
+	(*TimeSeriesPoint)(nil),         // 6: result.v1.TimeSeriesPoint
+	(*CandidateSeries)(nil),         // 7: result.v1.CandidateSeries
+	(*GetTimeSeriesResponse)(nil),   // 8: result.v1.GetTimeSeriesResponse
+	(*SubscribeTotalsRequest)(nil),  // 9: result.v1.SubscribeTotalsRequest
+	(*SubscribeTotalsResponse)(nil), // 10: result.v1.SubscribeTotalsResponse
+	(*TotalsChanged)(nil),           // 11: result.v1.TotalsChanged
 }
 var file_result_v1_result_proto_depIdxs = []int32{
-	3, // 0: result.v1.GetTotalsResponse.totals:type_name -> result.v1.Totals
-	3, // 1: result.v1.SubscribeTotalsResponse.totals:type_name -> result.v1.Totals
-	0, // 2: result.v1.ResultService.Ping:input_type -> result.v1.PingRequest
-	2, // 3: result.v1.ResultService.GetTotals:input_type -> result.v1.GetTotalsRequest
-	5, // 4: result.v1.ResultService.SubscribeTotals:input_type -> result.v1.SubscribeTotalsRequest
-	1, // 5: result.v1.ResultService.Ping:output_type -> result.v1.PingResponse
-	4, // 6: result.v1.ResultService.GetTotals:output_type -> result.v1.GetTotalsResponse
-	6, // 7: result.v1.ResultService.SubscribeTotals:output_type -> result.v1.SubscribeTotalsResponse
-	5, // [5:8] is the sub-list for method output_type
-	2, // [2:5] is the sub-list for method input_type
-	2, // [2:2] is the sub-list for extension type_name
-	2, // [2:2] is the sub-list for extension extendee
-	0, // [0:2] is the sub-list for field type_name
+	3,  // 0: result.v1.GetTotalsResponse.totals:type_name -> result.v1.Totals
+	6,  // 1: result.v1.CandidateSeries.points:type_name -> result.v1.TimeSeriesPoint
+	7,  // 2: result.v1.GetTimeSeriesResponse.series:type_name -> result.v1.CandidateSeries
+	3,  // 3: result.v1.SubscribeTotalsResponse.totals:type_name -> result.v1.Totals
+	0,  // 4: result.v1.ResultService.Ping:input_type -> result.v1.PingRequest
+	2,  // 5: result.v1.ResultService.GetTotals:input_type -> result.v1.GetTotalsRequest
+	9,  // 6: result.v1.ResultService.SubscribeTotals:input_type -> result.v1.SubscribeTotalsRequest
+	5,  // 7: result.v1.ResultService.GetTimeSeries:input_type -> result.v1.GetTimeSeriesRequest
+	1,  // 8: result.v1.ResultService.Ping:output_type -> result.v1.PingResponse
+	4,  // 9: result.v1.ResultService.GetTotals:output_type -> result.v1.GetTotalsResponse
+	10, // 10: result.v1.ResultService.SubscribeTotals:output_type -> result.v1.SubscribeTotalsResponse
+	8,  // 11: result.v1.ResultService.GetTimeSeries:output_type -> result.v1.GetTimeSeriesResponse
+	8,  // [8:12] is the sub-list for method output_type
+	4,  // [4:8] is the sub-list for method input_type
+	4,  // [4:4] is the sub-list for extension type_name
+	4,  // [4:4] is the sub-list for extension extendee
+	0,  // [0:4] is the sub-list for field type_name
 }
 
 func init() { file_result_v1_result_proto_init() }
@@ -413,7 +921,7 @@ func file_result_v1_result_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_result_v1_result_proto_rawDesc), len(file_result_v1_result_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   7,
+			NumMessages:   12,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
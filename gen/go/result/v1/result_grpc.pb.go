@@ -22,6 +22,7 @@ const (
 	ResultService_Ping_FullMethodName            = "/result.v1.ResultService/Ping"
 	ResultService_GetTotals_FullMethodName       = "/result.v1.ResultService/GetTotals"
 	ResultService_SubscribeTotals_FullMethodName = "/result.v1.ResultService/SubscribeTotals"
+	ResultService_GetTimeSeries_FullMethodName   = "/result.v1.ResultService/GetTimeSeries"
 )
 
 // ResultServiceClient is the client API for ResultService service.
@@ -31,6 +32,7 @@ type ResultServiceClient interface {
 	Ping(ctx context.Context, in *PingRequest, opts ...grpc.CallOption) (*PingResponse, error)
 	GetTotals(ctx context.Context, in *GetTotalsRequest, opts ...grpc.CallOption) (*GetTotalsResponse, error)
 	SubscribeTotals(ctx context.Context, in *SubscribeTotalsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[SubscribeTotalsResponse], error)
+	GetTimeSeries(ctx context.Context, in *GetTimeSeriesRequest, opts ...grpc.CallOption) (*GetTimeSeriesResponse, error)
 }
 
 type resultServiceClient struct {
@@ -80,6 +82,16 @@ func (c *resultServiceClient) SubscribeTotals(ctx context.Context, in *Subscribe
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type ResultService_SubscribeTotalsClient = grpc.ServerStreamingClient[SubscribeTotalsResponse]
 
+func (c *resultServiceClient) GetTimeSeries(ctx context.Context, in *GetTimeSeriesRequest, opts ...grpc.CallOption) (*GetTimeSeriesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetTimeSeriesResponse)
+	err := c.cc.Invoke(ctx, ResultService_GetTimeSeries_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ResultServiceServer is the server API for ResultService service.
 // All implementations must embed UnimplementedResultServiceServer
 // for forward compatibility.
@@ -87,6 +99,7 @@ type ResultServiceServer interface {
 	Ping(context.Context, *PingRequest) (*PingResponse, error)
 	GetTotals(context.Context, *GetTotalsRequest) (*GetTotalsResponse, error)
 	SubscribeTotals(*SubscribeTotalsRequest, grpc.ServerStreamingServer[SubscribeTotalsResponse]) error
+	GetTimeSeries(context.Context, *GetTimeSeriesRequest) (*GetTimeSeriesResponse, error)
 	mustEmbedUnimplementedResultServiceServer()
 }
 
@@ -106,6 +119,9 @@ func (UnimplementedResultServiceServer) GetTotals(context.Context, *GetTotalsReq
 func (UnimplementedResultServiceServer) SubscribeTotals(*SubscribeTotalsRequest, grpc.ServerStreamingServer[SubscribeTotalsResponse]) error {
 	return status.Errorf(codes.Unimplemented, "method SubscribeTotals not implemented")
 }
+func (UnimplementedResultServiceServer) GetTimeSeries(context.Context, *GetTimeSeriesRequest) (*GetTimeSeriesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTimeSeries not implemented")
+}
 func (UnimplementedResultServiceServer) mustEmbedUnimplementedResultServiceServer() {}
 func (UnimplementedResultServiceServer) testEmbeddedByValue()                       {}
 
@@ -174,6 +190,24 @@ func _ResultService_SubscribeTotals_Handler(srv interface{}, stream grpc.ServerS
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type ResultService_SubscribeTotalsServer = grpc.ServerStreamingServer[SubscribeTotalsResponse]
 
+func _ResultService_GetTimeSeries_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTimeSeriesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ResultServiceServer).GetTimeSeries(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ResultService_GetTimeSeries_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ResultServiceServer).GetTimeSeries(ctx, req.(*GetTimeSeriesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // ResultService_ServiceDesc is the grpc.ServiceDesc for ResultService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -189,6 +223,10 @@ var ResultService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetTotals",
 			Handler:    _ResultService_GetTotals_Handler,
 		},
+		{
+			MethodName: "GetTimeSeries",
+			Handler:    _ResultService_GetTimeSeries_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
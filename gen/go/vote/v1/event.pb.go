@@ -0,0 +1,282 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.9
+// 	protoc        (unknown)
+// source: vote/v1/event.proto
+
+package votev1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// VoteEvent is the versioned envelope carried on stream:votes.
+type VoteEvent struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Unique per ballot; stable across redeliveries and outbox relays.
+	EventId string `protobuf:"bytes,1,opt,name=event_id,json=eventId,proto3" json:"event_id,omitempty"`
+	// e.g. "vote.cast"
+	EventType string `protobuf:"bytes,2,opt,name=event_type,json=eventType,proto3" json:"event_type,omitempty"`
+	// Major version of the envelope. Fields are only ever added within a
+	// version; consumers reject versions they do not know.
+	SchemaVersion uint32 `protobuf:"varint,3,opt,name=schema_version,json=schemaVersion,proto3" json:"schema_version,omitempty"`
+	ElectionId    string `protobuf:"bytes,4,opt,name=election_id,json=electionId,proto3" json:"election_id,omitempty"`
+	// Service instance that produced the event, e.g. "vote-api/<hostname>".
+	Producer   string                 `protobuf:"bytes,5,opt,name=producer,proto3" json:"producer,omitempty"`
+	OccurredAt *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=occurred_at,json=occurredAt,proto3" json:"occurred_at,omitempty"`
+	// Organisation the election belongs to; empty means "default".
+	TenantId string `protobuf:"bytes,7,opt,name=tenant_id,json=tenantId,proto3" json:"tenant_id,omitempty"`
+	// Types that are valid to be assigned to Payload:
+	//
+	//	*VoteEvent_VoteCast
+	Payload       isVoteEvent_Payload `protobuf_oneof:"payload"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *VoteEvent) Reset() {
+	*x = VoteEvent{}
+	mi := &file_vote_v1_event_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VoteEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VoteEvent) ProtoMessage() {}
+
+func (x *VoteEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_vote_v1_event_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VoteEvent.ProtoReflect.Descriptor instead.
+func (*VoteEvent) Descriptor() ([]byte, []int) {
+	return file_vote_v1_event_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *VoteEvent) GetEventId() string {
+	if x != nil {
+		return x.EventId
+	}
+	return ""
+}
+
+func (x *VoteEvent) GetEventType() string {
+	if x != nil {
+		return x.EventType
+	}
+	return ""
+}
+
+func (x *VoteEvent) GetSchemaVersion() uint32 {
+	if x != nil {
+		return x.SchemaVersion
+	}
+	return 0
+}
+
+func (x *VoteEvent) GetElectionId() string {
+	if x != nil {
+		return x.ElectionId
+	}
+	return ""
+}
+
+func (x *VoteEvent) GetProducer() string {
+	if x != nil {
+		return x.Producer
+	}
+	return ""
+}
+
+func (x *VoteEvent) GetOccurredAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.OccurredAt
+	}
+	return nil
+}
+
+func (x *VoteEvent) GetTenantId() string {
+	if x != nil {
+		return x.TenantId
+	}
+	return ""
+}
+
+func (x *VoteEvent) GetPayload() isVoteEvent_Payload {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+func (x *VoteEvent) GetVoteCast() *VoteCast {
+	if x != nil {
+		if x, ok := x.Payload.(*VoteEvent_VoteCast); ok {
+			return x.VoteCast
+		}
+	}
+	return nil
+}
+
+type isVoteEvent_Payload interface {
+	isVoteEvent_Payload()
+}
+
+type VoteEvent_VoteCast struct {
+	VoteCast *VoteCast `protobuf:"bytes,10,opt,name=vote_cast,json=voteCast,proto3,oneof"`
+}
+
+func (*VoteEvent_VoteCast) isVoteEvent_Payload() {}
+
+type VoteCast struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        int64                  `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	CandidateId   int64                  `protobuf:"varint,2,opt,name=candidate_id,json=candidateId,proto3" json:"candidate_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *VoteCast) Reset() {
+	*x = VoteCast{}
+	mi := &file_vote_v1_event_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VoteCast) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VoteCast) ProtoMessage() {}
+
+func (x *VoteCast) ProtoReflect() protoreflect.Message {
+	mi := &file_vote_v1_event_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VoteCast.ProtoReflect.Descriptor instead.
+func (*VoteCast) Descriptor() ([]byte, []int) {
+	return file_vote_v1_event_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *VoteCast) GetUserId() int64 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *VoteCast) GetCandidateId() int64 {
+	if x != nil {
+		return x.CandidateId
+	}
+	return 0
+}
+
+var File_vote_v1_event_proto protoreflect.FileDescriptor
+
+const file_vote_v1_event_proto_rawDesc = "" +
+	"\n" +
+	"\x13vote/v1/event.proto\x12\avote.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\xc0\x02\n" +
+	"\tVoteEvent\x12\x19\n" +
+	"\bevent_id\x18\x01 \x01(\tR\aeventId\x12\x1d\n" +
+	"\n" +
+	"event_type\x18\x02 \x01(\tR\teventType\x12%\n" +
+	"\x0eschema_version\x18\x03 \x01(\rR\rschemaVersion\x12\x1f\n" +
+	"\velection_id\x18\x04 \x01(\tR\n" +
+	"electionId\x12\x1a\n" +
+	"\bproducer\x18\x05 \x01(\tR\bproducer\x12;\n" +
+	"\voccurred_at\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"occurredAt\x12\x1b\n" +
+	"\ttenant_id\x18\a \x01(\tR\btenantId\x120\n" +
+	"\tvote_cast\x18\n" +
+	" \x01(\v2\x11.vote.v1.VoteCastH\x00R\bvoteCastB\t\n" +
+	"\apayload\"F\n" +
+	"\bVoteCast\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\x03R\x06userId\x12!\n" +
+	"\fcandidate_id\x18\x02 \x01(\x03R\vcandidateIdB=Z;github.com/yoyo1025/k8s-vote-platform/gen/go/vote/v1;votev1b\x06proto3"
+
+var (
+	file_vote_v1_event_proto_rawDescOnce sync.Once
+	file_vote_v1_event_proto_rawDescData []byte
+)
+
+func file_vote_v1_event_proto_rawDescGZIP() []byte {
+	file_vote_v1_event_proto_rawDescOnce.Do(func() {
+		file_vote_v1_event_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_vote_v1_event_proto_rawDesc), len(file_vote_v1_event_proto_rawDesc)))
+	})
+	return file_vote_v1_event_proto_rawDescData
+}
+
+var file_vote_v1_event_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_vote_v1_event_proto_goTypes = []any{
+	(*VoteEvent)(nil),             // 0: vote.v1.VoteEvent
+	(*VoteCast)(nil),              // 1: vote.v1.VoteCast
+	(*timestamppb.Timestamp)(nil), // 2: google.protobuf.Timestamp
+}
+var file_vote_v1_event_proto_depIdxs = []int32{
+	2, // 0: vote.v1.VoteEvent.occurred_at:type_name -> google.protobuf.Timestamp
+	1, // 1: vote.v1.VoteEvent.vote_cast:type_name -> vote.v1.VoteCast
+	2, // [2:2] is the sub-list for method output_type
+	2, // [2:2] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
+}
+
+func init() { file_vote_v1_event_proto_init() }
+func file_vote_v1_event_proto_init() {
+	if File_vote_v1_event_proto != nil {
+		return
+	}
+	file_vote_v1_event_proto_msgTypes[0].OneofWrappers = []any{
+		(*VoteEvent_VoteCast)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_vote_v1_event_proto_rawDesc), len(file_vote_v1_event_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   2,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_vote_v1_event_proto_goTypes,
+		DependencyIndexes: file_vote_v1_event_proto_depIdxs,
+		MessageInfos:      file_vote_v1_event_proto_msgTypes,
+	}.Build()
+	File_vote_v1_event_proto = out.File
+	file_vote_v1_event_proto_goTypes = nil
+	file_vote_v1_event_proto_depIdxs = nil
+}
@@ -0,0 +1,154 @@
+// Package authn verifies the bearer tokens issued by the auth service and
+// extracts the claims the other services act on. Every service that scopes
+// data to a tenant verifies tokens itself with the auth service's JWKS, so a
+// request that bypasses the gateway cannot claim another tenant.
+package authn
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+)
+
+// MaxTenantLen bounds the tenant claim; it is used in keys and channel
+// payloads downstream.
+const MaxTenantLen = 64
+
+const defaultRefreshInterval = 15 * time.Minute
+
+var (
+	// ErrMissingToken is returned for requests without a bearer token.
+	ErrMissingToken = errors.New("missing bearer token")
+	// ErrInvalidToken is returned for tokens that fail verification or lack
+	// required claims. Wrapped errors carry the reason.
+	ErrInvalidToken = errors.New("invalid token")
+	// ErrKeysUnavailable is returned when the JWKS cannot be fetched, so the
+	// token could not be checked either way.
+	ErrKeysUnavailable = errors.New("signing keys unavailable")
+)
+
+// Config selects where signing keys come from and which claims are required.
+type Config struct {
+	// JWKSURL is the auth service's key set, e.g.
+	// http://auth:18080/.well-known/jwks.json.
+	JWKSURL string
+	// Issuer and Audience, when set, must match the token's iss and aud.
+	Issuer   string
+	Audience string
+	// RefreshInterval is how often the key set is re-fetched. Zero uses 15m.
+	RefreshInterval time.Duration
+}
+
+// Claims are the verified claims services act on.
+type Claims struct {
+	Subject string
+	Tenant  string
+}
+
+// Verifier checks token signatures against a key set.
+type Verifier struct {
+	cfg   Config
+	cache *jwk.Cache
+	// static replaces the JWKS fetch in tests.
+	static jwk.Set
+}
+
+// New returns a Verifier that fetches and periodically refreshes the key set
+// at cfg.JWKSURL until ctx is done. The first fetch happens on first use, so
+// services can start before the auth service does.
+func New(ctx context.Context, cfg Config) (*Verifier, error) {
+	if cfg.JWKSURL == "" {
+		return nil, errors.New("jwks url is required")
+	}
+	if cfg.RefreshInterval <= 0 {
+		cfg.RefreshInterval = defaultRefreshInterval
+	}
+	cache := jwk.NewCache(ctx)
+	if err := cache.Register(cfg.JWKSURL, jwk.WithMinRefreshInterval(cfg.RefreshInterval)); err != nil {
+		return nil, fmt.Errorf("register jwks: %w", err)
+	}
+	return &Verifier{cfg: cfg, cache: cache}, nil
+}
+
+// NewWithKeySet returns a Verifier using a fixed key set.
+func NewWithKeySet(set jwk.Set, cfg Config) *Verifier {
+	return &Verifier{cfg: cfg, static: set}
+}
+
+// FromRequest verifies the request's bearer token.
+func (v *Verifier) FromRequest(r *http.Request) (Claims, error) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || strings.TrimSpace(token) == "" {
+		return Claims{}, ErrMissingToken
+	}
+	return v.Verify(r.Context(), strings.TrimSpace(token))
+}
+
+// Verify checks the token's signature, expiry, issuer and audience and
+// returns its claims. The tenant claim is required.
+func (v *Verifier) Verify(ctx context.Context, token string) (Claims, error) {
+	set, err := v.keys(ctx)
+	if err != nil {
+		return Claims{}, fmt.Errorf("%w: %v", ErrKeysUnavailable, err)
+	}
+
+	opts := []jwt.ParseOption{jwt.WithKeySet(set), jwt.WithValidate(true)}
+	if v.cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(v.cfg.Issuer))
+	}
+	if v.cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(v.cfg.Audience))
+	}
+	tok, err := jwt.ParseString(token, opts...)
+	if err != nil {
+		return Claims{}, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	claims := Claims{Subject: tok.Subject()}
+	if raw, ok := tok.Get("tenant"); ok {
+		claims.Tenant, _ = raw.(string)
+	}
+	if err := ValidateTenant(claims.Tenant); err != nil {
+		return Claims{}, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	return claims, nil
+}
+
+func (v *Verifier) keys(ctx context.Context) (jwk.Set, error) {
+	if v.static != nil {
+		return v.static, nil
+	}
+	return v.cache.Get(ctx, v.cfg.JWKSURL)
+}
+
+// ValidateTenant rejects empty or oversized tenants and ones containing
+// characters outside printable ASCII or "/", which separates keys.
+func ValidateTenant(tenant string) error {
+	if tenant == "" {
+		return errors.New("tenant claim is required")
+	}
+	if len(tenant) > MaxTenantLen {
+		return fmt.Errorf("tenant must be at most %d characters", MaxTenantLen)
+	}
+	if strings.ContainsFunc(tenant, func(r rune) bool {
+		return r < 0x21 || r > 0x7e || r == '/'
+	}) {
+		return errors.New("tenant contains invalid characters")
+	}
+	return nil
+}
+
+// Status maps a verification error to an HTTP status: 401 for missing or
+// invalid tokens and 503 when keys could not be fetched.
+func Status(err error) int {
+	if errors.Is(err, ErrKeysUnavailable) {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusUnauthorized
+}
@@ -0,0 +1,48 @@
+package authn_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/yoyo1025/k8s-vote-platform/pkg/authn"
+	"github.com/yoyo1025/k8s-vote-platform/pkg/authn/authntest"
+)
+
+func TestFromRequest(t *testing.T) {
+	issuer := authntest.NewIssuer(t)
+	v := issuer.Verifier()
+
+	request := func(token string) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		return req
+	}
+
+	claims, err := v.FromRequest(request(issuer.Token("alice", "acme")))
+	if err != nil || claims.Subject != "alice" || claims.Tenant != "acme" {
+		t.Fatalf("claims = %+v, %v", claims, err)
+	}
+
+	if _, err := v.FromRequest(request("")); !errors.Is(err, authn.ErrMissingToken) {
+		t.Fatalf("no token: err = %v, want ErrMissingToken", err)
+	}
+
+	// A well-formed token signed by someone else must not be trusted.
+	forged := authntest.NewIssuer(t).Token("mallory", "acme")
+	if _, err := v.FromRequest(request(forged)); !errors.Is(err, authn.ErrInvalidToken) {
+		t.Fatalf("forged token: err = %v, want ErrInvalidToken", err)
+	}
+
+	for _, tenant := range []string{"", "a/b", "white space"} {
+		if _, err := v.FromRequest(request(issuer.Token("alice", tenant))); !errors.Is(err, authn.ErrInvalidToken) {
+			t.Fatalf("tenant %q: err = %v, want ErrInvalidToken", tenant, err)
+		}
+	}
+	if got := authn.Status(authn.ErrMissingToken); got != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want 401", got)
+	}
+}
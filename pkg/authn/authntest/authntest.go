@@ -0,0 +1,70 @@
+// Package authntest issues tokens that an authn.Verifier accepts, for tests
+// of services that authenticate requests.
+package authntest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"github.com/yoyo1025/k8s-vote-platform/pkg/authn"
+)
+
+// Issuer signs tokens with a throwaway RSA key.
+type Issuer struct {
+	t   testing.TB
+	key jwk.Key
+	set jwk.Set
+}
+
+// NewIssuer generates a signing key.
+func NewIssuer(t testing.TB) *Issuer {
+	t.Helper()
+	raw, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	key, err := jwk.FromRaw(raw)
+	if err != nil {
+		t.Fatalf("jwk from key: %v", err)
+	}
+	_ = key.Set(jwk.KeyIDKey, "test")
+	_ = key.Set(jwk.AlgorithmKey, jwa.RS256)
+	pub, err := key.PublicKey()
+	if err != nil {
+		t.Fatalf("public key: %v", err)
+	}
+	set := jwk.NewSet()
+	_ = set.AddKey(pub)
+	return &Issuer{t: t, key: key, set: set}
+}
+
+// Verifier returns a Verifier that trusts this issuer's key.
+func (i *Issuer) Verifier() *authn.Verifier {
+	return authn.NewWithKeySet(i.set, authn.Config{})
+}
+
+// Token returns a signed token for subject in tenant, valid for an hour.
+func (i *Issuer) Token(subject, tenant string) string {
+	i.t.Helper()
+	b := jwt.NewBuilder().
+		Subject(subject).
+		IssuedAt(time.Now()).
+		Expiration(time.Now().Add(time.Hour))
+	if tenant != "" {
+		b = b.Claim("tenant", tenant)
+	}
+	tok, err := b.Build()
+	if err != nil {
+		i.t.Fatalf("build token: %v", err)
+	}
+	signed, err := jwt.Sign(tok, jwt.WithKey(jwa.RS256, i.key))
+	if err != nil {
+		i.t.Fatalf("sign token: %v", err)
+	}
+	return string(signed)
+}
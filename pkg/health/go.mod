@@ -0,0 +1,3 @@
+module github.com/yoyo1025/k8s-vote-platform/pkg/health
+
+go 1.25.1
@@ -0,0 +1,74 @@
+// Package health runs the dependency checks behind the services' /readyz
+// endpoints and builds the JSON body they return.
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultTimeout bounds each check when a service does not configure one.
+const DefaultTimeout = 2 * time.Second
+
+// Check is a named dependency probe. A failing optional check is reported
+// but does not make the service unready.
+type Check struct {
+	Name     string
+	Fn       func(ctx context.Context) error
+	Optional bool
+}
+
+// Response is the /readyz body.
+type Response struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+// Result is the outcome of a single check.
+type Result struct {
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+// Run executes all checks concurrently, each bounded by timeout, and reports
+// whether every required check passed.
+func Run(ctx context.Context, timeout time.Duration, checks []Check) (Response, bool) {
+	resp := Response{Status: "ok", Checks: make(map[string]Result, len(checks))}
+	ready := true
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for _, chk := range checks {
+		wg.Add(1)
+		go func(chk Check) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			start := time.Now()
+			err := chk.Fn(ctx)
+			res := Result{Status: "ok", DurationMS: time.Since(start).Milliseconds()}
+			if err != nil {
+				res.Status = "fail"
+				res.Error = err.Error()
+			}
+
+			mu.Lock()
+			resp.Checks[chk.Name] = res
+			if err != nil && !chk.Optional {
+				ready = false
+			}
+			mu.Unlock()
+		}(chk)
+	}
+	wg.Wait()
+
+	if !ready {
+		resp.Status = "fail"
+	}
+	return resp, ready
+}
@@ -0,0 +1,68 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	t.Run("all checks pass", func(t *testing.T) {
+		resp, ready := Run(context.Background(), time.Second, []Check{
+			{Name: "a", Fn: func(context.Context) error { return nil }},
+			{Name: "b", Fn: func(context.Context) error { return nil }},
+		})
+		if !ready || resp.Status != "ok" {
+			t.Fatalf("expected ready, got %+v", resp)
+		}
+		if len(resp.Checks) != 2 {
+			t.Fatalf("expected 2 check results, got %d", len(resp.Checks))
+		}
+	})
+
+	t.Run("failing check marks not ready", func(t *testing.T) {
+		resp, ready := Run(context.Background(), time.Second, []Check{
+			{Name: "ok", Fn: func(context.Context) error { return nil }},
+			{Name: "broken", Fn: func(context.Context) error { return errors.New("boom") }},
+		})
+		if ready || resp.Status != "fail" {
+			t.Fatalf("expected not ready, got %+v", resp)
+		}
+		if got := resp.Checks["broken"]; got.Status != "fail" || got.Error != "boom" {
+			t.Fatalf("unexpected broken result: %+v", got)
+		}
+		if got := resp.Checks["ok"]; got.Status != "ok" {
+			t.Fatalf("unexpected ok result: %+v", got)
+		}
+	})
+
+	t.Run("failing optional check keeps ready", func(t *testing.T) {
+		resp, ready := Run(context.Background(), time.Second, []Check{
+			{Name: "ok", Fn: func(context.Context) error { return nil }},
+			{Name: "degraded", Optional: true, Fn: func(context.Context) error { return errors.New("boom") }},
+		})
+		if !ready || resp.Status != "ok" {
+			t.Fatalf("expected ready, got %+v", resp)
+		}
+		if got := resp.Checks["degraded"]; got.Status != "fail" {
+			t.Fatalf("unexpected degraded result: %+v", got)
+		}
+	})
+
+	t.Run("slow check is bounded by timeout", func(t *testing.T) {
+		start := time.Now()
+		_, ready := Run(context.Background(), 20*time.Millisecond, []Check{
+			{Name: "slow", Fn: func(ctx context.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			}},
+		})
+		if ready {
+			t.Fatal("expected slow check to fail")
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Fatalf("check was not bounded: %v", elapsed)
+		}
+	})
+}
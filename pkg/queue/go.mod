@@ -0,0 +1,21 @@
+module github.com/yoyo1025/k8s-vote-platform/pkg/queue
+
+go 1.25.1
+
+require (
+	github.com/alicebob/miniredis/v2 v2.30.0
+	github.com/nats-io/nats.go v1.47.0
+	github.com/redis/go-redis/v9 v9.6.1
+)
+
+require (
+	github.com/alicebob/gopher-json v0.0.0-20200520072559-a9ecdc9d1d3a // indirect
+	github.com/cespare/xxhash/v2 v2.2.0 // indirect
+	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
+	github.com/klauspost/compress v1.18.0 // indirect
+	github.com/nats-io/nkeys v0.4.11 // indirect
+	github.com/nats-io/nuid v1.0.1 // indirect
+	github.com/yuin/gopher-lua v0.0.0-20220504180219-658193537a64 // indirect
+	golang.org/x/crypto v0.37.0 // indirect
+	golang.org/x/sys v0.32.0 // indirect
+)
@@ -0,0 +1,133 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Memory is an in-process queue implementing both Producer and Consumer with
+// single consumer-group semantics. It is intended for tests and for running
+// vote-api and the worker inside one binary during development; nothing
+// survives a restart.
+type Memory struct {
+	mu         sync.Mutex
+	seq        uint64
+	deliveries uint64
+	ready      []Message
+	pending    map[string]*memoryPending
+	// notify is closed and replaced whenever new messages arrive.
+	notify chan struct{}
+}
+
+type memoryPending struct {
+	msg         Message
+	seq         uint64
+	deliveredAt time.Time
+}
+
+// NewMemory returns an empty in-memory queue.
+func NewMemory() *Memory {
+	return &Memory{
+		pending: make(map[string]*memoryPending),
+		notify:  make(chan struct{}),
+	}
+}
+
+// Publish implements Producer.
+func (m *Memory) Publish(_ context.Context, entries []map[string]any) []error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now().UnixMilli()
+	for _, values := range entries {
+		m.seq++
+		copied := make(map[string]any, len(values))
+		for k, v := range values {
+			copied[k] = v
+		}
+		m.ready = append(m.ready, Message{ID: fmt.Sprintf("%d-%d", now, m.seq), Values: copied})
+	}
+	close(m.notify)
+	m.notify = make(chan struct{})
+	return make([]error, len(entries))
+}
+
+// Read implements Consumer.
+func (m *Memory) Read(ctx context.Context, count int, block time.Duration) ([]Message, error) {
+	timer := time.NewTimer(block)
+	defer timer.Stop()
+
+	for {
+		m.mu.Lock()
+		if len(m.ready) > 0 {
+			n := min(count, len(m.ready))
+			msgs := append([]Message(nil), m.ready[:n]...)
+			m.ready = m.ready[n:]
+			now := time.Now()
+			for _, msg := range msgs {
+				m.deliveries++
+				m.pending[msg.ID] = &memoryPending{msg: msg, seq: m.deliveries, deliveredAt: now}
+			}
+			m.mu.Unlock()
+			return msgs, nil
+		}
+		notify := m.notify
+		m.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timer.C:
+			return nil, nil
+		case <-notify:
+		}
+	}
+}
+
+// Claim implements Consumer, redelivering pending messages in delivery order.
+func (m *Memory) Claim(_ context.Context, minIdle time.Duration, count int) ([]Message, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	var idle []*memoryPending
+	for _, p := range m.pending {
+		if now.Sub(p.deliveredAt) >= minIdle {
+			idle = append(idle, p)
+		}
+	}
+	sort.Slice(idle, func(i, j int) bool { return idle[i].seq < idle[j].seq })
+	if len(idle) > count {
+		idle = idle[:count]
+	}
+
+	msgs := make([]Message, 0, len(idle))
+	for _, p := range idle {
+		p.deliveredAt = now
+		msgs = append(msgs, p.msg)
+	}
+	return msgs, nil
+}
+
+// Ack implements Consumer.
+func (m *Memory) Ack(_ context.Context, ids ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, id := range ids {
+		delete(m.pending, id)
+	}
+	return nil
+}
+
+// Pending reports how many messages are delivered but not yet acknowledged.
+func (m *Memory) Pending() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.pending)
+}
+
+// Close implements Producer and Consumer.
+func (m *Memory) Close() error { return nil }
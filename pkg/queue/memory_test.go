@@ -0,0 +1,63 @@
+package queue
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	q := NewMemory()
+
+	errs := q.Publish(ctx, []map[string]any{{"n": "1"}, {"n": "2"}, {"n": "3"}})
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("publish %d: %v", i, err)
+		}
+	}
+
+	msgs, err := q.Read(ctx, 2, time.Second)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if len(msgs) != 2 || msgs[0].Values["n"] != "1" || msgs[1].Values["n"] != "2" {
+		t.Fatalf("unexpected messages: %+v", msgs)
+	}
+	if err := q.Ack(ctx, msgs[0].ID); err != nil {
+		t.Fatalf("ack: %v", err)
+	}
+	if got := q.Pending(); got != 1 {
+		t.Fatalf("expected 1 pending, got %d", got)
+	}
+
+	// The unacknowledged message is redelivered once idle.
+	claimed, err := q.Claim(ctx, 0, 10)
+	if err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+	if len(claimed) != 1 || claimed[0].ID != msgs[1].ID {
+		t.Fatalf("unexpected claimed messages: %+v", claimed)
+	}
+	if claimed, _ := q.Claim(ctx, time.Hour, 10); len(claimed) != 0 {
+		t.Fatalf("expected nothing idle for an hour, got %+v", claimed)
+	}
+}
+
+func TestMemoryReadBlocksUntilPublish(t *testing.T) {
+	ctx := context.Background()
+	q := NewMemory()
+
+	if msgs, err := q.Read(ctx, 1, 10*time.Millisecond); err != nil || len(msgs) != 0 {
+		t.Fatalf("expected empty read after timeout, got %v %v", msgs, err)
+	}
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		q.Publish(ctx, []map[string]any{{"n": "1"}})
+	}()
+	msgs, err := q.Read(ctx, 1, time.Second)
+	if err != nil || len(msgs) != 1 {
+		t.Fatalf("expected one message, got %v %v", msgs, err)
+	}
+}
@@ -0,0 +1,188 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// NATSConfig describes the JetStream stream carrying votes.
+type NATSConfig struct {
+	URL     string
+	Stream  string
+	Subject string
+}
+
+func connectNATS(ctx context.Context, cfg NATSConfig) (*nats.Conn, jetstream.JetStream, error) {
+	if cfg.URL == "" || cfg.Stream == "" || cfg.Subject == "" {
+		return nil, nil, errors.New("nats url, stream and subject are required")
+	}
+	nc, err := nats.Connect(cfg.URL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("nats connect: %w", err)
+	}
+	js, err := jetstream.New(nc)
+	if err != nil {
+		nc.Close()
+		return nil, nil, fmt.Errorf("jetstream: %w", err)
+	}
+	if _, err := js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:     cfg.Stream,
+		Subjects: []string{cfg.Subject},
+	}); err != nil {
+		nc.Close()
+		return nil, nil, fmt.Errorf("create stream %s: %w", cfg.Stream, err)
+	}
+	return nc, js, nil
+}
+
+// NATSProducer publishes entries to a JetStream subject as JSON.
+type NATSProducer struct {
+	nc      *nats.Conn
+	js      jetstream.JetStream
+	subject string
+}
+
+// NewNATSProducer connects to NATS and ensures the stream exists.
+func NewNATSProducer(ctx context.Context, cfg NATSConfig) (*NATSProducer, error) {
+	nc, js, err := connectNATS(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &NATSProducer{nc: nc, js: js, subject: cfg.Subject}, nil
+}
+
+// Publish implements Producer. Entries are published asynchronously and the
+// call waits for every acknowledgement from the server.
+func (p *NATSProducer) Publish(ctx context.Context, entries []map[string]any) []error {
+	errs := make([]error, len(entries))
+	futures := make([]jetstream.PubAckFuture, len(entries))
+	for i, values := range entries {
+		data, err := json.Marshal(values)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		futures[i], errs[i] = p.js.PublishAsync(p.subject, data)
+	}
+	for i, f := range futures {
+		if f == nil {
+			continue
+		}
+		select {
+		case <-f.Ok():
+		case err := <-f.Err():
+			errs[i] = err
+		case <-ctx.Done():
+			errs[i] = ctx.Err()
+		}
+	}
+	return errs
+}
+
+// Close implements Producer.
+func (p *NATSProducer) Close() error {
+	p.nc.Close()
+	return nil
+}
+
+// NATSConsumer pulls from a durable JetStream consumer. The durable name plays
+// the role of a Redis consumer group.
+type NATSConsumer struct {
+	nc       *nats.Conn
+	consumer jetstream.Consumer
+
+	mu       sync.Mutex
+	inflight map[string]jetstream.Msg
+}
+
+// NewNATSConsumer connects to NATS and creates or updates the durable
+// consumer. Unacknowledged messages are redelivered after ackWait.
+func NewNATSConsumer(ctx context.Context, cfg NATSConfig, durable string, ackWait time.Duration) (*NATSConsumer, error) {
+	nc, js, err := connectNATS(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	cons, err := js.CreateOrUpdateConsumer(ctx, cfg.Stream, jetstream.ConsumerConfig{
+		Durable:       durable,
+		AckPolicy:     jetstream.AckExplicitPolicy,
+		AckWait:       ackWait,
+		FilterSubject: cfg.Subject,
+	})
+	if err != nil {
+		nc.Close()
+		return nil, fmt.Errorf("create consumer %s: %w", durable, err)
+	}
+	return &NATSConsumer{nc: nc, consumer: cons, inflight: make(map[string]jetstream.Msg)}, nil
+}
+
+// Read implements Consumer.
+func (c *NATSConsumer) Read(ctx context.Context, count int, block time.Duration) ([]Message, error) {
+	batch, err := c.consumer.Fetch(count, jetstream.FetchMaxWait(block))
+	if err != nil {
+		return nil, err
+	}
+
+	var msgs []Message
+	for m := range batch.Messages() {
+		meta, err := m.Metadata()
+		if err != nil {
+			return msgs, fmt.Errorf("message metadata: %w", err)
+		}
+		id := fmt.Sprintf("%d-%d", meta.Timestamp.UnixMilli(), meta.Sequence.Stream)
+
+		var values map[string]any
+		if err := json.Unmarshal(m.Data(), &values); err != nil {
+			// Surface as an empty message so the caller can reject and ack it.
+			values = map[string]any{}
+		}
+
+		c.mu.Lock()
+		c.inflight[id] = m
+		c.mu.Unlock()
+		msgs = append(msgs, Message{ID: id, Values: values})
+	}
+	if err := batch.Error(); err != nil && !errors.Is(err, nats.ErrTimeout) {
+		return msgs, err
+	}
+	if err := ctx.Err(); err != nil {
+		return msgs, err
+	}
+	return msgs, nil
+}
+
+// Claim implements Consumer. JetStream redelivers unacknowledged messages to
+// the durable consumer after AckWait, so there is nothing to claim.
+func (c *NATSConsumer) Claim(context.Context, time.Duration, int) ([]Message, error) {
+	return nil, nil
+}
+
+// Ack implements Consumer.
+func (c *NATSConsumer) Ack(_ context.Context, ids ...string) error {
+	var errs []error
+	for _, id := range ids {
+		c.mu.Lock()
+		m, ok := c.inflight[id]
+		delete(c.inflight, id)
+		c.mu.Unlock()
+		if !ok {
+			continue
+		}
+		if err := m.Ack(); err != nil {
+			errs = append(errs, fmt.Errorf("ack %s: %w", id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close implements Consumer.
+func (c *NATSConsumer) Close() error {
+	c.nc.Close()
+	return nil
+}
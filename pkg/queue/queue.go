@@ -0,0 +1,51 @@
+// Package queue abstracts the transport that carries votes from vote-api to
+// the worker so the services are not tied to Redis Streams.
+package queue
+
+import (
+	"context"
+	"time"
+)
+
+// Supported backend names for service configuration.
+const (
+	BackendRedis  = "redis"
+	BackendMemory = "memory"
+	BackendNATS   = "nats"
+)
+
+// Message is a single entry read from the queue. ID is unique within the
+// queue and ordered by publication time ("<unix ms>-<seq>").
+type Message struct {
+	ID     string
+	Values map[string]any
+}
+
+// Producer publishes vote entries.
+type Producer interface {
+	// Publish enqueues entries and returns one error slot per entry so
+	// callers can report partial failures.
+	Publish(ctx context.Context, entries []map[string]any) []error
+	Close() error
+}
+
+// Consumer reads vote entries as a member of a consumer group. Messages stay
+// pending until acknowledged and are redelivered if the consumer dies.
+type Consumer interface {
+	// Read returns up to count new messages, waiting at most block.
+	Read(ctx context.Context, count int, block time.Duration) ([]Message, error)
+	// Claim takes over messages another consumer has left unacknowledged for
+	// at least minIdle. Backends that redeliver on their own return nil.
+	Claim(ctx context.Context, minIdle time.Duration, count int) ([]Message, error)
+	// Ack marks messages as processed.
+	Ack(ctx context.Context, ids ...string) error
+	Close() error
+}
+
+// Releaser is implemented by consumers whose membership is tracked by the
+// broker. Release is called on shutdown after the last Ack; it optionally
+// hands unacknowledged messages to a peer and deregisters the consumer once
+// it owns none. It reports how many messages remain pending on the consumer.
+type Releaser interface {
+	Release(ctx context.Context, handoff bool) (int, error)
+}
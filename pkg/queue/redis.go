@@ -0,0 +1,208 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisProducer appends entries to a Redis Stream with pipelined XADD.
+type RedisProducer struct {
+	client *redis.Client
+	stream string
+}
+
+// NewRedisProducer returns a producer writing to stream. The client is owned
+// by the caller and is not closed by Close.
+func NewRedisProducer(client *redis.Client, stream string) *RedisProducer {
+	return &RedisProducer{client: client, stream: stream}
+}
+
+// Publish implements Producer.
+func (p *RedisProducer) Publish(ctx context.Context, entries []map[string]any) []error {
+	errs := make([]error, len(entries))
+	pipe := p.client.Pipeline()
+	cmds := make([]*redis.StringCmd, len(entries))
+	for i, values := range entries {
+		cmds[i] = pipe.XAdd(ctx, &redis.XAddArgs{Stream: p.stream, Values: values})
+	}
+	// Exec only reports the first failure; inspect each command instead.
+	_, _ = pipe.Exec(ctx)
+	for i, cmd := range cmds {
+		errs[i] = cmd.Err()
+	}
+	return errs
+}
+
+// Close implements Producer.
+func (p *RedisProducer) Close() error { return nil }
+
+// RedisConsumer reads a Redis Stream through a consumer group.
+type RedisConsumer struct {
+	client   *redis.Client
+	stream   string
+	group    string
+	consumer string
+}
+
+// NewRedisConsumer ensures group exists on stream and returns a consumer
+// registered as consumer. The client is owned by the caller.
+func NewRedisConsumer(ctx context.Context, client *redis.Client, stream, group, consumer string) (*RedisConsumer, error) {
+	if err := EnsureRedisGroup(ctx, client, stream, group); err != nil {
+		return nil, err
+	}
+	return &RedisConsumer{client: client, stream: stream, group: group, consumer: consumer}, nil
+}
+
+// EnsureRedisGroup creates group on stream (and the stream itself) if needed.
+func EnsureRedisGroup(ctx context.Context, client *redis.Client, stream, group string) error {
+	if err := client.XGroupCreateMkStream(ctx, stream, group, "0").Err(); err != nil {
+		if strings.Contains(err.Error(), "BUSYGROUP") {
+			return nil
+		}
+		return fmt.Errorf("create group %s: %w", group, err)
+	}
+	return nil
+}
+
+// Read implements Consumer.
+func (c *RedisConsumer) Read(ctx context.Context, count int, block time.Duration) ([]Message, error) {
+	streams, err := c.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    c.group,
+		Consumer: c.consumer,
+		Streams:  []string{c.stream, ">"},
+		Count:    int64(count),
+		Block:    block,
+	}).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		if strings.Contains(err.Error(), "NOGROUP") {
+			// The stream was deleted or trimmed away entirely; recreate it.
+			return nil, EnsureRedisGroup(ctx, c.client, c.stream, c.group)
+		}
+		return nil, err
+	}
+
+	var msgs []Message
+	for _, stream := range streams {
+		for _, msg := range stream.Messages {
+			msgs = append(msgs, Message{ID: msg.ID, Values: msg.Values})
+		}
+	}
+	return msgs, nil
+}
+
+// Claim implements Consumer using XAUTOCLAIM. XAUTOCLAIM scans a bounded
+// part of the pending list per call, so it is repeated until count entries
+// are claimed or the whole list was scanned.
+func (c *RedisConsumer) Claim(ctx context.Context, minIdle time.Duration, count int) ([]Message, error) {
+	start := "0-0"
+	var claimed []Message
+
+	for len(claimed) < count {
+		msgs, next, err := c.client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+			Stream:   c.stream,
+			Group:    c.group,
+			Consumer: c.consumer,
+			MinIdle:  minIdle,
+			Start:    start,
+			Count:    int64(count - len(claimed)),
+		}).Result()
+		if err != nil {
+			if errors.Is(err, redis.Nil) {
+				break
+			}
+			return nil, err
+		}
+
+		start = next
+		if len(msgs) == 0 {
+			break
+		}
+		for _, msg := range msgs {
+			claimed = append(claimed, Message{ID: msg.ID, Values: msg.Values})
+		}
+		if next == "0-0" {
+			break
+		}
+	}
+	return claimed, nil
+}
+
+// Ack implements Consumer.
+func (c *RedisConsumer) Ack(ctx context.Context, ids ...string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return c.client.XAck(ctx, c.stream, c.group, ids...).Err()
+}
+
+// Close implements Consumer.
+func (c *RedisConsumer) Close() error { return nil }
+
+// Release implements Releaser. Pending entries are moved to the most
+// recently active peer with XCLAIM, which restarts their idle time: the peer
+// now owns them, and other consumers' XAUTOCLAIM leaves them alone until the
+// peer has had an idle timeout to process them. XGROUP DELCONSUMER drops a
+// consumer's pending entries, so it is only issued once none are left.
+func (c *RedisConsumer) Release(ctx context.Context, handoff bool) (int, error) {
+	pending, err := consumerPending(ctx, c.client, c.stream, c.group, c.consumer)
+	if err != nil {
+		return 0, err
+	}
+
+	left := len(pending)
+	if left > 0 && handoff {
+		peer, err := c.activePeer(ctx)
+		if err != nil {
+			return left, err
+		}
+		if peer != "" {
+			if left, err = handOffPending(ctx, c.client, c.stream, c.group, c.consumer, peer); err != nil {
+				return left, err
+			}
+		}
+	}
+
+	if left > 0 {
+		return left, nil
+	}
+	deleted, err := deleteIdleConsumer(ctx, c.client, c.stream, c.group, c.consumer, 0)
+	if err != nil {
+		return 0, fmt.Errorf("delete consumer %s: %w", c.consumer, err)
+	}
+	if !deleted {
+		// Something was delivered to this consumer after the check.
+		pending, err := consumerPending(ctx, c.client, c.stream, c.group, c.consumer)
+		return len(pending), err
+	}
+	return 0, nil
+}
+
+// activePeer returns the group member other than this consumer that was
+// seen most recently, or "" when there is none.
+func (c *RedisConsumer) activePeer(ctx context.Context) (string, error) {
+	consumers, err := c.client.XInfoConsumers(ctx, c.stream, c.group).Result()
+	if err != nil {
+		return "", fmt.Errorf("list consumers: %w", err)
+	}
+	var (
+		peer string
+		idle time.Duration
+	)
+	for _, cons := range consumers {
+		if cons.Name == c.consumer {
+			continue
+		}
+		if peer == "" || cons.Idle < idle {
+			peer, idle = cons.Name, cons.Idle
+		}
+	}
+	return peer, nil
+}
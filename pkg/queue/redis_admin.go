@@ -0,0 +1,255 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisGroupInfo returns XINFO GROUPS data for group, or nil when the stream
+// or group does not exist yet.
+func RedisGroupInfo(ctx context.Context, client *redis.Client, stream, group string) (*redis.XInfoGroup, error) {
+	groups, err := client.XInfoGroups(ctx, stream).Result()
+	if err != nil {
+		if strings.Contains(err.Error(), "no such key") {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("xinfo groups: %w", err)
+	}
+	for i := range groups {
+		if groups[i].Name == group {
+			return &groups[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// TrimRedisStream removes entries every consumer group of the stream has
+// both received and acknowledged, keeping anything newer than retention.
+// Shadow and other secondary groups hold trimming back like the primary, so
+// groups that are no longer consumed must be destroyed. It returns the
+// number of entries removed; trimming is approximate, so a few extra entries
+// may survive.
+func TrimRedisStream(ctx context.Context, client *redis.Client, stream string, retention time.Duration) (int64, error) {
+	groups, err := client.XInfoGroups(ctx, stream).Result()
+	if err != nil {
+		if strings.Contains(err.Error(), "no such key") {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("xinfo groups: %w", err)
+	}
+	if len(groups) == 0 {
+		return 0, nil
+	}
+
+	var minID string
+	for _, g := range groups {
+		low, err := groupLowWatermark(ctx, client, stream, g)
+		if err != nil {
+			return 0, err
+		}
+		if low == "" {
+			// The group has not received anything yet.
+			return 0, nil
+		}
+		if minID == "" || CompareStreamIDs(low, minID) < 0 {
+			minID = low
+		}
+	}
+
+	if retention > 0 {
+		retentionID := fmt.Sprintf("%d-0", time.Now().Add(-retention).UnixMilli())
+		if CompareStreamIDs(retentionID, minID) < 0 {
+			minID = retentionID
+		}
+	}
+
+	return client.XTrimMinIDApprox(ctx, stream, minID, 0).Result()
+}
+
+// groupLowWatermark returns the oldest entry group may still need: its
+// oldest pending entry, or the last delivered one. It is "" when the group
+// has received nothing.
+func groupLowWatermark(ctx context.Context, client *redis.Client, stream string, g redis.XInfoGroup) (string, error) {
+	low := g.LastDeliveredID
+	if low == "" || low == "0-0" {
+		return "", nil
+	}
+	if g.Pending > 0 {
+		pending, err := client.XPending(ctx, stream, g.Name).Result()
+		if err != nil {
+			return "", fmt.Errorf("xpending %s: %w", g.Name, err)
+		}
+		if pending.Count > 0 && CompareStreamIDs(pending.Lower, low) < 0 {
+			low = pending.Lower
+		}
+	}
+	return low, nil
+}
+
+// RemoveIdleRedisConsumers deletes members of group that have been idle for
+// at least minIdle, other than keep. Entries still pending on an idle
+// consumer are first claimed by keep, since XGROUP DELCONSUMER would drop
+// them; a consumer is only deleted once it owns none. When keep is "",
+// consumers with pending entries are left in place. It returns the names of
+// the removed consumers.
+func RemoveIdleRedisConsumers(ctx context.Context, client *redis.Client, stream, group string, minIdle time.Duration, keep string) ([]string, error) {
+	consumers, err := client.XInfoConsumers(ctx, stream, group).Result()
+	if err != nil {
+		return nil, fmt.Errorf("xinfo consumers: %w", err)
+	}
+	var removed []string
+	for _, c := range consumers {
+		if c.Name == keep || c.Idle < minIdle {
+			continue
+		}
+		if c.Pending > 0 {
+			if keep == "" {
+				continue
+			}
+			left, err := handOffPending(ctx, client, stream, group, c.Name, keep)
+			if err != nil {
+				return removed, err
+			}
+			if left > 0 {
+				continue
+			}
+		}
+		// The consumer may have read entries since it was listed, so the
+		// checks are repeated atomically with the deletion.
+		deleted, err := deleteIdleConsumer(ctx, client, stream, group, c.Name, minIdle)
+		if err != nil {
+			return removed, fmt.Errorf("delete consumer %s: %w", c.Name, err)
+		}
+		if !deleted {
+			continue
+		}
+		removed = append(removed, c.Name)
+	}
+	return removed, nil
+}
+
+// deleteIdleConsumerScript runs XGROUP DELCONSUMER only if the consumer owns
+// no pending entries and has been idle for at least ARGV[3] milliseconds, so
+// no entry can be dropped by a read racing the deletion. It returns 1 when
+// the consumer was deleted.
+var deleteIdleConsumerScript = redis.NewScript(`
+local pending = redis.call('XPENDING', KEYS[1], ARGV[1], '-', '+', 1, ARGV[2])
+if #pending > 0 then
+  return 0
+end
+
+-- Idle time is not what keeps entries safe, so a server that does not
+-- report it only skips that check.
+for _, consumer in ipairs(redis.call('XINFO', 'CONSUMERS', KEYS[1], ARGV[1])) do
+  local fields = {}
+  for i = 1, #consumer, 2 do
+    fields[consumer[i]] = consumer[i + 1]
+  end
+  local idle = tonumber(fields['idle'])
+  if fields['name'] == ARGV[2] and idle and idle < tonumber(ARGV[3]) then
+    return 0
+  end
+end
+
+redis.call('XGROUP', 'DELCONSUMER', KEYS[1], ARGV[1], ARGV[2])
+return 1
+`)
+
+// deleteIdleConsumer removes consumer from group if it owns no pending
+// entries and has been idle for at least minIdle, and reports whether it did.
+func deleteIdleConsumer(ctx context.Context, client *redis.Client, stream, group, consumer string, minIdle time.Duration) (bool, error) {
+	n, err := deleteIdleConsumerScript.Run(ctx, client, []string{stream}, group, consumer, minIdle.Milliseconds()).Int()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+// handOffPending moves every entry pending on from to the consumer to with
+// XCLAIM and returns how many are still pending on from afterwards. The
+// entries' idle time restarts, so the new owner's regular XAUTOCLAIM picks
+// them up after its idle timeout and other consumers do not race it for
+// them.
+func handOffPending(ctx context.Context, client *redis.Client, stream, group, from, to string) (int, error) {
+	pending, err := consumerPending(ctx, client, stream, group, from)
+	if err != nil || len(pending) == 0 {
+		return 0, err
+	}
+	pipe := client.Pipeline()
+	for _, p := range pending {
+		pipe.XClaimJustID(ctx, &redis.XClaimArgs{
+			Stream:   stream,
+			Group:    group,
+			Consumer: to,
+			Messages: []string{p.ID},
+		})
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return len(pending), fmt.Errorf("hand off %s to %s: %w", from, to, err)
+	}
+	if pending, err = consumerPending(ctx, client, stream, group, from); err != nil {
+		return 0, err
+	}
+	return len(pending), nil
+}
+
+// consumerPending lists every entry pending on consumer.
+func consumerPending(ctx context.Context, client *redis.Client, stream, group, consumer string) ([]redis.XPendingExt, error) {
+	const page = 1000
+	var all []redis.XPendingExt
+	start := "-"
+	for {
+		pending, err := client.XPendingExt(ctx, &redis.XPendingExtArgs{
+			Stream:   stream,
+			Group:    group,
+			Start:    start,
+			End:      "+",
+			Count:    page,
+			Consumer: consumer,
+		}).Result()
+		if errors.Is(err, redis.Nil) {
+			return all, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("list pending: %w", err)
+		}
+		all = append(all, pending...)
+		if len(pending) < page {
+			return all, nil
+		}
+		start = "(" + pending[len(pending)-1].ID
+	}
+}
+
+// CompareStreamIDs orders two "<ms>-<seq>" stream IDs.
+func CompareStreamIDs(a, b string) int {
+	am, as := splitStreamID(a)
+	bm, bs := splitStreamID(b)
+	switch {
+	case am != bm:
+		if am < bm {
+			return -1
+		}
+		return 1
+	case as != bs:
+		if as < bs {
+			return -1
+		}
+		return 1
+	default:
+		return 0
+	}
+}
+
+func splitStreamID(id string) (uint64, uint64) {
+	msStr, seqStr, _ := strings.Cut(id, "-")
+	ms, _ := strconv.ParseUint(msStr, 10, 64)
+	seq, _ := strconv.ParseUint(seqStr, 10, 64)
+	return ms, seq
+}
@@ -0,0 +1,214 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestCompareStreamIDs(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"1-0", "2-0", -1},
+		{"2-0", "1-5", 1},
+		{"5-1", "5-2", -1},
+		{"10-0", "9-0", 1},
+		{"3-3", "3-3", 0},
+	}
+	for _, tc := range cases {
+		if got := CompareStreamIDs(tc.a, tc.b); got != tc.want {
+			t.Errorf("CompareStreamIDs(%q, %q) = %d, want %d", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
+
+func TestRemoveIdleRedisConsumersHandsOffPending(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = rdb.Close() })
+
+	const stream, group = "stream:votes", "tally"
+	if err := EnsureRedisGroup(ctx, rdb, stream, group); err != nil {
+		t.Fatalf("ensure group: %v", err)
+	}
+	for range 3 {
+		if err := rdb.XAdd(ctx, &redis.XAddArgs{Stream: stream, Values: map[string]any{"k": "v"}}).Err(); err != nil {
+			t.Fatalf("xadd: %v", err)
+		}
+	}
+	read := func(consumer string, ack bool) string {
+		t.Helper()
+		streams, err := rdb.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group: group, Consumer: consumer, Streams: []string{stream, ">"}, Count: 1,
+		}).Result()
+		if err != nil {
+			t.Fatalf("xreadgroup %s: %v", consumer, err)
+		}
+		id := streams[0].Messages[0].ID
+		if ack {
+			if err := rdb.XAck(ctx, stream, group, id).Err(); err != nil {
+				t.Fatalf("xack: %v", err)
+			}
+		}
+		return id
+	}
+	orphan := read("busy", false)
+	read("dead", true)
+	read("self", true)
+
+	removed, err := RemoveIdleRedisConsumers(ctx, rdb, stream, group, 0, "self")
+	if err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	slices.Sort(removed)
+	if !slices.Equal(removed, []string{"busy", "dead"}) {
+		t.Fatalf("removed %v, want [busy dead]", removed)
+	}
+
+	// busy's unacknowledged entry now belongs to self instead of being lost.
+	pending, err := rdb.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream: stream, Group: group, Start: "-", End: "+", Count: 10,
+	}).Result()
+	if err != nil {
+		t.Fatalf("xpending: %v", err)
+	}
+	if len(pending) != 1 || pending[0].ID != orphan || pending[0].Consumer != "self" {
+		t.Fatalf("pending = %+v, want %s on self", pending, orphan)
+	}
+}
+
+func TestRemoveIdleRedisConsumersWithoutKeepLeavesPendingOwners(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = rdb.Close() })
+
+	const stream, group = "stream:votes", "tally"
+	if err := EnsureRedisGroup(ctx, rdb, stream, group); err != nil {
+		t.Fatalf("ensure group: %v", err)
+	}
+	if err := rdb.XAdd(ctx, &redis.XAddArgs{Stream: stream, Values: map[string]any{"k": "v"}}).Err(); err != nil {
+		t.Fatalf("xadd: %v", err)
+	}
+	if err := rdb.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group: group, Consumer: "busy", Streams: []string{stream, ">"}, Count: 1,
+	}).Err(); err != nil {
+		t.Fatalf("xreadgroup: %v", err)
+	}
+
+	removed, err := RemoveIdleRedisConsumers(ctx, rdb, stream, group, 0, "")
+	if err != nil || len(removed) != 0 {
+		t.Fatalf("removed %v, %v; want nothing", removed, err)
+	}
+}
+
+func TestDeleteIdleConsumerKeepsPendingEntries(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = rdb.Close() })
+
+	const stream, group = "stream:votes", "tally"
+	if err := EnsureRedisGroup(ctx, rdb, stream, group); err != nil {
+		t.Fatalf("ensure group: %v", err)
+	}
+	if err := rdb.XAdd(ctx, &redis.XAddArgs{Stream: stream, Values: map[string]any{"k": "v"}}).Err(); err != nil {
+		t.Fatalf("xadd: %v", err)
+	}
+	// The consumer reads an entry after it was listed as idle and empty.
+	streams, err := rdb.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group: group, Consumer: "racer", Streams: []string{stream, ">"}, Count: 1,
+	}).Result()
+	if err != nil {
+		t.Fatalf("xreadgroup: %v", err)
+	}
+	id := streams[0].Messages[0].ID
+
+	deleted, err := deleteIdleConsumer(ctx, rdb, stream, group, "racer", time.Minute)
+	if err != nil || deleted {
+		t.Fatalf("delete = %v, %v; want the consumer kept", deleted, err)
+	}
+	pending, err := rdb.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream: stream, Group: group, Start: "-", End: "+", Count: 10,
+	}).Result()
+	if err != nil || len(pending) != 1 || pending[0].ID != id || pending[0].Consumer != "racer" {
+		t.Fatalf("pending = %+v, %v; want %s still on racer", pending, err, id)
+	}
+
+	if err := rdb.XAck(ctx, stream, group, id).Err(); err != nil {
+		t.Fatalf("xack: %v", err)
+	}
+	if deleted, err := deleteIdleConsumer(ctx, rdb, stream, group, "racer", time.Minute); err != nil || !deleted {
+		t.Fatalf("delete after ack = %v, %v; want the consumer removed", deleted, err)
+	}
+}
+
+func TestTrimRedisStreamFollowsSlowestGroup(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = rdb.Close() })
+
+	const stream = "stream:votes"
+	for _, group := range []string{"tally", "tally-shadow"} {
+		if err := EnsureRedisGroup(ctx, rdb, stream, group); err != nil {
+			t.Fatalf("ensure group: %v", err)
+		}
+	}
+	var ids []string
+	for i := 1; i <= 4; i++ {
+		id, err := rdb.XAdd(ctx, &redis.XAddArgs{Stream: stream, ID: fmt.Sprintf("%d-0", i), Values: map[string]any{"k": "v"}}).Result()
+		if err != nil {
+			t.Fatalf("xadd: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	consume := func(group string, n int64) {
+		t.Helper()
+		streams, err := rdb.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group: group, Consumer: "c", Streams: []string{stream, ">"}, Count: n,
+		}).Result()
+		if err != nil {
+			t.Fatalf("xreadgroup %s: %v", group, err)
+		}
+		for _, msg := range streams[0].Messages {
+			if err := rdb.XAck(ctx, stream, group, msg.ID).Err(); err != nil {
+				t.Fatalf("xack: %v", err)
+			}
+		}
+	}
+	trim := func() []string {
+		t.Helper()
+		if _, err := TrimRedisStream(ctx, rdb, stream, 0); err != nil {
+			t.Fatalf("trim: %v", err)
+		}
+		msgs, err := rdb.XRange(ctx, stream, "-", "+").Result()
+		if err != nil {
+			t.Fatalf("xrange: %v", err)
+		}
+		var left []string
+		for _, m := range msgs {
+			left = append(left, m.ID)
+		}
+		return left
+	}
+
+	// The shadow group has not read anything, so nothing may go.
+	consume("tally", 4)
+	if left := trim(); !slices.Equal(left, ids) {
+		t.Fatalf("stream = %v, want all of %v", left, ids)
+	}
+	// Once the shadow group is at 2-0, only what precedes it goes.
+	consume("tally-shadow", 2)
+	if left := trim(); !slices.Equal(left, ids[1:]) {
+		t.Fatalf("stream = %v, want %v", left, ids[1:])
+	}
+}
@@ -0,0 +1,157 @@
+package queue
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestRedisConsumerClaimHonoursCount(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = rdb.Close() })
+
+	const stream, group = "stream:votes", "tally"
+	dead, err := NewRedisConsumer(ctx, rdb, stream, group, "dead")
+	if err != nil {
+		t.Fatalf("consumer: %v", err)
+	}
+	for range 5 {
+		if err := rdb.XAdd(ctx, &redis.XAddArgs{Stream: stream, Values: map[string]any{"k": "v"}}).Err(); err != nil {
+			t.Fatalf("xadd: %v", err)
+		}
+	}
+	if msgs, err := dead.Read(ctx, 5, 0); err != nil || len(msgs) != 5 {
+		t.Fatalf("read = %d, %v", len(msgs), err)
+	}
+
+	live, err := NewRedisConsumer(ctx, rdb, stream, group, "live")
+	if err != nil {
+		t.Fatalf("consumer: %v", err)
+	}
+	for _, want := range []int{2, 2, 1, 0} {
+		msgs, err := live.Claim(ctx, 0, 2)
+		if err != nil {
+			t.Fatalf("claim: %v", err)
+		}
+		if len(msgs) != want {
+			t.Fatalf("claimed %d entries, want %d", len(msgs), want)
+		}
+		if err := live.Ack(ctx, ids(msgs)...); err != nil {
+			t.Fatalf("ack: %v", err)
+		}
+	}
+}
+
+func TestRedisConsumerReleaseHandsOffPending(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = rdb.Close() })
+
+	const stream, group = "stream:votes", "tally"
+	start := time.Now()
+	mr.SetTime(start)
+
+	leaving, err := NewRedisConsumer(ctx, rdb, stream, group, "leaving")
+	if err != nil {
+		t.Fatalf("consumer: %v", err)
+	}
+	live, err := NewRedisConsumer(ctx, rdb, stream, group, "live")
+	if err != nil {
+		t.Fatalf("consumer: %v", err)
+	}
+	for range 3 {
+		if err := rdb.XAdd(ctx, &redis.XAddArgs{Stream: stream, Values: map[string]any{"k": "v"}}).Err(); err != nil {
+			t.Fatalf("xadd: %v", err)
+		}
+	}
+	unacked, err := leaving.Read(ctx, 2, 0)
+	if err != nil || len(unacked) != 2 {
+		t.Fatalf("read = %d, %v", len(unacked), err)
+	}
+	if msgs, err := live.Read(ctx, 1, 0); err != nil || len(msgs) != 1 {
+		t.Fatalf("read = %d, %v", len(msgs), err)
+	} else if err := live.Ack(ctx, ids(msgs)...); err != nil {
+		t.Fatalf("ack: %v", err)
+	}
+
+	// The entries have been pending for an hour when the consumer leaves.
+	mr.SetTime(start.Add(time.Hour))
+	left, err := leaving.Release(ctx, true)
+	if err != nil || left != 0 {
+		t.Fatalf("release = %d, %v; want 0, nil", left, err)
+	}
+
+	pending, err := rdb.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream: stream, Group: group, Start: "-", End: "+", Count: 10,
+	}).Result()
+	if err != nil {
+		t.Fatalf("xpending: %v", err)
+	}
+	if len(pending) != 2 {
+		t.Fatalf("pending = %+v, want the 2 handed-off entries", pending)
+	}
+	for i, p := range pending {
+		if p.ID != unacked[i].ID || p.Consumer != "live" {
+			t.Fatalf("pending[%d] = %+v, want %s on live", i, p, unacked[i].ID)
+		}
+		// The handoff restarts the idle time, so other consumers do not
+		// claim the entries away from the new owner straight away.
+		if p.Idle >= time.Minute {
+			t.Fatalf("pending[%d] idle = %s, want it reset", i, p.Idle)
+		}
+	}
+
+	consumers, err := rdb.XInfoConsumers(ctx, stream, group).Result()
+	if err != nil {
+		t.Fatalf("xinfo consumers: %v", err)
+	}
+	if len(consumers) != 1 || consumers[0].Name != "live" {
+		t.Fatalf("consumers = %+v, want only live", consumers)
+	}
+}
+
+func TestRedisConsumerReleaseKeepsConsumerWithPending(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = rdb.Close() })
+
+	const stream, group = "stream:votes", "tally"
+	leaving, err := NewRedisConsumer(ctx, rdb, stream, group, "leaving")
+	if err != nil {
+		t.Fatalf("consumer: %v", err)
+	}
+	if err := rdb.XAdd(ctx, &redis.XAddArgs{Stream: stream, Values: map[string]any{"k": "v"}}).Err(); err != nil {
+		t.Fatalf("xadd: %v", err)
+	}
+	if msgs, err := leaving.Read(ctx, 1, 0); err != nil || len(msgs) != 1 {
+		t.Fatalf("read = %d, %v", len(msgs), err)
+	}
+
+	// Without a peer to take it, deleting the consumer would drop the entry.
+	left, err := leaving.Release(ctx, true)
+	if err != nil || left != 1 {
+		t.Fatalf("release = %d, %v; want 1, nil", left, err)
+	}
+	consumers, err := rdb.XInfoConsumers(ctx, stream, group).Result()
+	if err != nil {
+		t.Fatalf("xinfo consumers: %v", err)
+	}
+	if len(consumers) != 1 || consumers[0].Name != "leaving" || consumers[0].Pending != 1 {
+		t.Fatalf("consumers = %+v, want leaving with 1 pending", consumers)
+	}
+}
+
+func ids(msgs []Message) []string {
+	out := make([]string, 0, len(msgs))
+	for _, m := range msgs {
+		out = append(out, m.ID)
+	}
+	return out
+}
@@ -0,0 +1,147 @@
+// Package totals reads election totals from Postgres. It is shared by the
+// services that serve results so they report the same numbers in the same
+// order.
+package totals
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Defaults used when a request names no tenant or election. They match what
+// vote-api assigns to votes.
+const (
+	DefaultTenant   = "default"
+	DefaultElection = "default"
+)
+
+// Candidate is one candidate's totals within an election.
+type Candidate struct {
+	ID    int64
+	Count int64
+	// Name is empty for candidates without metadata.
+	Name string
+	// Percentage is Count as a share of all votes in the election (0-100).
+	Percentage float64
+}
+
+// Election is an election's live totals.
+type Election struct {
+	Candidates []Candidate
+	// UpdatedAt is the time of the latest vote, zero if there is none.
+	UpdatedAt    time.Time
+	TotalBallots int64
+	// Version is bumped by every worker batch that changes the election.
+	Version int64
+}
+
+// TxBeginner is implemented by *pgxpool.Pool.
+type TxBeginner interface {
+	BeginTx(ctx context.Context, opts pgx.TxOptions) (pgx.Tx, error)
+}
+
+// WithTenant runs fn in a read-only, repeatable read transaction with
+// app.tenant set, which row-level security uses to hide other tenants' rows.
+// Queries in this package still filter on tenant_id explicitly so they stay
+// correct for roles that bypass RLS.
+func WithTenant(ctx context.Context, db TxBeginner, tenant string, fn func(pgx.Tx) error) error {
+	tx, err := db.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly, IsoLevel: pgx.RepeatableRead})
+	if err != nil {
+		return fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SELECT set_config('app.tenant', $1, true)`, tenant); err != nil {
+		return fmt.Errorf("set tenant: %w", err)
+	}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// Live returns an election's current totals from the totals view and the
+// summary the worker keeps in totals_meta. tx must come from WithTenant.
+func Live(ctx context.Context, tx pgx.Tx, tenant, election string) (Election, error) {
+	var e Election
+	var err error
+	e.Candidates, err = Query(ctx, tx, CandidatesSQL(`
+		SELECT candidate_id, count
+		FROM totals
+		WHERE tenant_id = $1 AND election_id = $2`), tenant, election)
+	if err != nil {
+		return Election{}, err
+	}
+
+	// Elections without votes have no row.
+	err = tx.QueryRow(ctx, `
+		SELECT last_updated_at, total_ballots, version
+		FROM totals_meta
+		WHERE tenant_id = $1 AND election_id = $2`,
+		tenant, election).Scan(&e.UpdatedAt, &e.TotalBallots, &e.Version)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+	case err != nil:
+		return Election{}, fmt.Errorf("query totals meta: %w", err)
+	default:
+		e.UpdatedAt = e.UpdatedAt.UTC()
+	}
+	return e, nil
+}
+
+// CandidatesSQL wraps a query yielding (candidate_id, count) so that every
+// candidate with metadata is reported, with its name, in display order. The
+// inner query and the candidates lookup share $1 (tenant) and $2 (election).
+func CandidatesSQL(inner string) string {
+	return `
+		SELECT candidate_id, COALESCE(t.count, 0), COALESCE(c.name, '')
+		FROM (` + inner + `) t
+		FULL JOIN (
+			SELECT candidate_id, name, display_order
+			FROM candidates
+			WHERE tenant_id = $1 AND election_id = $2
+		) c USING (candidate_id)
+		ORDER BY c.display_order NULLS LAST, candidate_id`
+}
+
+// Query runs a CandidatesSQL query and fills in each candidate's share of
+// the votes.
+func Query(ctx context.Context, tx pgx.Tx, sql string, args ...any) ([]Candidate, error) {
+	rows, err := tx.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query totals: %w", err)
+	}
+	defer rows.Close()
+
+	var (
+		candidates []Candidate
+		sum        int64
+	)
+	for rows.Next() {
+		var c Candidate
+		if err := rows.Scan(&c.ID, &c.Count, &c.Name); err != nil {
+			return nil, fmt.Errorf("scan totals: %w", err)
+		}
+		candidates = append(candidates, c)
+		sum += c.Count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows totals: %w", err)
+	}
+	setPercentages(candidates, sum)
+	return candidates, nil
+}
+
+// setPercentages sets each candidate's share of sum votes.
+func setPercentages(candidates []Candidate, sum int64) {
+	if sum <= 0 {
+		return
+	}
+	for i := range candidates {
+		candidates[i].Percentage = float64(candidates[i].Count) * 100 / float64(sum)
+	}
+}
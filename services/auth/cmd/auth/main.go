@@ -2,12 +2,18 @@ package main
 
 import (
 	"log"
+	"os"
+	"time"
 
 	"github.com/yoyo1025/k8s-vote-platform/services/auth/internal/auth"
 )
 
 func main() {
-	s, err := auth.New()
+	cfg := auth.Config{
+		ReadinessTimeout: durationDefault(os.Getenv("READINESS_TIMEOUT"), 2*time.Second),
+	}
+
+	s, err := auth.New(cfg)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -18,3 +24,17 @@ func main() {
 		log.Fatal(err)
 	}
 }
+
+func durationDefault(v string, def time.Duration) time.Duration {
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return def
+	}
+	if d <= 0 {
+		return def
+	}
+	return d
+}
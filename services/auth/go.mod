@@ -2,10 +2,13 @@ module github.com/yoyo1025/k8s-vote-platform/services/auth
 
 go 1.25.1
 
+replace github.com/yoyo1025/k8s-vote-platform/pkg/health => ../../pkg/health
+
 require (
 	github.com/golang-jwt/jwt/v5 v5.3.0
 	github.com/labstack/echo/v4 v4.13.4
 	github.com/lestrrat-go/jwx/v2 v2.1.6
+	github.com/yoyo1025/k8s-vote-platform/pkg/health v0.0.0-00010101000000-000000000000
 )
 
 require (
@@ -14,23 +14,40 @@ import (
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/labstack/echo/v4"
+	"github.com/lestrrat-go/jwx/v2/jwa"
 	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/yoyo1025/k8s-vote-platform/pkg/health"
 )
 
+// Config は auth の設定
+type Config struct {
+	// ReadinessTimeout は /readyz が各確認に待つ上限。0 以下なら既定値
+	ReadinessTimeout time.Duration
+}
+
 type Server struct {
 	e      *echo.Echo
 	priv   *rsa.PrivateKey
 	jwks   jwk.Set
 	keyID  string
 	issuer string
+	// defaultTenant はテナント未指定のログインに割り当てるテナント
+	defaultTenant string
+
+	readinessTimeout time.Duration
 }
 
 type loginReq struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
+	// Tenant は所属組織（省略時は AUTH_DEFAULT_TENANT）
+	Tenant string `json:"tenant"`
 }
 
-func New() (*Server, error) {
+func New(cfg Config) (*Server, error) {
+	if cfg.ReadinessTimeout <= 0 {
+		cfg.ReadinessTimeout = health.DefaultTimeout
+	}
 	e := echo.New()
 
 	// 1) 秘密鍵ロード（AUTH_PRIVATE_KEY_FILE があれば利用、無ければ生成）
@@ -47,6 +64,10 @@ func New() (*Server, error) {
 	if err := jwk.AssignKeyID(pubJWK); err != nil {
 		return nil, fmt.Errorf("assign kid: %w", err)
 	}
+	// 下流サービスは alg の無い鍵を検証に使わないため明示する
+	if err := pubJWK.Set(jwk.AlgorithmKey, jwa.RS256); err != nil {
+		return nil, fmt.Errorf("set alg: %w", err)
+	}
 	keyID := pubJWK.KeyID()
 	set := jwk.NewSet()
 	set.AddKey(pubJWK)
@@ -57,16 +78,20 @@ func New() (*Server, error) {
 		jwks:   set,
 		keyID:  keyID,
 		issuer: getenv("AUTH_ISSUER", "http://localhost:18080"),
+
+		defaultTenant: getenv("AUTH_DEFAULT_TENANT", "default"),
+
+		readinessTimeout: cfg.ReadinessTimeout,
 	}
 	s.routes()
 	return s, nil
 }
 
 func (s *Server) routes() {
-	// Liveness
-	s.e.GET("/healthz", func(c echo.Context) error {
-		return c.NoContent(http.StatusOK)
-	})
+	// Liveness / Readiness
+	s.e.GET("/healthz", s.handleLivez)
+	s.e.GET("/livez", s.handleLivez)
+	s.e.GET("/readyz", s.handleReadyz)
 
 	// JWKS（公開鍵配布）
 	s.e.GET("/.well-known/jwks.json", func(c echo.Context) error {
@@ -81,6 +106,11 @@ func (s *Server) routes() {
 			return c.JSON(http.StatusUnprocessableEntity, map[string]any{"error": "invalid payload"})
 		}
 
+		tenant := req.Tenant
+		if tenant == "" {
+			tenant = s.defaultTenant
+		}
+
 		now := time.Now()
 		claims := jwt.MapClaims{
 			"sub":   req.Email,
@@ -89,6 +119,8 @@ func (s *Server) routes() {
 			"iat":   now.Unix(),
 			"exp":   now.Add(30 * time.Minute).Unix(),
 			"scope": "read write",
+			// 下流サービスはこの値でデータをテナントごとに分離する
+			"tenant": tenant,
 		}
 
 		tok := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
@@ -0,0 +1,37 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/yoyo1025/k8s-vote-platform/pkg/health"
+)
+
+func (s *Server) healthChecks() []health.Check {
+	return []health.Check{
+		// 署名鍵と JWKS が揃っていないとトークン発行・検証ができない
+		{Name: "signing-key", Fn: func(context.Context) error {
+			if s.priv == nil || s.keyID == "" {
+				return errors.New("signing key not loaded")
+			}
+			if s.jwks == nil || s.jwks.Len() == 0 {
+				return errors.New("jwks is empty")
+			}
+			return nil
+		}},
+	}
+}
+
+func (s *Server) handleLivez(c echo.Context) error {
+	return c.NoContent(http.StatusOK)
+}
+
+func (s *Server) handleReadyz(c echo.Context) error {
+	resp, ready := health.Run(c.Request().Context(), s.readinessTimeout, s.healthChecks())
+	if !ready {
+		return c.JSON(http.StatusServiceUnavailable, resp)
+	}
+	return c.JSON(http.StatusOK, resp)
+}
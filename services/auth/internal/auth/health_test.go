@@ -0,0 +1,44 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/yoyo1025/k8s-vote-platform/pkg/health"
+)
+
+func TestReadyz(t *testing.T) {
+	t.Setenv("AUTH_PRIVATE_KEY_FILE", "")
+	s, err := New(Config{})
+	if err != nil {
+		t.Fatalf("new server: %v", err)
+	}
+
+	readyz := func() (int, health.Response) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		s.e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		var resp health.Response
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode readyz body %q: %v", rec.Body.String(), err)
+		}
+		return rec.Code, resp
+	}
+
+	if code, resp := readyz(); code != http.StatusOK || resp.Checks["signing-key"].Status != "ok" {
+		t.Fatalf("readyz = %d %+v, want 200 with signing-key ok", code, resp)
+	}
+
+	// 公開鍵が配布できない状態ではトークンを検証できないので not ready
+	s.jwks = jwk.NewSet()
+	code, resp := readyz()
+	if code != http.StatusServiceUnavailable || resp.Status != "fail" {
+		t.Fatalf("readyz = %d %+v, want 503", code, resp)
+	}
+	if got := resp.Checks["signing-key"]; got.Status != "fail" || got.Error != "jwks is empty" {
+		t.Fatalf("signing-key = %+v, want jwks is empty", got)
+	}
+}
@@ -3,14 +3,32 @@ package main
 import (
 	"log"
 	"os"
+	"strings"
+	"time"
 
+	"github.com/yoyo1025/k8s-vote-platform/pkg/authn"
 	httpapi "github.com/yoyo1025/k8s-vote-platform/services/result-api/internal/http"
 )
 
 func main() {
-	grpcTarget := getenv("RESULT_QUERY_ADDR", "result-query:50051")
+	cfg := httpapi.Config{
+		GRPCTarget:            getenv("RESULT_QUERY_ADDR", "result-query:50051"),
+		SSERetry:              durationDefault(os.Getenv("SSE_RETRY"), 3*time.Second),
+		SSEHeartbeat:          durationDefault(os.Getenv("SSE_HEARTBEAT"), 15*time.Second),
+		SSEMaxLifetime:        durationOrOff(os.Getenv("SSE_MAX_LIFETIME"), time.Hour),
+		ResubscribeBackoff:    durationDefault(os.Getenv("RESUBSCRIBE_BACKOFF"), 500*time.Millisecond),
+		ResubscribeMaxBackoff: durationDefault(os.Getenv("RESUBSCRIBE_MAX_BACKOFF"), 30*time.Second),
+		WSPingInterval:        durationDefault(os.Getenv("WS_PING_INTERVAL"), 30*time.Second),
+		WSOriginPatterns:      splitList(os.Getenv("WS_ORIGIN_PATTERNS")),
+		ReadinessTimeout:      durationDefault(os.Getenv("READINESS_TIMEOUT"), 2*time.Second),
+		Auth: authn.Config{
+			JWKSURL:  getenv("AUTH_JWKS_URL", "http://auth:18080/.well-known/jwks.json"),
+			Issuer:   os.Getenv("AUTH_ISSUER"),
+			Audience: getenv("AUTH_AUDIENCE", "vote-app"),
+		},
+	}
 
-	s, err := httpapi.New(grpcTarget)
+	s, err := httpapi.New(cfg)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -27,3 +45,37 @@ func getenv(k, def string) string {
 	}
 	return def
 }
+
+func durationDefault(v string, def time.Duration) time.Duration {
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return def
+	}
+	if d <= 0 {
+		return def
+	}
+	return d
+}
+
+// durationOrOff is durationDefault that also accepts "off", which disables
+// the setting by returning a negative duration.
+func durationOrOff(v string, def time.Duration) time.Duration {
+	if v == "off" {
+		return -1
+	}
+	return durationDefault(v, def)
+}
+
+// splitList parses a comma-separated list, ignoring empty entries.
+func splitList(v string) []string {
+	var out []string
+	for _, part := range strings.Split(v, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
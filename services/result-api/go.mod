@@ -1,18 +1,35 @@
 module github.com/yoyo1025/k8s-vote-platform/services/result-api
 
+go 1.25.1
+
 replace github.com/yoyo1025/k8s-vote-platform/gen/go => ../../gen/go
 
-go 1.25.1
+replace github.com/yoyo1025/k8s-vote-platform/pkg/authn => ../../pkg/authn
+
+replace github.com/yoyo1025/k8s-vote-platform/pkg/health => ../../pkg/health
 
 require (
+	github.com/coder/websocket v1.8.14
 	github.com/labstack/echo/v4 v4.13.4
 	github.com/yoyo1025/k8s-vote-platform/gen/go v0.0.0-00010101000000-000000000000
+	github.com/yoyo1025/k8s-vote-platform/pkg/authn v0.0.0-00010101000000-000000000000
+	github.com/yoyo1025/k8s-vote-platform/pkg/health v0.0.0-00010101000000-000000000000
+	google.golang.org/grpc v1.75.1
 )
 
 require (
+	github.com/decred/dcrd/dcrec/secp256k1/v4 v4.4.0 // indirect
+	github.com/goccy/go-json v0.10.3 // indirect
 	github.com/labstack/gommon v0.4.2 // indirect
+	github.com/lestrrat-go/blackmagic v1.0.3 // indirect
+	github.com/lestrrat-go/httpcc v1.0.1 // indirect
+	github.com/lestrrat-go/httprc v1.0.6 // indirect
+	github.com/lestrrat-go/iter v1.0.2 // indirect
+	github.com/lestrrat-go/jwx/v2 v2.1.6 // indirect
+	github.com/lestrrat-go/option v1.0.1 // indirect
 	github.com/mattn/go-colorable v0.1.14 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
+	github.com/segmentio/asm v1.2.0 // indirect
 	github.com/valyala/bytebufferpool v1.0.0 // indirect
 	github.com/valyala/fasttemplate v1.2.2 // indirect
 	golang.org/x/crypto v0.39.0 // indirect
@@ -20,6 +37,5 @@ require (
 	golang.org/x/sys v0.33.0 // indirect
 	golang.org/x/text v0.26.0 // indirect
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20250707201910-8d1bb00bc6a7 // indirect
-	google.golang.org/grpc v1.75.1 // indirect
 	google.golang.org/protobuf v1.36.9 // indirect
 )
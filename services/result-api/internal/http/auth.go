@@ -0,0 +1,48 @@
+package httpapi
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/yoyo1025/k8s-vote-platform/pkg/authn"
+)
+
+const claimsContextKey = "authn.claims"
+
+// authenticate は Bearer トークンを auth サービスの JWKS で検証し、クレームを
+// ハンドラに渡す。result-api は Kong を通らずにも届くため、トークンが無い・
+// 検証できないリクエストは default テナントに落とさず拒否する
+//
+// allowQuery が true のルートは ?access_token= も受け付ける
+// ブラウザの EventSource / WebSocket はヘッダを付けられないため
+func (s *Server) authenticate(allowQuery bool) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			r := c.Request()
+			var (
+				claims authn.Claims
+				err    error
+			)
+			if token := c.QueryParam("access_token"); allowQuery && token != "" && r.Header.Get("Authorization") == "" {
+				claims, err = s.verifier.Verify(r.Context(), token)
+			} else {
+				claims, err = s.verifier.FromRequest(r)
+			}
+			if err != nil {
+				status := authn.Status(err)
+				if status == http.StatusUnauthorized {
+					c.Response().Header().Set("WWW-Authenticate", `Bearer realm="result-api"`)
+				}
+				return c.JSON(status, map[string]any{"error": err.Error()})
+			}
+			c.Set(claimsContextKey, claims)
+			return next(c)
+		}
+	}
+}
+
+// tenantOf は authenticate で検証済みのテナントを返す
+func tenantOf(c echo.Context) string {
+	claims, _ := c.Get(claimsContextKey).(authn.Claims)
+	return claims.Tenant
+}
@@ -0,0 +1,32 @@
+package httpapi
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	resultv1 "github.com/yoyo1025/k8s-vote-platform/gen/go/result/v1"
+	"github.com/yoyo1025/k8s-vote-platform/pkg/health"
+)
+
+func (s *Server) healthChecks() []health.Check {
+	return []health.Check{
+		// 上流 result-query への疎通確認（gRPC Ping）
+		{Name: "result-query", Fn: func(ctx context.Context) error {
+			_, err := s.client.Ping(ctx, &resultv1.PingRequest{})
+			return err
+		}},
+	}
+}
+
+func (s *Server) handleLivez(c echo.Context) error {
+	return c.NoContent(http.StatusOK)
+}
+
+func (s *Server) handleReadyz(c echo.Context) error {
+	resp, ready := health.Run(c.Request().Context(), s.readinessTimeout, s.healthChecks())
+	if !ready {
+		return c.JSON(http.StatusServiceUnavailable, resp)
+	}
+	return c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,132 @@
+package httpapi
+
+import (
+	"context"
+	"sync"
+
+	resultv1 "github.com/yoyo1025/k8s-vote-platform/gen/go/result/v1"
+)
+
+// hub は tenant/選挙ごとに上流の SubscribeTotals を 1 本だけ張り、
+// このレプリカに繋がっている SSE / WebSocket クライアントへ配る
+// gRPC ストリーム数は視聴者数ではなく選挙数に比例する
+type hub struct {
+	s *Server
+
+	mu     sync.Mutex
+	topics map[topicKey]*topic
+}
+
+type topicKey struct {
+	tenant   string
+	election string
+}
+
+// topic は 1 選挙分の上流ストリームと購読者
+type topic struct {
+	cancel context.CancelFunc
+	subs   map[*hubSub]struct{}
+	// latest は最後に受信した集計。後から来た購読者にすぐ渡す
+	latest *resultv1.SubscribeTotalsResponse
+}
+
+// hubSub は 1 クライアント分の購読
+// 集計は毎回全量なので、読み遅れたクライアントには最新だけを残す
+type hubSub struct {
+	key topicKey
+	// ch は容量 1。未読があれば新しい集計で置き換える
+	ch chan *resultv1.SubscribeTotalsResponse
+	// done は上流が再購読しても直らないエラーで終わったときに閉じる
+	done chan struct{}
+
+	// last / sent は hub.mu の下で扱う。送信済みの sequence 以下は配らない
+	last uint64
+	sent bool
+}
+
+func newHub(s *Server) *hub {
+	return &hub{s: s, topics: make(map[topicKey]*topic)}
+}
+
+// subscribe は election の購読を始める。resumeAfter は Last-Event-ID / resume_after
+// 使い終わったら unsubscribe すること
+func (h *hub) subscribe(tenant, election string, resumeAfter uint64) *hubSub {
+	key := topicKey{tenant, electionOrDefault(election)}
+	sub := &hubSub{
+		key:  key,
+		ch:   make(chan *resultv1.SubscribeTotalsResponse, 1),
+		done: make(chan struct{}),
+		// 初回接続は最新の集計を必ず受け取る（初投票前の sequence 0 を含む）
+		last: resumeAfter,
+		sent: resumeAfter > 0,
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	t, ok := h.topics[key]
+	if !ok {
+		ctx, cancel := context.WithCancel(context.Background())
+		t = &topic{cancel: cancel, subs: make(map[*hubSub]struct{})}
+		h.topics[key] = t
+		go h.run(ctx, key, t)
+	}
+	t.subs[sub] = struct{}{}
+	if t.latest != nil {
+		sub.offer(t.latest)
+	}
+	return sub
+}
+
+// unsubscribe は購読をやめる。最後の購読者が抜けたら上流ストリームも閉じる
+func (h *hub) unsubscribe(sub *hubSub) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	t, ok := h.topics[sub.key]
+	if !ok {
+		return
+	}
+	delete(t.subs, sub)
+	if len(t.subs) == 0 {
+		t.cancel()
+		delete(h.topics, sub.key)
+	}
+}
+
+// run は上流から受信した集計を topic の購読者へ配る
+// 上流は recvTotals が再購読し続けるので、ここが終わるのは購読者がいなくなったときか
+// 直らないエラーのときだけ
+func (h *hub) run(ctx context.Context, key topicKey, t *topic) {
+	req := &resultv1.SubscribeTotalsRequest{Tenant: key.tenant, ElectionId: key.election}
+	for msg := range h.s.recvTotals(ctx, req) {
+		h.mu.Lock()
+		t.latest = msg
+		for sub := range t.subs {
+			sub.offer(msg)
+		}
+		h.mu.Unlock()
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.topics[key] == t {
+		delete(h.topics, key)
+	}
+	for sub := range t.subs {
+		close(sub.done)
+	}
+	t.subs = nil
+	t.cancel()
+}
+
+// offer は msg を未読として積む。hub.mu を保持して呼ぶ
+func (sub *hubSub) offer(msg *resultv1.SubscribeTotalsResponse) {
+	if sub.sent && msg.GetSequence() <= sub.last {
+		return
+	}
+	sub.last, sub.sent = msg.GetSequence(), true
+	select {
+	case <-sub.ch:
+	default:
+	}
+	sub.ch <- msg
+}
@@ -0,0 +1,90 @@
+package httpapi
+
+import (
+	"context"
+	"log"
+	"math/rand/v2"
+	"time"
+
+	resultv1 "github.com/yoyo1025/k8s-vote-platform/gen/go/result/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// backoff は上流への再購読間隔。attempt ごとに倍にし、max で頭打ちにする
+type backoff struct {
+	min time.Duration
+	max time.Duration
+}
+
+// delay は attempt 回目（0 始まり）の待ち時間
+// result-query の再起動で全クライアントが同時に再接続しないよう、半分をランダムにずらす
+func (b backoff) delay(attempt int) time.Duration {
+	d := b.min
+	for i := 0; i < attempt && d < b.max; i++ {
+		d *= 2
+	}
+	d = min(d, b.max)
+	return d/2 + rand.N(d/2+1)
+}
+
+// permanent は再購読しても直らない上流エラーか
+func permanent(err error) bool {
+	switch status.Code(err) {
+	case codes.InvalidArgument, codes.PermissionDenied, codes.Unauthenticated, codes.Unimplemented:
+		return true
+	}
+	return false
+}
+
+// recvTotals は req の SubscribeTotals を張り、受信した集計を返すチャネルを返す
+// 上流が切れたら最後に受け取った sequence を resume_after にして再購読するので、
+// result-query の再起動をまたいでも重複や取りこぼし無く流れ続ける
+// チャネルは ctx の終了か、再購読しても直らないエラーで閉じる
+func (s *Server) recvTotals(ctx context.Context, req *resultv1.SubscribeTotalsRequest) <-chan *resultv1.SubscribeTotalsResponse {
+	msgs := make(chan *resultv1.SubscribeTotalsResponse)
+	go func() {
+		defer close(msgs)
+		resumeAfter := req.GetResumeAfter()
+		attempt := 0
+		for {
+			started := time.Now()
+			stream, err := s.client.SubscribeTotals(ctx, &resultv1.SubscribeTotalsRequest{
+				Tenant:      req.GetTenant(),
+				ElectionId:  req.GetElectionId(),
+				ResumeAfter: resumeAfter,
+			})
+			for err == nil {
+				var msg *resultv1.SubscribeTotalsResponse
+				msg, err = stream.Recv()
+				if err != nil {
+					break
+				}
+				attempt = 0
+				resumeAfter = msg.GetSequence()
+				select {
+				case msgs <- msg:
+				case <-ctx.Done():
+					return
+				}
+			}
+			if ctx.Err() != nil || permanent(err) {
+				return
+			}
+			// 十分長く繋がっていたストリームの切断は初回扱いにする
+			if time.Since(started) > s.resubscribe.max {
+				attempt = 0
+			}
+
+			wait := s.resubscribe.delay(attempt)
+			attempt++
+			log.Printf("totals stream for %s/%s lost: %v; resubscribing in %s", req.GetTenant(), req.GetElectionId(), err, wait)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(wait):
+			}
+		}
+	}()
+	return msgs
+}
@@ -2,39 +2,123 @@ package httpapi
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/labstack/echo/v4"
 	resultv1 "github.com/yoyo1025/k8s-vote-platform/gen/go/result/v1"
+	"github.com/yoyo1025/k8s-vote-platform/pkg/authn"
+	"github.com/yoyo1025/k8s-vote-platform/pkg/health"
 	"google.golang.org/grpc"
 )
 
+const (
+	defaultSSERetry       = 3 * time.Second
+	defaultSSEHeartbeat   = 15 * time.Second
+	defaultWSPingInterval = 30 * time.Second
+	defaultSSEMaxLifetime = time.Hour
+
+	defaultResubscribeBackoff    = 500 * time.Millisecond
+	defaultResubscribeMaxBackoff = 30 * time.Second
+)
+
+// Config は result-api の設定
+type Config struct {
+	// GRPCTarget は result-query の gRPC アドレス
+	GRPCTarget string
+	// SSERetry は SSE の retry: で EventSource に提案する再接続間隔
+	SSERetry time.Duration
+	// SSEHeartbeat はデータが無い間に :ping コメントを送る間隔
+	SSEHeartbeat time.Duration
+	// SSEMaxLifetime は 1 本の SSE 接続を維持する上限。0 は既定値、負なら無制限
+	SSEMaxLifetime time.Duration
+	// ResubscribeBackoff / ResubscribeMaxBackoff は上流ストリームが切れたときの
+	// 再購読間隔の初期値と上限（指数バックオフ + ジッタ）
+	ResubscribeBackoff    time.Duration
+	ResubscribeMaxBackoff time.Duration
+	// WSPingInterval は WebSocket で ping を送る間隔。同じ時間内に pong が無ければ切断する
+	WSPingInterval time.Duration
+	// WSOriginPatterns は WebSocket を許可する Origin のホストパターン
+	// 空なら同一オリジンのみ
+	WSOriginPatterns []string
+	// Auth は auth サービスの署名鍵（JWKS）の場所と、要求するクレーム
+	Auth authn.Config
+	// ReadinessTimeout は /readyz が各依存先の確認に待つ上限。0 以下なら既定値
+	ReadinessTimeout time.Duration
+}
+
 type Server struct {
 	e      *echo.Echo
 	client resultv1.ResultServiceClient
+	hub    *hub
+
+	readinessTimeout time.Duration
+	sseRetry         time.Duration
+	sseHeartbeat     time.Duration
+	sseMaxLifetime   time.Duration
+	resubscribe      backoff
+	wsPingInterval   time.Duration
+	wsOriginPatterns []string
+	verifier         *authn.Verifier
 }
 
-func New(grpcTarget string) (*Server, error) {
-	conn, err := grpc.Dial(grpcTarget, grpc.WithInsecure())
+func New(cfg Config) (*Server, error) {
+	if cfg.SSERetry <= 0 {
+		cfg.SSERetry = defaultSSERetry
+	}
+	if cfg.SSEHeartbeat <= 0 {
+		cfg.SSEHeartbeat = defaultSSEHeartbeat
+	}
+	if cfg.SSEMaxLifetime == 0 {
+		cfg.SSEMaxLifetime = defaultSSEMaxLifetime
+	}
+	if cfg.ResubscribeBackoff <= 0 {
+		cfg.ResubscribeBackoff = defaultResubscribeBackoff
+	}
+	if cfg.ResubscribeMaxBackoff < cfg.ResubscribeBackoff {
+		cfg.ResubscribeMaxBackoff = max(defaultResubscribeMaxBackoff, cfg.ResubscribeBackoff)
+	}
+	if cfg.WSPingInterval <= 0 {
+		cfg.WSPingInterval = defaultWSPingInterval
+	}
+	if cfg.ReadinessTimeout <= 0 {
+		cfg.ReadinessTimeout = health.DefaultTimeout
+	}
+	// 鍵は初回の検証時に取得し、以後定期的に更新する（プロセスの寿命と同じ）
+	verifier, err := authn.New(context.Background(), cfg.Auth)
+	if err != nil {
+		return nil, fmt.Errorf("auth: %w", err)
+	}
+	conn, err := grpc.Dial(cfg.GRPCTarget, grpc.WithInsecure())
 	if err != nil {
 		return nil, fmt.Errorf("grpc dial: %w", err)
 	}
 	cli := resultv1.NewResultServiceClient(conn)
 
 	e := echo.New()
-	s := &Server{e: e, client: cli}
+	s := &Server{
+		e:                e,
+		client:           cli,
+		readinessTimeout: cfg.ReadinessTimeout,
+		sseRetry:         cfg.SSERetry,
+		sseHeartbeat:     cfg.SSEHeartbeat,
+		sseMaxLifetime:   cfg.SSEMaxLifetime,
+		resubscribe:      backoff{min: cfg.ResubscribeBackoff, max: cfg.ResubscribeMaxBackoff},
+		wsPingInterval:   cfg.WSPingInterval,
+		wsOriginPatterns: cfg.WSOriginPatterns,
+		verifier:         verifier,
+	}
+	s.hub = newHub(s)
 	s.routes()
 	return s, nil
 }
 
 func (s *Server) routes() {
 
-	s.e.GET("/healthz", func(c echo.Context) error {
-		return c.NoContent(http.StatusOK)
-	})
+	s.e.GET("/healthz", s.handleLivez)
+	s.e.GET("/livez", s.handleLivez)
+	s.e.GET("/readyz", s.handleReadyz)
 
 	// GET /api/v1/results -> gRPC GetTotals を呼んで JSON を返却
 	s.e.GET("/api/v1/results", func(c echo.Context) error {
@@ -42,66 +126,27 @@ func (s *Server) routes() {
 		ctx, cancel := context.WithTimeout(c.Request().Context(), 2*time.Second)
 		defer cancel()
 
-		resp, err := s.client.GetTotals(ctx, &resultv1.GetTotalsRequest{})
+		// ?as_of=RFC3339 で過去時点の集計（スナップショット or votes から再計算）
+		// ?election_id で選挙を指定（省略時は default）
+		resp, err := s.client.GetTotals(ctx, &resultv1.GetTotalsRequest{
+			AsOf:       c.QueryParam("as_of"),
+			Tenant:     tenantOf(c),
+			ElectionId: c.QueryParam("election_id"),
+		})
 		if err != nil {
-			return c.JSON(http.StatusServiceUnavailable, map[string]any{"error": err.Error()})
+			return grpcErrorJSON(c, err)
 		}
 		return c.JSON(http.StatusOK, resp)
-	})
+	}, s.authenticate(false))
 
-	s.e.GET("/api/v1/results/stream", func(c echo.Context) error {
-		ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Minute)
-		defer cancel()
+	// GET /api/v1/results/timeseries -> gRPC GetTimeSeries（分/時間単位の推移）
+	s.e.GET("/api/v1/results/timeseries", s.handleTimeSeries, s.authenticate(false))
 
-		stream, err := s.client.SubscribeTotals(ctx, &resultv1.SubscribeTotalsRequest{Tenant: "default"})
-		if err != nil {
-			return c.JSON(http.StatusServiceUnavailable, map[string]any{"error": err.Error()})
-		}
-		// SSE ヘッダ
-		c.Response().Header().Set(echo.HeaderContentType, "text/event-stream")
-		c.Response().Header().Set("Cache-Control", "no-cache")
-		c.Response().Header().Set("Connection", "keep-alive")
-		c.Response().WriteHeader(http.StatusOK)
-		flusher, ok := c.Response().Writer.(http.Flusher)
-		if !ok {
-			return c.String(http.StatusInternalServerError, "streaming unsupported")
-		}
+	// GET /api/v1/results/stream -> gRPC SubscribeTotals を SSE で中継
+	s.e.GET("/api/v1/results/stream", s.handleStream, s.authenticate(true))
 
-		// 1分ごとにハートビート送信
-		heartbeat := time.NewTicker(1 * time.Minute)
-		defer heartbeat.Stop()
-
-		// 初回コメント
-		_, _ = c.Response().Writer.Write([]byte(":ok\n\n"))
-		flusher.Flush()
-
-		// 受信ループ：gRPC → JSON → SSE data
-		for {
-			select {
-			case <-c.Request().Context().Done():
-				// ブラウザ切断。gRPC の ctx もキャンセルされる
-				return nil
-			case <-heartbeat.C:
-				// 心拍（コメント行）
-				_, _ = c.Response().Writer.Write([]byte(":ping\n\n"))
-				flusher.Flush()
-			default:
-				// ノンブロッキングだと忙しいので、短い受信待ちでブロック
-				msg, err := stream.Recv()
-				if err != nil {
-					// 下流切断：ここで終了（ブラウザ側は EventSource が再接続する）
-					// ここで 204 を返す必要はない（既にヘッダ送信済み）
-					return nil
-				}
-				b, _ := json.Marshal(msg) // 小さく行く：そのまま JSON に
-				// SSE フレーム：data: <json>\n\n
-				_, _ = c.Response().Writer.Write([]byte("data: "))
-				_, _ = c.Response().Writer.Write(b)
-				_, _ = c.Response().Writer.Write([]byte("\n\n"))
-				flusher.Flush()
-			}
-		}
-	})
+	// GET /api/v1/results/ws -> SSE を使えないクライアント向けの WebSocket 版
+	s.e.GET("/api/v1/results/ws", s.handleWebSocket, s.authenticate(true))
 }
 
 func (s *Server) Start(addr string) error {
@@ -0,0 +1,101 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	resultv1 "github.com/yoyo1025/k8s-vote-platform/gen/go/result/v1"
+)
+
+// handleStream は GET /api/v1/results/stream
+// 各イベントの id: には result-query の sequence（選挙の集計バージョン）を載せる
+// EventSource が再接続時に送る Last-Event-ID をそのまま resume_after に渡すので、
+// 受信済みの集計は再送されない
+func (s *Server) handleStream(c echo.Context) error {
+	resumeAfter, err := lastEventID(c.Request())
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]any{"error": err.Error()})
+	}
+
+	var (
+		ctx    context.Context
+		cancel context.CancelFunc
+	)
+	if s.sseMaxLifetime > 0 {
+		// 寿命で切ってもブラウザは Last-Event-ID 付きで再接続するので取りこぼさない
+		// 長寿命接続を定期的に張り替えさせ、レプリカ間の偏りを解消する
+		ctx, cancel = context.WithTimeout(c.Request().Context(), s.sseMaxLifetime)
+	} else {
+		ctx, cancel = context.WithCancel(c.Request().Context())
+	}
+	defer cancel()
+
+	// 同じ選挙を見ているクライアントとは上流ストリームを共有する
+	sub := s.hub.subscribe(tenantOf(c), c.QueryParam("election_id"), resumeAfter)
+	defer s.hub.unsubscribe(sub)
+
+	// SSE ヘッダ
+	c.Response().Header().Set(echo.HeaderContentType, "text/event-stream")
+	c.Response().Header().Set("Cache-Control", "no-cache")
+	c.Response().Header().Set("Connection", "keep-alive")
+	c.Response().WriteHeader(http.StatusOK)
+	flusher, ok := c.Response().Writer.(http.Flusher)
+	if !ok {
+		return c.String(http.StatusInternalServerError, "streaming unsupported")
+	}
+
+	// 初回：再接続間隔の提案とコメント
+	_, _ = fmt.Fprintf(c.Response().Writer, "retry: %d\n:ok\n\n", s.sseRetry.Milliseconds())
+	flusher.Flush()
+
+	// 受信は hub の goroutine が行うので、待機中もハートビートと切断検知が回る
+	// 上流が切れても再購読されるので、SSE 接続はそのまま維持する
+	heartbeat := time.NewTicker(s.sseHeartbeat)
+	defer heartbeat.Stop()
+
+	// 受信ループ：gRPC → JSON → SSE data
+	for {
+		select {
+		case <-ctx.Done():
+			// ブラウザ切断（または寿命切れ）。最後の視聴者なら unsubscribe で上流も閉じる
+			return nil
+		case <-heartbeat.C:
+			// 心拍（コメント行）。プロキシのアイドル切断を防ぐ
+			_, _ = c.Response().Writer.Write([]byte(":ping\n\n"))
+			flusher.Flush()
+		case <-sub.done:
+			// 再購読しても直らない上流エラー：ここで終了
+			// ここで 204 を返す必要はない（既にヘッダ送信済み）
+			return nil
+		case msg := <-sub.ch:
+			writeEvent(c.Response().Writer, msg)
+			flusher.Flush()
+		}
+	}
+}
+
+// writeEvent は 1 件の集計を SSE フレーム（id: <sequence>\ndata: <json>\n\n）で書き出す
+func writeEvent(w http.ResponseWriter, msg *resultv1.SubscribeTotalsResponse) {
+	b, _ := json.Marshal(msg) // 小さく行く：そのまま JSON に
+	_, _ = fmt.Fprintf(w, "id: %d\ndata: %s\n\n", msg.GetSequence(), b)
+}
+
+// lastEventID は Last-Event-ID ヘッダを sequence として読む
+// 未指定（初回接続）は 0
+func lastEventID(r *http.Request) (uint64, error) {
+	v := strings.TrimSpace(r.Header.Get("Last-Event-ID"))
+	if v == "" {
+		return 0, nil
+	}
+	seq, err := strconv.ParseUint(v, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid Last-Event-ID: %q", v)
+	}
+	return seq, nil
+}
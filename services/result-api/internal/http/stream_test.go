@@ -0,0 +1,338 @@
+package httpapi
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	resultv1 "github.com/yoyo1025/k8s-vote-platform/gen/go/result/v1"
+	"github.com/yoyo1025/k8s-vote-platform/pkg/authn/authntest"
+	"github.com/yoyo1025/k8s-vote-platform/pkg/health"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeResultClient は SubscribeTotals だけを実装する。ストリームは選挙ごとの
+// チャネルから受信し、ctx がキャンセルされるまでブロックする
+type fakeResultClient struct {
+	resultv1.ResultServiceClient
+	reqs     chan *resultv1.SubscribeTotalsRequest
+	canceled chan string
+
+	mu      sync.Mutex
+	streams map[string]chan *resultv1.SubscribeTotalsResponse
+	drops   map[string]chan error
+}
+
+func newFakeResultClient() *fakeResultClient {
+	return &fakeResultClient{
+		reqs:     make(chan *resultv1.SubscribeTotalsRequest, 8),
+		canceled: make(chan string, 8),
+		streams:  make(map[string]chan *resultv1.SubscribeTotalsResponse),
+		drops:    make(map[string]chan error),
+	}
+}
+
+// stream は election の上流ストリームへ流すチャネル
+func (f *fakeResultClient) stream(election string) chan *resultv1.SubscribeTotalsResponse {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	ch, ok := f.streams[election]
+	if !ok {
+		ch = make(chan *resultv1.SubscribeTotalsResponse)
+		f.streams[election] = ch
+	}
+	return ch
+}
+
+// drop は election の上流ストリームを err で切るためのチャネル
+func (f *fakeResultClient) drop(election string) chan error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	ch, ok := f.drops[election]
+	if !ok {
+		ch = make(chan error)
+		f.drops[election] = ch
+	}
+	return ch
+}
+
+func (f *fakeResultClient) SubscribeTotals(ctx context.Context, req *resultv1.SubscribeTotalsRequest, _ ...grpc.CallOption) (grpc.ServerStreamingClient[resultv1.SubscribeTotalsResponse], error) {
+	f.reqs <- req
+	return &fakeTotalsStream{ctx: ctx, f: f, election: req.GetElectionId()}, nil
+}
+
+type fakeTotalsStream struct {
+	grpc.ClientStream
+	ctx      context.Context
+	f        *fakeResultClient
+	election string
+}
+
+func (s *fakeTotalsStream) Recv() (*resultv1.SubscribeTotalsResponse, error) {
+	select {
+	case msg := <-s.f.stream(s.election):
+		return msg, nil
+	case err := <-s.f.drop(s.election):
+		return nil, err
+	case <-s.ctx.Done():
+		s.f.canceled <- s.election
+		return nil, s.ctx.Err()
+	}
+}
+
+// newStreamTestServer はテスト用の result-api を起動し、tenant "acme" のトークンを返す
+// opts で Server の設定を上書きできる
+func newStreamTestServer(t *testing.T, client resultv1.ResultServiceClient, opts ...func(*Server)) (*httptest.Server, string) {
+	t.Helper()
+	issuer := authntest.NewIssuer(t)
+	s := &Server{
+		e:                echo.New(),
+		client:           client,
+		readinessTimeout: health.DefaultTimeout,
+		sseRetry:         time.Second,
+		sseHeartbeat:     20 * time.Millisecond,
+		sseMaxLifetime:   time.Minute,
+		resubscribe:      backoff{min: time.Millisecond, max: 10 * time.Millisecond},
+		wsPingInterval:   time.Minute,
+		verifier:         issuer.Verifier(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.hub = newHub(s)
+	s.routes()
+	ts := httptest.NewServer(s.e)
+	t.Cleanup(ts.Close)
+	return ts, issuer.Token("alice", "acme")
+}
+
+func TestStreamSendsHeartbeatsWhileIdle(t *testing.T) {
+	client := newFakeResultClient()
+	ts, token := newStreamTestServer(t, client)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/api/v1/results/stream?election_id=e1", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Last-Event-ID", "7")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	<-client.reqs
+
+	// 上流から何も届かない間もハートビートが流れること
+	lines := bufio.NewScanner(resp.Body)
+	pings := 0
+	for pings < 2 && lines.Scan() {
+		if lines.Text() == ":ping" {
+			pings++
+		}
+	}
+	if pings < 2 {
+		t.Fatalf("got %d heartbeats before the stream ended: %v", pings, lines.Err())
+	}
+
+	// Last-Event-ID 以下の集計は再送しない
+	client.stream("e1") <- &resultv1.SubscribeTotalsResponse{ElectionId: "e1", Sequence: 7}
+	client.stream("e1") <- &resultv1.SubscribeTotalsResponse{ElectionId: "e1", Sequence: 8}
+	for lines.Scan() {
+		if strings.HasPrefix(lines.Text(), "id: ") {
+			if got := lines.Text(); got != "id: 8" {
+				t.Fatalf("event id line = %q, want %q", got, "id: 8")
+			}
+			break
+		}
+	}
+
+	// クライアント切断で上流ストリームもすぐに閉じること
+	cancel()
+	select {
+	case <-client.canceled:
+	case <-time.After(time.Second):
+		t.Fatal("upstream stream was not canceled after the client disconnected")
+	}
+}
+
+func TestStreamResubscribesAfterUpstreamLoss(t *testing.T) {
+	client := newFakeResultClient()
+	ts, token := newStreamTestServer(t, client)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/api/v1/results/stream?election_id=e1&access_token="+token, nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	defer resp.Body.Close()
+	<-client.reqs
+
+	lines := bufio.NewScanner(resp.Body)
+	nextID := func() string {
+		t.Helper()
+		for lines.Scan() {
+			if id, ok := strings.CutPrefix(lines.Text(), "id: "); ok {
+				return id
+			}
+		}
+		t.Fatalf("stream ended: %v", lines.Err())
+		return ""
+	}
+
+	client.stream("e1") <- &resultv1.SubscribeTotalsResponse{Sequence: 5}
+	if id := nextID(); id != "5" {
+		t.Fatalf("id = %s, want 5", id)
+	}
+
+	// result-query の再起動を模して上流を切る。SSE は切れずに続きから再購読すること
+	client.drop("e1") <- status.Error(codes.Unavailable, "result-query restarting")
+	select {
+	case req := <-client.reqs:
+		if req.GetResumeAfter() != 5 {
+			t.Fatalf("resubscribed with resume_after %d, want 5", req.GetResumeAfter())
+		}
+	case <-time.After(time.Second):
+		t.Fatal("did not resubscribe after the upstream stream was lost")
+	}
+
+	client.stream("e1") <- &resultv1.SubscribeTotalsResponse{Sequence: 6}
+	if id := nextID(); id != "6" {
+		t.Fatalf("id = %s, want 6", id)
+	}
+}
+
+func TestBackoffDelay(t *testing.T) {
+	b := backoff{min: 100 * time.Millisecond, max: time.Second}
+	for attempt, want := range []time.Duration{100, 200, 400, 800, 1000, 1000} {
+		want *= time.Millisecond
+		if attempt == 5 {
+			attempt = 50 // 上限で頭打ちになり、溢れないこと
+		}
+		for range 20 {
+			if d := b.delay(attempt); d < want/2 || d > want {
+				t.Fatalf("delay(%d) = %s, want within [%s, %s]", attempt, d, want/2, want)
+			}
+		}
+	}
+}
+
+func TestStreamSharesUpstreamPerElection(t *testing.T) {
+	client := newFakeResultClient()
+	ts, token := newStreamTestServer(t, client)
+
+	open := func(lastEventID string) (*bufio.Scanner, context.CancelFunc) {
+		t.Helper()
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/api/v1/results/stream?election_id=e1&access_token="+token, nil)
+		if lastEventID != "" {
+			req.Header.Set("Last-Event-ID", lastEventID)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request: %v", err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		lines := bufio.NewScanner(resp.Body)
+		// ヘッダと retry: を読み切ってから次へ（購読が hub に登録済みになる）
+		for lines.Scan() && lines.Text() != ":ok" {
+		}
+		return lines, cancel
+	}
+	nextID := func(lines *bufio.Scanner) string {
+		t.Helper()
+		for lines.Scan() {
+			if id, ok := strings.CutPrefix(lines.Text(), "id: "); ok {
+				return id
+			}
+		}
+		t.Fatalf("stream ended: %v", lines.Err())
+		return ""
+	}
+
+	first, closeFirst := open("")
+	client.stream("e1") <- &resultv1.SubscribeTotalsResponse{Sequence: 3}
+	if id := nextID(first); id != "3" {
+		t.Fatalf("first client id = %s, want 3", id)
+	}
+
+	// 後から来たクライアントは最新の集計をすぐ受け取り、上流は増えない
+	second, closeSecond := open("")
+	if id := nextID(second); id != "3" {
+		t.Fatalf("second client id = %s, want 3", id)
+	}
+	client.stream("e1") <- &resultv1.SubscribeTotalsResponse{Sequence: 4}
+	if a, b := nextID(first), nextID(second); a != "4" || b != "4" {
+		t.Fatalf("ids = %s, %s, want 4 for both", a, b)
+	}
+	if n := len(client.reqs); n != 1 {
+		t.Fatalf("%d upstream subscriptions, want 1", n)
+	}
+
+	// 最後の視聴者が抜けたときだけ上流を閉じる
+	closeFirst()
+	select {
+	case <-client.canceled:
+		t.Fatal("upstream canceled while a client was still watching")
+	case <-time.After(50 * time.Millisecond):
+	}
+	closeSecond()
+	select {
+	case <-client.canceled:
+	case <-time.After(time.Second):
+		t.Fatal("upstream stream was not canceled after the last client left")
+	}
+}
+
+func TestStreamRequiresVerifiedToken(t *testing.T) {
+	client := newFakeResultClient()
+	ts, token := newStreamTestServer(t, client)
+
+	get := func(query string) int {
+		t.Helper()
+		resp, err := http.Get(ts.URL + "/api/v1/results/stream?election_id=e1" + query)
+		if err != nil {
+			t.Fatalf("request: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// トークン無しは default テナントに落とさず拒否する
+	if code := get(""); code != http.StatusUnauthorized {
+		t.Fatalf("no token: status %d, want 401", code)
+	}
+	// 別の鍵で署名された（偽造）トークンで他テナントは読めない
+	forged := authntest.NewIssuer(t).Token("mallory", "acme")
+	if code := get("&access_token=" + forged); code != http.StatusUnauthorized {
+		t.Fatalf("forged token: status %d, want 401", code)
+	}
+	select {
+	case req := <-client.reqs:
+		t.Fatalf("unexpected upstream subscription %v", req)
+	default:
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/api/v1/results/stream?election_id=e1&access_token="+token, nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	defer resp.Body.Close()
+	if got := (<-client.reqs).GetTenant(); got != "acme" {
+		t.Fatalf("upstream tenant = %q, want acme", got)
+	}
+}
@@ -0,0 +1,54 @@
+package httpapi
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	resultv1 "github.com/yoyo1025/k8s-vote-platform/gen/go/result/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// handleTimeSeries は GET /api/v1/results/timeseries
+// クエリ: election_id（省略時は default）, resolution=minute|hour, from/to=RFC3339,
+// candidate_id=1,2 (複数指定可)
+func (s *Server) handleTimeSeries(c echo.Context) error {
+	req := &resultv1.GetTimeSeriesRequest{
+		Resolution: c.QueryParam("resolution"),
+		From:       c.QueryParam("from"),
+		To:         c.QueryParam("to"),
+		Tenant:     tenantOf(c),
+		ElectionId: c.QueryParam("election_id"),
+	}
+	for _, v := range c.QueryParams()["candidate_id"] {
+		for _, part := range strings.Split(v, ",") {
+			id, err := strconv.ParseUint(strings.TrimSpace(part), 10, 64)
+			if err != nil {
+				return c.JSON(http.StatusBadRequest, map[string]any{"error": "invalid candidate_id: " + part})
+			}
+			req.CandidateIds = append(req.CandidateIds, id)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 2*time.Second)
+	defer cancel()
+
+	resp, err := s.client.GetTimeSeries(ctx, req)
+	if err != nil {
+		return grpcErrorJSON(c, err)
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// grpcErrorJSON は gRPC エラーを HTTP に変換する
+// 入力エラーは 400、それ以外は上流障害として 503
+func grpcErrorJSON(c echo.Context, err error) error {
+	if st, ok := status.FromError(err); ok && st.Code() == codes.InvalidArgument {
+		return c.JSON(http.StatusBadRequest, map[string]any{"error": st.Message()})
+	}
+	return c.JSON(http.StatusServiceUnavailable, map[string]any{"error": err.Error()})
+}
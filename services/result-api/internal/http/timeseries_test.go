@@ -0,0 +1,120 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"testing"
+
+	resultv1 "github.com/yoyo1025/k8s-vote-platform/gen/go/result/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeTimeSeriesClient は GetTimeSeries のリクエストを記録し、err を返す
+type fakeTimeSeriesClient struct {
+	resultv1.ResultServiceClient
+	req *resultv1.GetTimeSeriesRequest
+	err error
+}
+
+func (f *fakeTimeSeriesClient) GetTimeSeries(_ context.Context, req *resultv1.GetTimeSeriesRequest, _ ...grpc.CallOption) (*resultv1.GetTimeSeriesResponse, error) {
+	f.req = req
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &resultv1.GetTimeSeriesResponse{Resolution: req.GetResolution(), ElectionId: req.GetElectionId()}, nil
+}
+
+func TestTimeSeriesMapsQueryParams(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		upstream error
+		wantCode int
+		// wantReq は上流に渡るリクエスト。nil なら上流を呼ばないこと
+		wantReq *resultv1.GetTimeSeriesRequest
+	}{
+		{
+			name:     "省略時はそのまま渡す",
+			query:    "",
+			wantCode: http.StatusOK,
+			wantReq:  &resultv1.GetTimeSeriesRequest{Tenant: "acme"},
+		},
+		{
+			name:     "全パラメータ",
+			query:    "?election_id=e1&resolution=hour&from=2024-05-01T00:00:00Z&to=2024-05-02T00:00:00Z",
+			wantCode: http.StatusOK,
+			wantReq: &resultv1.GetTimeSeriesRequest{
+				Tenant: "acme", ElectionId: "e1", Resolution: "hour",
+				From: "2024-05-01T00:00:00Z", To: "2024-05-02T00:00:00Z",
+			},
+		},
+		{
+			name:     "candidate_id はカンマ区切りと複数指定を併用できる",
+			query:    "?candidate_id=1,%202&candidate_id=3",
+			wantCode: http.StatusOK,
+			wantReq:  &resultv1.GetTimeSeriesRequest{Tenant: "acme", CandidateIds: []uint64{1, 2, 3}},
+		},
+		{name: "数値でない candidate_id", query: "?candidate_id=1,x", wantCode: http.StatusBadRequest},
+		{name: "負の candidate_id", query: "?candidate_id=-1", wantCode: http.StatusBadRequest},
+		{name: "空の candidate_id", query: "?candidate_id=1,", wantCode: http.StatusBadRequest},
+		{
+			name:     "上流の入力エラーは 400",
+			query:    "?resolution=day",
+			upstream: status.Error(codes.InvalidArgument, `unknown resolution "day"`),
+			wantCode: http.StatusBadRequest,
+			wantReq:  &resultv1.GetTimeSeriesRequest{Tenant: "acme", Resolution: "day"},
+		},
+		{
+			name:     "上流障害は 503",
+			query:    "",
+			upstream: status.Error(codes.Unavailable, "connection refused"),
+			wantCode: http.StatusServiceUnavailable,
+			wantReq:  &resultv1.GetTimeSeriesRequest{Tenant: "acme"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &fakeTimeSeriesClient{err: tt.upstream}
+			ts, token := newStreamTestServer(t, client)
+
+			req, _ := http.NewRequest(http.MethodGet, ts.URL+"/api/v1/results/timeseries"+tt.query, nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("request: %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tt.wantCode {
+				t.Fatalf("status %d, want %d", resp.StatusCode, tt.wantCode)
+			}
+			if tt.wantCode != http.StatusOK {
+				var body struct {
+					Error string `json:"error"`
+				}
+				if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || body.Error == "" {
+					t.Fatalf("error body = %+v (%v), want an error message", body, err)
+				}
+			}
+
+			got := client.req
+			if tt.wantReq == nil {
+				if got != nil {
+					t.Fatalf("unexpected upstream request %v", got)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatal("upstream was not called")
+			}
+			if got.GetTenant() != tt.wantReq.GetTenant() || got.GetElectionId() != tt.wantReq.GetElectionId() ||
+				got.GetResolution() != tt.wantReq.GetResolution() || got.GetFrom() != tt.wantReq.GetFrom() ||
+				got.GetTo() != tt.wantReq.GetTo() || !slices.Equal(got.GetCandidateIds(), tt.wantReq.GetCandidateIds()) {
+				t.Fatalf("upstream request = %v, want %v", got, tt.wantReq)
+			}
+		})
+	}
+}
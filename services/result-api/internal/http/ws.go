@@ -0,0 +1,237 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	"github.com/labstack/echo/v4"
+	resultv1 "github.com/yoyo1025/k8s-vote-platform/gen/go/result/v1"
+)
+
+const (
+	defaultElection = "default"
+
+	// maxWSSubscriptions は 1 接続で同時に購読できる選挙の上限
+	maxWSSubscriptions = 16
+	wsWriteTimeout     = 10 * time.Second
+)
+
+// wsClientMessage はクライアント → サーバのメッセージ
+//
+//	{"type":"subscribe","election_id":"e1","resume_after":12}
+//	{"type":"unsubscribe","election_id":"e1"}
+type wsClientMessage struct {
+	Type        string `json:"type"`
+	ElectionID  string `json:"election_id"`
+	ResumeAfter uint64 `json:"resume_after"`
+}
+
+// wsServerMessage はサーバ → クライアントのメッセージ
+// type は subscribed / unsubscribed / totals / error
+// totals の中身は SSE の data: と同じ JSON
+type wsServerMessage struct {
+	Type       string                            `json:"type"`
+	ElectionID string                            `json:"election_id,omitempty"`
+	Totals     *resultv1.SubscribeTotalsResponse `json:"totals,omitempty"`
+	Error      string                            `json:"error,omitempty"`
+}
+
+// wsSession は 1 本の WebSocket 接続と、その上の選挙ごとの購読
+type wsSession struct {
+	s      *Server
+	conn   *websocket.Conn
+	tenant string
+	// cancel は接続全体を終わらせる（書き込み失敗や ping 無応答時）
+	cancel context.CancelFunc
+
+	mu   sync.Mutex
+	subs map[string]*wsSubscription
+	wg   sync.WaitGroup
+}
+
+type wsSubscription struct {
+	cancel context.CancelFunc
+}
+
+// handleWebSocket は GET /api/v1/results/ws
+// SubscribeTotals を WebSocket のテキストフレーム（JSON）に中継する
+// 1 接続で複数の選挙を subscribe / unsubscribe でき、生存確認は ping/pong で行う
+func (s *Server) handleWebSocket(c echo.Context) error {
+	conn, err := websocket.Accept(c.Response(), c.Request(), &websocket.AcceptOptions{
+		OriginPatterns: s.wsOriginPatterns,
+	})
+	if err != nil {
+		// Accept がエラーレスポンスを書き込み済み
+		return nil
+	}
+	defer conn.CloseNow()
+
+	// hijack 後はリクエストの ctx では切断を検知できないので、読み込みエラーで終わらせる
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sess := &wsSession{
+		s:      s,
+		conn:   conn,
+		tenant: tenantOf(c),
+		cancel: cancel,
+		subs:   make(map[string]*wsSubscription),
+	}
+	go sess.keepAlive(ctx)
+
+	err = sess.readLoop(ctx)
+	cancel()
+	sess.wg.Wait()
+
+	switch websocket.CloseStatus(err) {
+	case websocket.StatusNormalClosure, websocket.StatusGoingAway:
+		return nil
+	}
+	if ctx.Err() != nil || errors.Is(err, context.Canceled) {
+		// ping 無応答や書き込み失敗で打ち切った
+		return nil
+	}
+	_ = conn.Close(websocket.StatusInternalError, "read failed")
+	return nil
+}
+
+// readLoop はクライアントからのメッセージを処理し続ける
+// 解釈できないメッセージには error を返すだけで接続は維持する
+func (w *wsSession) readLoop(ctx context.Context) error {
+	for {
+		_, data, err := w.conn.Read(ctx)
+		if err != nil {
+			return err
+		}
+		var msg wsClientMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			w.send(ctx, wsServerMessage{Type: "error", Error: "invalid message"})
+			continue
+		}
+		switch msg.Type {
+		case "subscribe":
+			w.subscribe(ctx, msg)
+		case "unsubscribe":
+			w.unsubscribe(ctx, electionOrDefault(msg.ElectionID))
+		default:
+			w.send(ctx, wsServerMessage{Type: "error", Error: fmt.Sprintf("unknown message type %q", msg.Type)})
+		}
+	}
+}
+
+func (w *wsSession) subscribe(ctx context.Context, msg wsClientMessage) {
+	election := electionOrDefault(msg.ElectionID)
+
+	w.mu.Lock()
+	if _, ok := w.subs[election]; ok {
+		// 購読済み：二重に上流ストリームを張らない
+		w.mu.Unlock()
+		w.send(ctx, wsServerMessage{Type: "subscribed", ElectionID: election})
+		return
+	}
+	if len(w.subs) >= maxWSSubscriptions {
+		w.mu.Unlock()
+		w.send(ctx, wsServerMessage{
+			Type:       "error",
+			ElectionID: election,
+			Error:      fmt.Sprintf("at most %d subscriptions per connection", maxWSSubscriptions),
+		})
+		return
+	}
+	subCtx, cancel := context.WithCancel(ctx)
+	sub := &wsSubscription{cancel: cancel}
+	w.subs[election] = sub
+	w.mu.Unlock()
+
+	w.send(ctx, wsServerMessage{Type: "subscribed", ElectionID: election})
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		defer w.remove(election, sub)
+		w.forward(subCtx, election, msg.ResumeAfter)
+	}()
+}
+
+func (w *wsSession) unsubscribe(ctx context.Context, election string) {
+	w.mu.Lock()
+	if sub, ok := w.subs[election]; ok {
+		sub.cancel()
+		delete(w.subs, election)
+	}
+	w.mu.Unlock()
+	w.send(ctx, wsServerMessage{Type: "unsubscribed", ElectionID: election})
+}
+
+// remove は購読が終わったときに登録を外す
+// unsubscribe 後に同じ選挙を再購読していれば、その新しい購読は残す
+func (w *wsSession) remove(election string, sub *wsSubscription) {
+	sub.cancel()
+	w.mu.Lock()
+	if w.subs[election] == sub {
+		delete(w.subs, election)
+	}
+	w.mu.Unlock()
+}
+
+// forward は 1 選挙分の集計を hub から受け取ってクライアントへ送る
+// 上流が切れても hub が再購読する。直らないエラーのときだけ error を送って購読を終える
+func (w *wsSession) forward(ctx context.Context, election string, resumeAfter uint64) {
+	sub := w.s.hub.subscribe(w.tenant, election, resumeAfter)
+	defer w.s.hub.unsubscribe(sub)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sub.done:
+			w.send(ctx, wsServerMessage{Type: "error", ElectionID: election, Error: "upstream closed"})
+			return
+		case msg := <-sub.ch:
+			w.send(ctx, wsServerMessage{Type: "totals", ElectionID: election, Totals: msg})
+		}
+	}
+}
+
+// send は 1 メッセージを書き込む。Conn の書き込みは並行呼び出しに対応している
+// 書き込めないクライアントは接続ごと打ち切る
+func (w *wsSession) send(ctx context.Context, msg wsServerMessage) {
+	ctx, cancel := context.WithTimeout(ctx, wsWriteTimeout)
+	defer cancel()
+	if err := wsjson.Write(ctx, w.conn, msg); err != nil {
+		w.cancel()
+	}
+}
+
+// keepAlive は一定間隔で ping を送り、pong が返らなければ接続を終わらせる
+// Ping の応答は readLoop の Read が処理する
+func (w *wsSession) keepAlive(ctx context.Context) {
+	t := time.NewTicker(w.s.wsPingInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			pingCtx, cancel := context.WithTimeout(ctx, w.s.wsPingInterval)
+			err := w.conn.Ping(pingCtx)
+			cancel()
+			if err != nil {
+				w.cancel()
+				return
+			}
+		}
+	}
+}
+
+func electionOrDefault(election string) string {
+	if election == "" {
+		return defaultElection
+	}
+	return election
+}
@@ -0,0 +1,194 @@
+package httpapi
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	resultv1 "github.com/yoyo1025/k8s-vote-platform/gen/go/result/v1"
+)
+
+func TestWebSocketMultiplexesElections(t *testing.T) {
+	client := newFakeResultClient()
+	ts, token := newStreamTestServer(t, client)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(ts.URL, "http")+"/api/v1/results/ws?access_token="+token, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.CloseNow()
+
+	write := func(msg wsClientMessage) {
+		t.Helper()
+		if err := wsjson.Write(ctx, conn, msg); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+	read := func() wsServerMessage {
+		t.Helper()
+		var msg wsServerMessage
+		if err := wsjson.Read(ctx, conn, &msg); err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		return msg
+	}
+
+	write(wsClientMessage{Type: "subscribe", ElectionID: "e1", ResumeAfter: 3})
+	if msg := read(); msg.Type != "subscribed" || msg.ElectionID != "e1" {
+		t.Fatalf("got %+v, want subscribed e1", msg)
+	}
+	if req := <-client.reqs; req.GetElectionId() != "e1" {
+		t.Fatalf("upstream request = %v", req)
+	}
+	write(wsClientMessage{Type: "subscribe"})
+	if msg := read(); msg.Type != "subscribed" || msg.ElectionID != "default" {
+		t.Fatalf("got %+v, want subscribed default", msg)
+	}
+	<-client.reqs
+
+	client.stream("default") <- &resultv1.SubscribeTotalsResponse{Sequence: 2}
+	if msg := read(); msg.Type != "totals" || msg.ElectionID != "default" || msg.Totals.GetSequence() != 2 {
+		t.Fatalf("got %+v, want totals for default", msg)
+	}
+	client.stream("e1") <- &resultv1.SubscribeTotalsResponse{Sequence: 4}
+	if msg := read(); msg.Type != "totals" || msg.ElectionID != "e1" || msg.Totals.GetSequence() != 4 {
+		t.Fatalf("got %+v, want totals for e1", msg)
+	}
+
+	write(wsClientMessage{Type: "unsubscribe", ElectionID: "e1"})
+	if msg := read(); msg.Type != "unsubscribed" || msg.ElectionID != "e1" {
+		t.Fatalf("got %+v, want unsubscribed e1", msg)
+	}
+	select {
+	case election := <-client.canceled:
+		if election != "e1" {
+			t.Fatalf("canceled %q, want e1", election)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("upstream stream for e1 was not canceled")
+	}
+
+	write(wsClientMessage{Type: "bogus"})
+	if msg := read(); msg.Type != "error" {
+		t.Fatalf("got %+v, want error", msg)
+	}
+
+	// 切断で残りの購読も閉じること
+	conn.Close(websocket.StatusNormalClosure, "")
+	select {
+	case election := <-client.canceled:
+		if election != "default" {
+			t.Fatalf("canceled %q, want default", election)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("upstream stream for default was not canceled after close")
+	}
+}
+
+// dialWS は結果 WebSocket に接続し、JSON の読み書きヘルパを返す
+func dialWS(t *testing.T, ctx context.Context, url, token string) (*websocket.Conn, func(wsClientMessage), func() wsServerMessage) {
+	t.Helper()
+	conn, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(url, "http")+"/api/v1/results/ws?access_token="+token, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.CloseNow() })
+	write := func(msg wsClientMessage) {
+		t.Helper()
+		if err := wsjson.Write(ctx, conn, msg); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+	read := func() wsServerMessage {
+		t.Helper()
+		var msg wsServerMessage
+		if err := wsjson.Read(ctx, conn, &msg); err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		return msg
+	}
+	return conn, write, read
+}
+
+func TestWebSocketUnsubscribe(t *testing.T) {
+	client := newFakeResultClient()
+	ts, token := newStreamTestServer(t, client)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, write, read := dialWS(t, ctx, ts.URL, token)
+
+	// 購読していない選挙の unsubscribe も確認応答だけ返す
+	write(wsClientMessage{Type: "unsubscribe", ElectionID: "e9"})
+	if msg := read(); msg.Type != "unsubscribed" || msg.ElectionID != "e9" {
+		t.Fatalf("got %+v, want unsubscribed e9", msg)
+	}
+
+	write(wsClientMessage{Type: "subscribe", ElectionID: "e1"})
+	if msg := read(); msg.Type != "subscribed" || msg.ElectionID != "e1" {
+		t.Fatalf("got %+v, want subscribed e1", msg)
+	}
+	<-client.reqs
+	write(wsClientMessage{Type: "unsubscribe", ElectionID: "e1"})
+	if msg := read(); msg.Type != "unsubscribed" || msg.ElectionID != "e1" {
+		t.Fatalf("got %+v, want unsubscribed e1", msg)
+	}
+	select {
+	case election := <-client.canceled:
+		if election != "e1" {
+			t.Fatalf("canceled %q, want e1", election)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("upstream stream for e1 was not canceled")
+	}
+
+	// 再購読すると新しい上流ストリームから届いた分だけが流れる
+	write(wsClientMessage{Type: "subscribe", ElectionID: "e1", ResumeAfter: 4})
+	if msg := read(); msg.Type != "subscribed" || msg.ElectionID != "e1" {
+		t.Fatalf("got %+v, want subscribed e1", msg)
+	}
+	if req := <-client.reqs; req.GetElectionId() != "e1" {
+		t.Fatalf("upstream request = %v, want e1", req)
+	}
+	client.stream("e1") <- &resultv1.SubscribeTotalsResponse{Sequence: 5}
+	if msg := read(); msg.Type != "totals" || msg.ElectionID != "e1" || msg.Totals.GetSequence() != 5 {
+		t.Fatalf("got %+v, want totals 5 for e1", msg)
+	}
+}
+
+func TestWebSocketClosesWithoutPong(t *testing.T) {
+	client := newFakeResultClient()
+	ts, token := newStreamTestServer(t, client, func(s *Server) {
+		s.wsPingInterval = 20 * time.Millisecond
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, write, _ := dialWS(t, ctx, ts.URL, token)
+
+	write(wsClientMessage{Type: "subscribe", ElectionID: "e1"})
+	<-client.reqs
+
+	// クライアントが Read しないので pong が返らず、サーバが接続を打ち切る
+	select {
+	case election := <-client.canceled:
+		if election != "e1" {
+			t.Fatalf("canceled %q, want e1", election)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("connection without pong was not closed")
+	}
+
+	var msg wsServerMessage
+	if err := wsjson.Read(ctx, conn, &msg); err != nil || msg.Type != "subscribed" {
+		t.Fatalf("read = %+v, %v; want the buffered subscribed", msg, err)
+	}
+	if err := wsjson.Read(ctx, conn, &msg); err == nil {
+		t.Fatalf("read after timeout = %+v, want the connection closed", msg)
+	}
+}
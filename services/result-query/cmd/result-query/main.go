@@ -26,6 +26,9 @@ func main() {
 		RedisUsername: os.Getenv("REDIS_USERNAME"),
 		RedisPassword: os.Getenv("REDIS_PASSWORD"),
 		RedisChannel:  getenv("REDIS_CHANNEL", "results:totals"),
+
+		SnapshotTolerance: durationDefault(os.Getenv("SNAPSHOT_TOLERANCE"), time.Minute),
+		CacheMaxStale:     durationOrOff(os.Getenv("CACHE_MAX_STALE"), 5*time.Second),
 	}
 
 	grpcAddr := getenv("GRPC_ADDR", ":50051")
@@ -85,6 +88,29 @@ func getenv(key, def string) string {
 	return def
 }
 
+func durationDefault(v string, def time.Duration) time.Duration {
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return def
+	}
+	if d <= 0 {
+		return def
+	}
+	return d
+}
+
+// durationOrOff is durationDefault that also accepts "off", which disables
+// the setting by returning a negative duration.
+func durationOrOff(v string, def time.Duration) time.Duration {
+	if v == "off" {
+		return -1
+	}
+	return durationDefault(v, def)
+}
+
 func buildPostgresDSN() string {
 	if dsn := os.Getenv("PG_DSN"); dsn != "" {
 		return dsn
@@ -92,7 +118,7 @@ func buildPostgresDSN() string {
 
 	host := getenv("PG_HOST", "localhost")
 	port := getenv("PG_PORT", "5432")
-	user := getenv("PG_USER", "vote")
+	user := getenv("PG_USER", "vote_app")
 	password := os.Getenv("PG_PASSWORD")
 	database := getenv("PG_DATABASE", "vote")
 	sslmode := getenv("PG_SSLMODE", "disable")
@@ -4,11 +4,16 @@ go 1.25.1
 
 replace github.com/yoyo1025/k8s-vote-platform/gen/go => ../../gen/go
 
+replace github.com/yoyo1025/k8s-vote-platform/pkg/totals => ../../pkg/totals
+
 require (
 	github.com/jackc/pgx/v5 v5.6.0
 	github.com/redis/go-redis/v9 v9.6.1
 	github.com/yoyo1025/k8s-vote-platform/gen/go v0.0.0-00010101000000-000000000000
+	github.com/yoyo1025/k8s-vote-platform/pkg/totals v0.0.0-00010101000000-000000000000
+	golang.org/x/sync v0.15.0
 	google.golang.org/grpc v1.75.1
+	google.golang.org/protobuf v1.36.9
 )
 
 require (
@@ -19,9 +24,7 @@ require (
 	github.com/jackc/puddle/v2 v2.2.1 // indirect
 	golang.org/x/crypto v0.39.0 // indirect
 	golang.org/x/net v0.41.0 // indirect
-	golang.org/x/sync v0.15.0 // indirect
 	golang.org/x/sys v0.33.0 // indirect
 	golang.org/x/text v0.26.0 // indirect
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20250707201910-8d1bb00bc6a7 // indirect
-	google.golang.org/protobuf v1.36.9 // indirect
 )
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	resultv1 "github.com/yoyo1025/k8s-vote-platform/gen/go/result/v1"
+	"github.com/yoyo1025/k8s-vote-platform/pkg/totals"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const defaultSnapshotTolerance = time.Minute
+
+// Values of GetTotalsResponse.source.
+const (
+	sourceLive     = "live"
+	sourceSnapshot = "snapshot"
+	sourceVotes    = "votes"
+)
+
+// totalsAsOf answers point-in-time queries. The latest snapshot taken at most
+// snapshotTolerance before asOf wins, since it records what was published at
+// the time; otherwise totals are recomputed from votes.voted_at.
+func (s *Server) totalsAsOf(ctx context.Context, tenant, election, raw string) (*resultv1.GetTotalsResponse, error) {
+	asOf, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid as_of: %v", err)
+	}
+	if asOf.After(time.Now()) {
+		return nil, status.Error(codes.InvalidArgument, "as_of is in the future")
+	}
+
+	var resp *resultv1.GetTotalsResponse
+	err = s.withTenant(ctx, tenant, func(tx pgx.Tx) error {
+		var (
+			snapshotID int64
+			takenAt    time.Time
+		)
+		err := tx.QueryRow(ctx, `
+			SELECT id, taken_at
+			FROM totals_snapshots
+			WHERE taken_at <= $1 AND taken_at >= $2
+			ORDER BY taken_at DESC
+			LIMIT 1`, asOf, asOf.Add(-s.snapshotTolerance)).Scan(&snapshotID, &takenAt)
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			items, err := queryTotals(ctx, tx, totals.CandidatesSQL(`
+				SELECT candidate_id, COUNT(*) AS count
+				FROM votes
+				WHERE tenant_id = $1 AND election_id = $2 AND voted_at <= $3
+				GROUP BY candidate_id`), tenant, election, asOf)
+			if err != nil {
+				return err
+			}
+			ballots, err := countBallots(ctx, tx, tenant, election, asOf)
+			if err != nil {
+				return err
+			}
+			resp = &resultv1.GetTotalsResponse{
+				Totals:       items,
+				UpdatedAt:    asOf.UTC().Format(time.RFC3339),
+				Source:       sourceVotes,
+				ElectionId:   election,
+				TotalBallots: ballots,
+			}
+			return nil
+		case err != nil:
+			return fmt.Errorf("query snapshot: %w", err)
+		}
+
+		items, err := queryTotals(ctx, tx, totals.CandidatesSQL(`
+			SELECT candidate_id, count
+			FROM totals_snapshot_items
+			WHERE tenant_id = $1 AND election_id = $2 AND snapshot_id = $3`), tenant, election, snapshotID)
+		if err != nil {
+			return err
+		}
+		// Snapshots hold counts only; ballots are recounted from votes.
+		ballots, err := countBallots(ctx, tx, tenant, election, takenAt)
+		if err != nil {
+			return err
+		}
+		resp = &resultv1.GetTotalsResponse{
+			Totals:       items,
+			UpdatedAt:    takenAt.UTC().Format(time.RFC3339),
+			Source:       sourceSnapshot,
+			SnapshotId:   uint64(snapshotID),
+			ElectionId:   election,
+			TotalBallots: ballots,
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
@@ -0,0 +1,268 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeDB answers the point-in-time queries of totalsAsOf from votes and
+// snapshots kept in memory, and records time series queries. Candidates have
+// no metadata.
+type fakeDB struct {
+	votes     []fakeVote
+	snapshots []fakeSnapshot
+	// seriesArgs records the arguments of the last totals_timeseries query,
+	// which returns no rows.
+	seriesArgs []any
+	// begun counts transactions, so tests can tell whether the database
+	// was consulted at all.
+	begun int
+}
+
+type fakeVote struct {
+	user, candidateID int64
+	at                time.Time
+}
+
+type fakeSnapshot struct {
+	id      int64
+	takenAt time.Time
+	counts  map[int64]int64
+}
+
+func (db *fakeDB) BeginTx(context.Context, pgx.TxOptions) (pgx.Tx, error) {
+	db.begun++
+	return &fakeTx{db: db}, nil
+}
+
+type fakeTx struct {
+	pgx.Tx
+	db *fakeDB
+}
+
+func (tx *fakeTx) Exec(_ context.Context, sql string, _ ...any) (pgconn.CommandTag, error) {
+	if strings.Contains(sql, "set_config('app.tenant'") {
+		return pgconn.NewCommandTag("SELECT 1"), nil
+	}
+	return pgconn.CommandTag{}, errors.New("unexpected statement: " + sql)
+}
+
+// QueryRow answers the snapshot lookup and countBallots.
+func (tx *fakeTx) QueryRow(_ context.Context, sql string, args ...any) pgx.Row {
+	switch {
+	case strings.Contains(sql, "FROM totals_snapshots"):
+		asOf, earliest := args[0].(time.Time), args[1].(time.Time)
+		var best *fakeSnapshot
+		for i, snap := range tx.db.snapshots {
+			if snap.takenAt.After(asOf) || snap.takenAt.Before(earliest) {
+				continue
+			}
+			if best == nil || snap.takenAt.After(best.takenAt) {
+				best = &tx.db.snapshots[i]
+			}
+		}
+		if best == nil {
+			return fakeRow{err: pgx.ErrNoRows}
+		}
+		return fakeRow{values: []any{best.id, best.takenAt}}
+	case strings.Contains(sql, "COUNT(DISTINCT user_id)"):
+		asOf := args[2].(time.Time)
+		users := make(map[int64]bool)
+		for _, v := range tx.db.votes {
+			if !v.at.After(asOf) {
+				users[v.user] = true
+			}
+		}
+		return fakeRow{values: []any{int64(len(users))}}
+	}
+	return fakeRow{err: errors.New("unexpected query: " + sql)}
+}
+
+// Query answers the totals.CandidatesSQL queries over votes and snapshot
+// items, and records time series queries.
+func (tx *fakeTx) Query(_ context.Context, sql string, args ...any) (pgx.Rows, error) {
+	counts := make(map[int64]int64)
+	switch {
+	case strings.Contains(sql, "FROM totals_timeseries"):
+		tx.db.seriesArgs = args
+		return &fakeRows{}, nil
+	case strings.Contains(sql, "FROM totals_snapshot_items"):
+		for _, snap := range tx.db.snapshots {
+			if snap.id == args[2].(int64) {
+				counts = snap.counts
+			}
+		}
+	case strings.Contains(sql, "FROM votes"):
+		asOf := args[2].(time.Time)
+		for _, v := range tx.db.votes {
+			if !v.at.After(asOf) {
+				counts[v.candidateID]++
+			}
+		}
+	default:
+		return nil, errors.New("unexpected query: " + sql)
+	}
+	rows := &fakeRows{}
+	for _, id := range []int64{1, 2, 3} {
+		if n, ok := counts[id]; ok {
+			rows.rows = append(rows.rows, []any{id, n, ""})
+		}
+	}
+	return rows, nil
+}
+
+func (tx *fakeTx) Commit(context.Context) error   { return nil }
+func (tx *fakeTx) Rollback(context.Context) error { return nil }
+
+type fakeRow struct {
+	values []any
+	err    error
+}
+
+func (r fakeRow) Scan(dest ...any) error {
+	if r.err != nil {
+		return r.err
+	}
+	return scanValues(r.values, dest)
+}
+
+type fakeRows struct {
+	pgx.Rows
+	rows [][]any
+	next int
+}
+
+func (r *fakeRows) Next() bool {
+	r.next++
+	return r.next <= len(r.rows)
+}
+
+func (r *fakeRows) Scan(dest ...any) error { return scanValues(r.rows[r.next-1], dest) }
+func (r *fakeRows) Close()                 {}
+func (r *fakeRows) Err() error             { return nil }
+
+func scanValues(values, dest []any) error {
+	for i, v := range values {
+		switch d := dest[i].(type) {
+		case *int64:
+			*d = v.(int64)
+		case *string:
+			*d = v.(string)
+		case *time.Time:
+			*d = v.(time.Time)
+		default:
+			return errors.New("unexpected scan destination")
+		}
+	}
+	return nil
+}
+
+func TestTotalsAsOfRejectsInvalidTime(t *testing.T) {
+	for _, raw := range []string{
+		"yesterday",
+		"2024-01-02 03:04:05",
+		time.Now().Add(time.Hour).UTC().Format(time.RFC3339),
+	} {
+		db := &fakeDB{}
+		s := &Server{db: db, snapshotTolerance: time.Minute}
+		_, err := s.totalsAsOf(context.Background(), "t", "e", raw)
+		if status.Code(err) != codes.InvalidArgument {
+			t.Fatalf("totalsAsOf(%q) error = %v, want InvalidArgument", raw, err)
+		}
+		if db.begun != 0 {
+			t.Fatalf("totalsAsOf(%q) queried the database", raw)
+		}
+	}
+}
+
+func TestTotalsAsOfSource(t *testing.T) {
+	asOf := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	votes := []fakeVote{
+		{user: 1, candidateID: 1, at: asOf.Add(-time.Hour)},
+		{user: 2, candidateID: 2, at: asOf.Add(-30 * time.Minute)},
+		{user: 3, candidateID: 1, at: asOf.Add(-10 * time.Second)},
+		// Cast after as_of, so never counted.
+		{user: 4, candidateID: 2, at: asOf.Add(time.Minute)},
+	}
+	tests := []struct {
+		name         string
+		snapshots    []fakeSnapshot
+		wantSource   string
+		wantSnapshot uint64
+		wantUpdated  time.Time
+		wantCounts   map[uint64]uint64
+		wantBallots  uint64
+	}{
+		{
+			name:        "no snapshot",
+			wantSource:  sourceVotes,
+			wantUpdated: asOf,
+			wantCounts:  map[uint64]uint64{1: 2, 2: 1},
+			wantBallots: 3,
+		},
+		{
+			name: "nearest snapshot within tolerance",
+			snapshots: []fakeSnapshot{
+				{id: 1, takenAt: asOf.Add(-50 * time.Second), counts: map[int64]int64{1: 1}},
+				{id: 2, takenAt: asOf.Add(-20 * time.Second), counts: map[int64]int64{1: 1, 2: 1}},
+				{id: 3, takenAt: asOf.Add(10 * time.Second), counts: map[int64]int64{1: 2, 2: 1}},
+			},
+			wantSource:   sourceSnapshot,
+			wantSnapshot: 2,
+			wantUpdated:  asOf.Add(-20 * time.Second),
+			wantCounts:   map[uint64]uint64{1: 1, 2: 1},
+			wantBallots:  2,
+		},
+		{
+			name: "snapshot older than tolerance",
+			snapshots: []fakeSnapshot{
+				{id: 1, takenAt: asOf.Add(-2 * time.Minute), counts: map[int64]int64{1: 1, 2: 1}},
+			},
+			wantSource:  sourceVotes,
+			wantUpdated: asOf,
+			wantCounts:  map[uint64]uint64{1: 2, 2: 1},
+			wantBallots: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{db: &fakeDB{votes: votes, snapshots: tt.snapshots}, snapshotTolerance: time.Minute}
+			resp, err := s.totalsAsOf(context.Background(), "t", "e", asOf.Format(time.RFC3339))
+			if err != nil {
+				t.Fatalf("totalsAsOf: %v", err)
+			}
+			if resp.GetSource() != tt.wantSource || resp.GetSnapshotId() != tt.wantSnapshot {
+				t.Fatalf("source = %q snapshot %d, want %q snapshot %d", resp.GetSource(), resp.GetSnapshotId(), tt.wantSource, tt.wantSnapshot)
+			}
+			if want := tt.wantUpdated.Format(time.RFC3339); resp.GetUpdatedAt() != want {
+				t.Fatalf("updated_at = %q, want %q", resp.GetUpdatedAt(), want)
+			}
+			got := make(map[uint64]uint64)
+			for _, c := range resp.GetTotals() {
+				got[c.GetCandidateId()] = c.GetCount()
+			}
+			if len(got) != len(tt.wantCounts) {
+				t.Fatalf("totals = %v, want %v", got, tt.wantCounts)
+			}
+			for id, n := range tt.wantCounts {
+				if got[id] != n {
+					t.Fatalf("totals = %v, want %v", got, tt.wantCounts)
+				}
+			}
+			if resp.GetTotalBallots() != tt.wantBallots {
+				t.Fatalf("total_ballots = %d, want %d", resp.GetTotalBallots(), tt.wantBallots)
+			}
+			if resp.GetElectionId() != "e" {
+				t.Fatalf("election_id = %q, want e", resp.GetElectionId())
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	defaultCacheMaxStale = 5 * time.Second
+	// cacheFetchTimeout bounds a shared fetch, which runs detached from the
+	// caller that started it so other waiters are not failed by its cancel.
+	cacheFetchTimeout = 5 * time.Second
+)
+
+type electionKey struct {
+	tenant   string
+	election string
+}
+
+func (k electionKey) String() string {
+	return k.tenant + "/" + k.election
+}
+
+type cachedTotals struct {
+	totals    electionTotals
+	fetchedAt time.Time
+}
+
+// totalsCache is a read-through cache of live election totals. Entries are
+// dropped when the worker reports a change and are never served older than
+// maxStale, which bounds staleness if a notification is lost. Concurrent
+// misses for the same election share one fetch.
+type totalsCache struct {
+	maxStale time.Duration
+	fetch    func(ctx context.Context, tenant, election string) (electionTotals, error)
+	now      func() time.Time
+
+	group singleflight.Group
+
+	mu      sync.Mutex
+	entries map[electionKey]cachedTotals
+	// gen is bumped on every invalidation so a fetch that started before
+	// one does not store its result afterwards. It also records every key
+	// fetched so far.
+	gen map[electionKey]uint64
+	// epoch is bumped when everything is invalidated.
+	epoch uint64
+}
+
+func newTotalsCache(maxStale time.Duration, fetch func(context.Context, string, string) (electionTotals, error)) *totalsCache {
+	return &totalsCache{
+		maxStale: maxStale,
+		fetch:    fetch,
+		now:      time.Now,
+		entries:  make(map[electionKey]cachedTotals),
+		gen:      make(map[electionKey]uint64),
+	}
+}
+
+// get returns the cached totals for an election, fetching them on a miss.
+// With a non-positive maxStale every call fetches, though concurrent calls
+// are still collapsed.
+func (c *totalsCache) get(ctx context.Context, tenant, election string) (electionTotals, error) {
+	key := electionKey{tenant, election}
+
+	c.mu.Lock()
+	if e, ok := c.entries[key]; ok && c.now().Sub(e.fetchedAt) < c.maxStale {
+		c.mu.Unlock()
+		return e.totals, nil
+	}
+	c.mu.Unlock()
+
+	ch := c.group.DoChan(key.String(), func() (any, error) {
+		c.mu.Lock()
+		gen, epoch := c.gen[key], c.epoch
+		c.gen[key] = gen
+		c.mu.Unlock()
+
+		fetchCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), cacheFetchTimeout)
+		defer cancel()
+		fetchedAt := c.now()
+		t, err := c.fetch(fetchCtx, tenant, election)
+		if err != nil {
+			return electionTotals{}, err
+		}
+
+		if c.maxStale > 0 {
+			c.mu.Lock()
+			if c.gen[key] == gen && c.epoch == epoch {
+				c.entries[key] = cachedTotals{totals: t, fetchedAt: fetchedAt}
+			}
+			c.mu.Unlock()
+		}
+		return t, nil
+	})
+
+	select {
+	case <-ctx.Done():
+		return electionTotals{}, ctx.Err()
+	case res := <-ch:
+		if res.Err != nil {
+			return electionTotals{}, res.Err
+		}
+		return res.Val.(electionTotals), nil
+	}
+}
+
+// invalidate drops the cached totals of one election.
+func (c *totalsCache) invalidate(tenant, election string) {
+	key := electionKey{tenant, election}
+	c.mu.Lock()
+	delete(c.entries, key)
+	c.gen[key]++
+	c.mu.Unlock()
+	// Callers arriving from now on must not join a fetch that may predate
+	// the change.
+	c.group.Forget(key.String())
+}
+
+// invalidateAll drops every cached entry.
+func (c *totalsCache) invalidateAll() {
+	c.mu.Lock()
+	keys := make([]electionKey, 0, len(c.gen))
+	for key := range c.gen {
+		keys = append(keys, key)
+	}
+	c.entries = make(map[electionKey]cachedTotals)
+	c.epoch++
+	c.mu.Unlock()
+	for _, key := range keys {
+		c.group.Forget(key.String())
+	}
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	resultv1 "github.com/yoyo1025/k8s-vote-platform/gen/go/result/v1"
+)
+
+func TestTotalsCacheCollapsesConcurrentMisses(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	c := newTotalsCache(time.Minute, func(context.Context, string, string) (electionTotals, error) {
+		calls.Add(1)
+		<-release
+		return electionTotals{totalBallots: 1}, nil
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.get(context.Background(), "t", "e"); err != nil {
+				t.Errorf("get: %v", err)
+			}
+		}()
+	}
+	// Let the callers pile up behind the first fetch.
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if _, err := c.get(context.Background(), "t", "e"); err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("fetch called %d times, want 1", n)
+	}
+}
+
+func TestTotalsCacheInvalidateAndStaleness(t *testing.T) {
+	var calls atomic.Int32
+	now := time.Now()
+	c := newTotalsCache(time.Second, func(context.Context, string, string) (electionTotals, error) {
+		n := calls.Add(1)
+		return electionTotals{totals: []*resultv1.Totals{{Count: uint64(n)}}}, nil
+	})
+	c.now = func() time.Time { return now }
+
+	get := func() uint64 {
+		t.Helper()
+		got, err := c.get(context.Background(), "t", "e")
+		if err != nil {
+			t.Fatalf("get: %v", err)
+		}
+		return got.totals[0].Count
+	}
+
+	if got := get(); got != 1 {
+		t.Fatalf("first get = %d, want 1", got)
+	}
+	if got := get(); got != 1 {
+		t.Fatalf("cached get = %d, want 1", got)
+	}
+
+	c.invalidate("t", "other")
+	if got := get(); got != 1 {
+		t.Fatalf("get after unrelated invalidation = %d, want 1", got)
+	}
+
+	c.invalidate("t", "e")
+	if got := get(); got != 2 {
+		t.Fatalf("get after invalidation = %d, want 2", got)
+	}
+
+	now = now.Add(2 * time.Second)
+	if got := get(); got != 3 {
+		t.Fatalf("get after max staleness = %d, want 3", got)
+	}
+
+	c.invalidateAll()
+	if got := get(); got != 4 {
+		t.Fatalf("get after invalidateAll = %d, want 4", got)
+	}
+}
+
+func TestParseTotalsChanged(t *testing.T) {
+	keys, all := parseTotalsChanged(`{"tenant":"acme","electionIds":["e1","e2"]}`)
+	if all || len(keys) != 2 || keys[0] != (electionKey{"acme", "e1"}) {
+		t.Fatalf("keys = %v all = %v", keys, all)
+	}
+	if _, all := parseTotalsChanged("refresh"); !all {
+		t.Fatal("legacy refresh should concern every election")
+	}
+}
@@ -0,0 +1,96 @@
+package server
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+	resultv1 "github.com/yoyo1025/k8s-vote-platform/gen/go/result/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// changeFeed holds the process-wide subscription to the results channel. It
+// invalidates the cache before waking subscribers, so a woken subscriber
+// never reads totals cached before the change.
+type changeFeed struct {
+	mu       sync.Mutex
+	watchers map[chan struct{}]electionKey
+}
+
+func newChangeFeed() *changeFeed {
+	return &changeFeed{watchers: make(map[chan struct{}]electionKey)}
+}
+
+// watch returns a channel that receives a value whenever the election's
+// totals change. Wake-ups are coalesced; call stop when done.
+func (f *changeFeed) watch(key electionKey) (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+	f.mu.Lock()
+	f.watchers[ch] = key
+	f.mu.Unlock()
+	return ch, func() {
+		f.mu.Lock()
+		delete(f.watchers, ch)
+		f.mu.Unlock()
+	}
+}
+
+// notify wakes the watchers of keys, or every watcher when all is set.
+func (f *changeFeed) notify(keys []electionKey, all bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for ch, watched := range f.watchers {
+		if !all && !slices.Contains(keys, watched) {
+			continue
+		}
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// parseTotalsChanged returns the elections named by a results channel
+// message. Messages that are not a TotalsChanged, such as the plain
+// "refresh" sent by older workers, concern every election.
+func parseTotalsChanged(payload string) (keys []electionKey, all bool) {
+	var msg resultv1.TotalsChanged
+	if err := protojson.Unmarshal([]byte(payload), &msg); err != nil {
+		return nil, true
+	}
+	for _, election := range msg.GetElectionIds() {
+		keys = append(keys, electionKey{tenantOrDefault(msg.GetTenant()), election})
+	}
+	return keys, false
+}
+
+// listenChanges applies results channel messages until the subscription is
+// closed. go-redis resubscribes after a dropped connection; messages missed
+// meanwhile are covered by the cache staleness bound and the subscriber
+// heartbeat.
+func (s *Server) listenChanges(pubsub *redis.PubSub) {
+	for msg := range pubsub.Channel() {
+		keys, all := parseTotalsChanged(msg.Payload)
+		if all {
+			s.cache.invalidateAll()
+		} else {
+			for _, key := range keys {
+				s.cache.invalidate(key.tenant, key.election)
+			}
+		}
+		s.changes.notify(keys, all)
+	}
+}
+
+// subscribeChanges subscribes to the results channel and waits for the
+// subscription to be confirmed, so no change published after New returns
+// is missed.
+func (s *Server) subscribeChanges(ctx context.Context) (*redis.PubSub, error) {
+	pubsub := s.redis.Subscribe(ctx, s.channel)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, err
+	}
+	return pubsub, nil
+}
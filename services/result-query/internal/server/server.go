@@ -7,9 +7,11 @@ import (
 	"log"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/redis/go-redis/v9"
 	resultv1 "github.com/yoyo1025/k8s-vote-platform/gen/go/result/v1"
+	"github.com/yoyo1025/k8s-vote-platform/pkg/totals"
 )
 
 const (
@@ -25,16 +27,32 @@ type Config struct {
 	RedisUsername string
 	RedisPassword string
 	RedisChannel  string
+
+	// SnapshotTolerance is how far before an as_of instant a snapshot may
+	// have been taken and still be used to answer GetTotals.
+	SnapshotTolerance time.Duration
+	// CacheMaxStale bounds how long live totals are served from memory when
+	// no change notification arrives. Zero uses the default; negative
+	// disables caching.
+	CacheMaxStale time.Duration
 }
 
 // Server implements the gRPC ResultService backed by Postgres totals and Redis notifications.
 type Server struct {
 	resultv1.UnimplementedResultServiceServer
 
-	pool    *pgxpool.Pool
+	pool *pgxpool.Pool
+	// db runs queries; it is pool outside tests.
+	db      totals.TxBeginner
 	redis   *redis.Client
 	channel string
 	logger  *log.Logger
+
+	snapshotTolerance time.Duration
+
+	cache   *totalsCache
+	changes *changeFeed
+	pubsub  *redis.PubSub
 }
 
 // New initialises connections to Postgres and Redis and returns a ready Server.
@@ -48,6 +66,12 @@ func New(ctx context.Context, cfg Config) (*Server, error) {
 	if cfg.RedisChannel == "" {
 		cfg.RedisChannel = defaultRedisChannel
 	}
+	if cfg.SnapshotTolerance <= 0 {
+		cfg.SnapshotTolerance = defaultSnapshotTolerance
+	}
+	if cfg.CacheMaxStale == 0 {
+		cfg.CacheMaxStale = defaultCacheMaxStale
+	}
 
 	logger := log.New(log.Writer(), "[result-query] ", log.LstdFlags|log.Lmsgprefix)
 
@@ -70,16 +94,36 @@ func New(ctx context.Context, cfg Config) (*Server, error) {
 		return nil, fmt.Errorf("redis ping: %w", err)
 	}
 
-	return &Server{
+	s := &Server{
 		pool:    pool,
+		db:      pool,
 		redis:   redisClient,
 		channel: cfg.RedisChannel,
 		logger:  logger,
-	}, nil
+
+		snapshotTolerance: cfg.SnapshotTolerance,
+		changes:           newChangeFeed(),
+	}
+	s.cache = newTotalsCache(cfg.CacheMaxStale, s.fetchTotals)
+
+	s.pubsub, err = s.subscribeChanges(ctx)
+	if err != nil {
+		pool.Close()
+		redisClient.Close()
+		return nil, fmt.Errorf("redis subscribe: %w", err)
+	}
+	go s.listenChanges(s.pubsub)
+
+	return s, nil
 }
 
 // Close releases underlying resources.
 func (s *Server) Close() {
+	if s.pubsub != nil {
+		if err := s.pubsub.Close(); err != nil {
+			s.logger.Printf("redis unsubscribe error: %v", err)
+		}
+	}
 	if s.pool != nil {
 		s.pool.Close()
 	}
@@ -90,42 +134,70 @@ func (s *Server) Close() {
 	}
 }
 
-// GetTotals returns the latest aggregated totals from Postgres.
-func (s *Server) GetTotals(ctx context.Context, _ *resultv1.GetTotalsRequest) (*resultv1.GetTotalsResponse, error) {
-	totals, updatedAt, err := s.fetchTotals(ctx)
+// Ping reports that the service is reachable. It deliberately avoids touching
+// Postgres or Redis so callers can use it as a cheap upstream probe.
+func (s *Server) Ping(context.Context, *resultv1.PingRequest) (*resultv1.PingResponse, error) {
+	return &resultv1.PingResponse{Message: "pong"}, nil
+}
+
+// GetTotals returns the latest totals of one election, or the totals at
+// req.AsOf when set.
+func (s *Server) GetTotals(ctx context.Context, req *resultv1.GetTotalsRequest) (*resultv1.GetTotalsResponse, error) {
+	tenant := tenantOrDefault(req.GetTenant())
+	election := electionOrDefault(req.GetElectionId())
+	if req.GetAsOf() != "" {
+		return s.totalsAsOf(ctx, tenant, election, req.GetAsOf())
+	}
+	t, err := s.cache.get(ctx, tenant, election)
 	if err != nil {
 		return nil, err
 	}
 	return &resultv1.GetTotalsResponse{
-		Totals:    totals,
-		UpdatedAt: updatedAt.Format(time.RFC3339),
+		Totals:       t.totals,
+		UpdatedAt:    formatUpdatedAt(t.updatedAt),
+		Source:       sourceLive,
+		ElectionId:   election,
+		TotalBallots: t.totalBallots,
 	}, nil
 }
 
-// SubscribeTotals streams totals whenever the worker publishes an update event.
+// SubscribeTotals streams an election's totals whenever the worker reports a
+// change to it. Updates carry the election's totals version as a sequence
+// and are only sent when it advances, so a client resuming with
+// req.ResumeAfter receives nothing it has already seen.
 func (s *Server) SubscribeTotals(req *resultv1.SubscribeTotalsRequest, stream resultv1.ResultService_SubscribeTotalsServer) error {
 	ctx := stream.Context()
+	tenant := tenantOrDefault(req.GetTenant())
+	election := electionOrDefault(req.GetElectionId())
+
+	// Watch before the initial read so a change in between is not lost.
+	changed, stop := s.changes.watch(electionKey{tenant, election})
+	defer stop()
+
+	last := req.GetResumeAfter()
+	// A fresh subscriber always gets the current totals, even before the
+	// first vote when the sequence is still 0.
+	sent := last > 0
+	send := func(t electionTotals) error {
+		if sent && t.version <= last {
+			return nil
+		}
+		if err := stream.Send(subscribeResponse(election, t)); err != nil {
+			return err
+		}
+		last, sent = t.version, true
+		return nil
+	}
 
 	// Send initial snapshot.
-	totals, updatedAt, err := s.fetchTotals(ctx)
+	t, err := s.cache.get(ctx, tenant, election)
 	if err != nil {
 		return err
 	}
-	if err := stream.Send(&resultv1.SubscribeTotalsResponse{
-		Totals:    totals,
-		UpdatedAt: updatedAt.Format(time.RFC3339),
-	}); err != nil {
+	if err := send(t); err != nil {
 		return err
 	}
 
-	pubsub := s.redis.Subscribe(ctx, s.channel)
-	defer pubsub.Close()
-
-	if _, err := pubsub.Receive(ctx); err != nil {
-		return err
-	}
-
-	ch := pubsub.Channel()
 	heartbeat := time.NewTicker(defaultHeartbeatFreq)
 	defer heartbeat.Stop()
 
@@ -134,75 +206,47 @@ func (s *Server) SubscribeTotals(req *resultv1.SubscribeTotalsRequest, stream re
 		case <-ctx.Done():
 			return ctx.Err()
 		case <-heartbeat.C:
-			// Periodic heartbeat with a refresh to keep the stream warm.
-			totals, updatedAt, err := s.fetchTotals(ctx)
+			// Periodic refresh in case a change notification was lost.
+			t, err := s.cache.get(ctx, tenant, election)
 			if err != nil {
 				s.logger.Printf("heartbeat fetch error: %v", err)
 				continue
 			}
-			if err := stream.Send(&resultv1.SubscribeTotalsResponse{
-				Totals:    totals,
-				UpdatedAt: updatedAt.Format(time.RFC3339),
-			}); err != nil {
+			if err := send(t); err != nil {
 				return err
 			}
-		case msg, ok := <-ch:
-			if !ok {
-				return errors.New("redis pubsub channel closed")
-			}
-			if msg == nil {
-				continue
-			}
-
-			totals, updatedAt, err := s.fetchTotals(ctx)
+		case <-changed:
+			t, err := s.cache.get(ctx, tenant, election)
 			if err != nil {
 				s.logger.Printf("fetch totals error: %v", err)
 				continue
 			}
-			if err := stream.Send(&resultv1.SubscribeTotalsResponse{
-				Totals:    totals,
-				UpdatedAt: updatedAt.Format(time.RFC3339),
-			}); err != nil {
+			if err := send(t); err != nil {
 				return err
 			}
 		}
 	}
 }
 
-func (s *Server) fetchTotals(ctx context.Context) ([]*resultv1.Totals, time.Time, error) {
-	rows, err := s.pool.Query(ctx, `
-		SELECT candidate_id, count
-		FROM totals
-		ORDER BY candidate_id`)
-	if err != nil {
-		return nil, time.Time{}, fmt.Errorf("query totals: %w", err)
-	}
-	defer rows.Close()
-
-	var totals []*resultv1.Totals
-	for rows.Next() {
-		var (
-			candidateID int64
-			count       int64
-		)
-		if err := rows.Scan(&candidateID, &count); err != nil {
-			return nil, time.Time{}, fmt.Errorf("scan totals: %w", err)
-		}
-		totals = append(totals, &resultv1.Totals{
-			CandidateId: uint64(candidateID),
-			Count:       uint64(count),
-		})
-	}
-	if err := rows.Err(); err != nil {
-		return nil, time.Time{}, fmt.Errorf("rows totals: %w", err)
-	}
-
-	var updatedAt time.Time
-	if err := s.pool.QueryRow(ctx, `
-		SELECT COALESCE(MAX(voted_at), NOW())
-		FROM votes`).Scan(&updatedAt); err != nil {
-		return nil, time.Time{}, fmt.Errorf("query updated_at: %w", err)
+func subscribeResponse(election string, t electionTotals) *resultv1.SubscribeTotalsResponse {
+	return &resultv1.SubscribeTotalsResponse{
+		Totals:       t.totals,
+		UpdatedAt:    formatUpdatedAt(t.updatedAt),
+		ElectionId:   election,
+		TotalBallots: t.totalBallots,
+		Sequence:     t.version,
 	}
+}
 
-	return totals, updatedAt.UTC(), nil
+func (s *Server) fetchTotals(ctx context.Context, tenant, election string) (electionTotals, error) {
+	var t electionTotals
+	err := s.withTenant(ctx, tenant, func(tx pgx.Tx) error {
+		e, err := totals.Live(ctx, tx, tenant, election)
+		if err != nil {
+			return err
+		}
+		t = fromLive(e)
+		return nil
+	})
+	return t, err
 }
@@ -0,0 +1,21 @@
+package server
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/yoyo1025/k8s-vote-platform/pkg/totals"
+)
+
+func tenantOrDefault(tenant string) string {
+	if tenant == "" {
+		return totals.DefaultTenant
+	}
+	return tenant
+}
+
+// withTenant runs fn in a read-only transaction scoped to tenant; see
+// totals.WithTenant.
+func (s *Server) withTenant(ctx context.Context, tenant string, fn func(pgx.Tx) error) error {
+	return totals.WithTenant(ctx, s.db, tenant, fn)
+}
@@ -0,0 +1,134 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	resultv1 "github.com/yoyo1025/k8s-vote-platform/gen/go/result/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// timeSeriesResolution describes one bucket size maintained by the worker in
+// totals_timeseries.
+type timeSeriesResolution struct {
+	size time.Duration
+	// defaultRange applies when the request omits from.
+	defaultRange time.Duration
+	// maxRange bounds the number of buckets a single request can return.
+	maxRange time.Duration
+}
+
+var timeSeriesResolutions = map[string]timeSeriesResolution{
+	"minute": {size: time.Minute, defaultRange: time.Hour, maxRange: 24 * time.Hour},
+	"hour":   {size: time.Hour, defaultRange: 24 * time.Hour, maxRange: 90 * 24 * time.Hour},
+}
+
+// GetTimeSeries returns per-candidate vote counts bucketed by minute or hour.
+// Buckets without votes are omitted.
+func (s *Server) GetTimeSeries(ctx context.Context, req *resultv1.GetTimeSeriesRequest) (*resultv1.GetTimeSeriesResponse, error) {
+	name := req.GetResolution()
+	if name == "" {
+		name = "minute"
+	}
+	res, ok := timeSeriesResolutions[name]
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown resolution %q", name)
+	}
+
+	to := time.Now().UTC()
+	if req.GetTo() != "" {
+		t, err := time.Parse(time.RFC3339, req.GetTo())
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid to: %v", err)
+		}
+		to = t.UTC()
+	}
+	from := to.Add(-res.defaultRange)
+	if req.GetFrom() != "" {
+		t, err := time.Parse(time.RFC3339, req.GetFrom())
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid from: %v", err)
+		}
+		from = t.UTC()
+	}
+	if !from.Before(to) {
+		return nil, status.Error(codes.InvalidArgument, "from must be before to")
+	}
+	if to.Sub(from) > res.maxRange {
+		return nil, status.Errorf(codes.InvalidArgument, "range exceeds %s for %s resolution", res.maxRange, name)
+	}
+	// Include the bucket that contains from.
+	from = from.Truncate(res.size)
+
+	candidateIDs := make([]int64, 0, len(req.GetCandidateIds()))
+	for _, id := range req.GetCandidateIds() {
+		candidateIDs = append(candidateIDs, int64(id))
+	}
+
+	election := electionOrDefault(req.GetElectionId())
+	series, err := s.fetchTimeSeries(ctx, tenantOrDefault(req.GetTenant()), election, name, from, to, candidateIDs)
+	if err != nil {
+		return nil, err
+	}
+	return &resultv1.GetTimeSeriesResponse{
+		Resolution: name,
+		From:       from.Format(time.RFC3339),
+		To:         to.Format(time.RFC3339),
+		Series:     series,
+		ElectionId: election,
+	}, nil
+}
+
+func (s *Server) fetchTimeSeries(ctx context.Context, tenant, election, resolution string, from, to time.Time, candidateIDs []int64) ([]*resultv1.CandidateSeries, error) {
+	var series []*resultv1.CandidateSeries
+	err := s.withTenant(ctx, tenant, func(tx pgx.Tx) error {
+		var err error
+		series, err = scanTimeSeries(ctx, tx, tenant, election, resolution, from, to, candidateIDs)
+		return err
+	})
+	return series, err
+}
+
+func scanTimeSeries(ctx context.Context, tx pgx.Tx, tenant, election, resolution string, from, to time.Time, candidateIDs []int64) ([]*resultv1.CandidateSeries, error) {
+	rows, err := tx.Query(ctx, `
+		SELECT candidate_id, bucket_start, cnt
+		FROM totals_timeseries
+		WHERE tenant_id = $1 AND election_id = $2 AND resolution = $3
+		  AND bucket_start >= $4 AND bucket_start < $5
+		  AND (cardinality($6::bigint[]) = 0 OR candidate_id = ANY($6))
+		ORDER BY candidate_id, bucket_start`, tenant, election, resolution, from, to, candidateIDs)
+	if err != nil {
+		return nil, fmt.Errorf("query timeseries: %w", err)
+	}
+	defer rows.Close()
+
+	var (
+		series  []*resultv1.CandidateSeries
+		current *resultv1.CandidateSeries
+	)
+	for rows.Next() {
+		var (
+			candidateID int64
+			bucketStart time.Time
+			count       int64
+		)
+		if err := rows.Scan(&candidateID, &bucketStart, &count); err != nil {
+			return nil, fmt.Errorf("scan timeseries: %w", err)
+		}
+		if current == nil || current.CandidateId != uint64(candidateID) {
+			current = &resultv1.CandidateSeries{CandidateId: uint64(candidateID)}
+			series = append(series, current)
+		}
+		current.Points = append(current.Points, &resultv1.TimeSeriesPoint{
+			BucketStart: bucketStart.UTC().Format(time.RFC3339),
+			Count:       uint64(count),
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows timeseries: %w", err)
+	}
+	return series, nil
+}
@@ -0,0 +1,128 @@
+package server
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	resultv1 "github.com/yoyo1025/k8s-vote-platform/gen/go/result/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestGetTimeSeriesRejectsInvalidRequests(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *resultv1.GetTimeSeriesRequest
+	}{
+		{name: "unknown resolution", req: &resultv1.GetTimeSeriesRequest{Resolution: "day"}},
+		{name: "unparsable to", req: &resultv1.GetTimeSeriesRequest{To: "now"}},
+		{name: "unparsable from", req: &resultv1.GetTimeSeriesRequest{From: "2024-05-01"}},
+		{name: "from equals to", req: &resultv1.GetTimeSeriesRequest{
+			From: "2024-05-01T12:00:00Z", To: "2024-05-01T12:00:00Z",
+		}},
+		{name: "from after to", req: &resultv1.GetTimeSeriesRequest{
+			From: "2024-05-01T13:00:00Z", To: "2024-05-01T12:00:00Z",
+		}},
+		{name: "minute range too long", req: &resultv1.GetTimeSeriesRequest{
+			From: "2024-05-01T11:59:59Z", To: "2024-05-02T12:00:00Z",
+		}},
+		{name: "hour range too long", req: &resultv1.GetTimeSeriesRequest{
+			Resolution: "hour", From: "2024-01-01T00:00:00Z", To: "2024-05-01T00:00:00Z",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &fakeDB{}
+			s := &Server{db: db}
+			_, err := s.GetTimeSeries(context.Background(), tt.req)
+			if status.Code(err) != codes.InvalidArgument {
+				t.Fatalf("GetTimeSeries error = %v, want InvalidArgument", err)
+			}
+			if db.begun != 0 {
+				t.Fatal("GetTimeSeries queried the database")
+			}
+		})
+	}
+}
+
+func TestGetTimeSeriesQuery(t *testing.T) {
+	tests := []struct {
+		name           string
+		req            *resultv1.GetTimeSeriesRequest
+		wantResolution string
+		wantFrom       string
+		wantTo         string
+		wantElection   string
+		wantCandidates []int64
+	}{
+		{
+			name:           "minute with default range",
+			req:            &resultv1.GetTimeSeriesRequest{To: "2024-05-01T12:00:30Z"},
+			wantResolution: "minute",
+			wantFrom:       "2024-05-01T11:00:00Z",
+			wantTo:         "2024-05-01T12:00:30Z",
+			wantElection:   "default",
+			wantCandidates: []int64{},
+		},
+		{
+			name: "full minute range truncated to bucket",
+			req: &resultv1.GetTimeSeriesRequest{
+				From: "2024-05-01T12:00:30Z", To: "2024-05-02T12:00:30Z", ElectionId: "e1",
+			},
+			wantResolution: "minute",
+			wantFrom:       "2024-05-01T12:00:00Z",
+			wantTo:         "2024-05-02T12:00:30Z",
+			wantElection:   "e1",
+			wantCandidates: []int64{},
+		},
+		{
+			name: "hour with candidates and offset",
+			req: &resultv1.GetTimeSeriesRequest{
+				Resolution:   "hour",
+				From:         "2024-05-01T09:30:00+09:00",
+				To:           "2024-05-03T00:00:00Z",
+				CandidateIds: []uint64{2, 5},
+			},
+			wantResolution: "hour",
+			wantFrom:       "2024-05-01T00:00:00Z",
+			wantTo:         "2024-05-03T00:00:00Z",
+			wantElection:   "default",
+			wantCandidates: []int64{2, 5},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &fakeDB{}
+			s := &Server{db: db}
+			resp, err := s.GetTimeSeries(context.Background(), tt.req)
+			if err != nil {
+				t.Fatalf("GetTimeSeries: %v", err)
+			}
+			if resp.GetResolution() != tt.wantResolution || resp.GetFrom() != tt.wantFrom ||
+				resp.GetTo() != tt.wantTo || resp.GetElectionId() != tt.wantElection {
+				t.Fatalf("response = %s %s..%s election %q, want %s %s..%s election %q",
+					resp.GetResolution(), resp.GetFrom(), resp.GetTo(), resp.GetElectionId(),
+					tt.wantResolution, tt.wantFrom, tt.wantTo, tt.wantElection)
+			}
+
+			args := db.seriesArgs
+			if len(args) != 6 {
+				t.Fatalf("query args = %v, want 6", args)
+			}
+			if args[1] != tt.wantElection || args[2] != tt.wantResolution {
+				t.Fatalf("queried election %v resolution %v, want %s %s", args[1], args[2], tt.wantElection, tt.wantResolution)
+			}
+			if from := args[3].(time.Time).Format(time.RFC3339); from != tt.wantFrom {
+				t.Fatalf("queried from %s, want %s", from, tt.wantFrom)
+			}
+			if to := args[4].(time.Time).Format(time.RFC3339); to != tt.wantTo {
+				t.Fatalf("queried to %s, want %s", to, tt.wantTo)
+			}
+			if ids := args[5].([]int64); !slices.Equal(ids, tt.wantCandidates) {
+				t.Fatalf("queried candidates %v, want %v", ids, tt.wantCandidates)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	resultv1 "github.com/yoyo1025/k8s-vote-platform/gen/go/result/v1"
+	"github.com/yoyo1025/k8s-vote-platform/pkg/totals"
+)
+
+func electionOrDefault(election string) string {
+	if election == "" {
+		return totals.DefaultElection
+	}
+	return election
+}
+
+// electionTotals is one election's totals as returned to clients.
+type electionTotals struct {
+	totals       []*resultv1.Totals
+	updatedAt    time.Time
+	totalBallots uint64
+	// version is totals_meta.version, bumped by every worker batch that
+	// changes the election.
+	version uint64
+}
+
+func fromLive(e totals.Election) electionTotals {
+	return electionTotals{
+		totals:       toProto(e.Candidates),
+		updatedAt:    e.UpdatedAt,
+		totalBallots: uint64(e.TotalBallots),
+		version:      uint64(e.Version),
+	}
+}
+
+func toProto(candidates []totals.Candidate) []*resultv1.Totals {
+	out := make([]*resultv1.Totals, 0, len(candidates))
+	for _, c := range candidates {
+		out = append(out, &resultv1.Totals{
+			CandidateId: uint64(c.ID),
+			Count:       uint64(c.Count),
+			Name:        c.Name,
+			Percentage:  c.Percentage,
+		})
+	}
+	return out
+}
+
+// queryTotals runs a totals.CandidatesSQL query.
+func queryTotals(ctx context.Context, tx pgx.Tx, sql string, args ...any) ([]*resultv1.Totals, error) {
+	candidates, err := totals.Query(ctx, tx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	return toProto(candidates), nil
+}
+
+// countBallots returns the number of distinct voters in an election up to
+// asOf. It scans the election's votes, so it is only used for point-in-time
+// queries; live reads use totals_meta.
+func countBallots(ctx context.Context, tx pgx.Tx, tenant, election string, asOf time.Time) (uint64, error) {
+	var ballots int64
+	if err := tx.QueryRow(ctx, `
+		SELECT COUNT(DISTINCT user_id)
+		FROM votes
+		WHERE tenant_id = $1 AND election_id = $2 AND voted_at <= $3`,
+		tenant, election, asOf).Scan(&ballots); err != nil {
+		return 0, fmt.Errorf("count ballots: %w", err)
+	}
+	return uint64(ballots), nil
+}
+
+// formatUpdatedAt renders updatedAt for responses, leaving it empty for
+// elections that have no votes yet.
+func formatUpdatedAt(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
@@ -9,9 +9,13 @@ import (
 	"net/url"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/yoyo1025/k8s-vote-platform/pkg/authn"
+	"github.com/yoyo1025/k8s-vote-platform/pkg/queue"
 	"github.com/yoyo1025/k8s-vote-platform/services/vote-api/internal/server"
 )
 
@@ -24,9 +28,55 @@ func main() {
 		RedisPassword: os.Getenv("REDIS_PASSWORD"),
 		RedisStream:   getenv("REDIS_STREAM", "stream:votes"),
 		PGConnString:  buildPostgresDSN(),
+
+		QueueBackend: getenv("QUEUE_BACKEND", "redis"),
+		EventFormat:  getenv("EVENT_FORMAT", server.EventFormatLegacy),
+		NATS: queue.NATSConfig{
+			URL:     getenv("NATS_URL", "nats://localhost:4222"),
+			Stream:  getenv("NATS_STREAM", "VOTES"),
+			Subject: getenv("NATS_SUBJECT", "votes"),
+		},
+
+		ReadinessTimeout: durationDefault(os.Getenv("READINESS_TIMEOUT"), 2*time.Second),
+		RateLimits: map[string]server.RateLimit{
+			"/votes":       rateLimitDefault(os.Getenv("RATE_LIMIT_VOTES"), "60/1m"),
+			"/votes:batch": rateLimitDefault(os.Getenv("RATE_LIMIT_VOTES_BATCH"), "10/1m"),
+			"/results":     rateLimitDefault(os.Getenv("RATE_LIMIT_RESULTS"), "300/1m"),
+		},
+		MaxBatchSize: atoiDefault(os.Getenv("MAX_BATCH_SIZE"), 500),
+		Outbox: server.OutboxConfig{
+			Enabled:      os.Getenv("OUTBOX_ENABLED") == "true",
+			PollInterval: durationDefault(os.Getenv("OUTBOX_POLL_INTERVAL"), time.Second),
+			BatchSize:    atoiDefault(os.Getenv("OUTBOX_BATCH_SIZE"), 500),
+		},
+		Backpressure: server.BackpressureConfig{
+			Group:         getenv("REDIS_GROUP", "tally"),
+			MaxLen:        int64Default(os.Getenv("BACKPRESSURE_MAX_LEN"), 0),
+			MaxLag:        int64Default(os.Getenv("BACKPRESSURE_MAX_LAG"), 0),
+			Interval:      durationDefault(os.Getenv("BACKPRESSURE_INTERVAL"), time.Second),
+			RetryAfter:    durationDefault(os.Getenv("BACKPRESSURE_RETRY_AFTER"), 5*time.Second),
+			TrimInterval:  durationDefault(os.Getenv("TRIM_INTERVAL"), 0),
+			TrimRetention: durationDefault(os.Getenv("TRIM_RETENTION"), 24*time.Hour),
+		},
+		Results: server.ResultsConfig{
+			QueryAddr: os.Getenv("RESULT_QUERY_ADDR"),
+			Timeout:   durationDefault(os.Getenv("RESULT_QUERY_TIMEOUT"), 2*time.Second),
+		},
+		Auth: authn.Config{
+			JWKSURL:  getenv("AUTH_JWKS_URL", "http://auth:18080/.well-known/jwks.json"),
+			Issuer:   os.Getenv("AUTH_ISSUER"),
+			Audience: getenv("AUTH_AUDIENCE", "vote-app"),
+		},
+		TrustedProxies: splitList(os.Getenv("TRUSTED_PROXY_CIDRS")),
 	}
 	httpAddr := getenv("HTTP_ADDR", ":9080")
 
+	// The in-memory queue only reaches a worker in the same process, so a
+	// standalone vote-api would accept votes nobody counts.
+	if cfg.QueueBackend == queue.BackendMemory {
+		log.Fatal("QUEUE_BACKEND=memory is not supported by the standalone vote-api; use redis or nats")
+	}
+
 	srv, err := server.New(ctx, cfg)
 	if err != nil {
 		log.Fatalf("failed to create server: %v", err)
@@ -60,6 +110,66 @@ func getenv(key, def string) string {
 	return def
 }
 
+// splitList parses a comma-separated value, dropping empty items.
+func splitList(v string) []string {
+	var out []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+func atoiDefault(v string, def int) int {
+	if v == "" {
+		return def
+	}
+	if parsed, err := strconv.Atoi(v); err == nil && parsed > 0 {
+		return parsed
+	}
+	return def
+}
+
+func int64Default(v string, def int64) int64 {
+	if v == "" {
+		return def
+	}
+	if parsed, err := strconv.ParseInt(v, 10, 64); err == nil && parsed > 0 {
+		return parsed
+	}
+	return def
+}
+
+func durationDefault(v string, def time.Duration) time.Duration {
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return def
+	}
+	if d <= 0 {
+		return def
+	}
+	return d
+}
+
+// rateLimitDefault parses a "<limit>/<window>" value; "off" disables the limit.
+func rateLimitDefault(v, def string) server.RateLimit {
+	if v == "" {
+		v = def
+	}
+	if v == "off" {
+		return server.RateLimit{}
+	}
+	rl, err := server.ParseRateLimit(v)
+	if err != nil {
+		log.Fatalf("invalid rate limit: %v", err)
+	}
+	return rl
+}
+
 func buildPostgresDSN() string {
 	if dsn := os.Getenv("PG_DSN"); dsn != "" {
 		return dsn
@@ -67,7 +177,7 @@ func buildPostgresDSN() string {
 
 	host := getenv("PG_HOST", "localhost")
 	port := getenv("PG_PORT", "5432")
-	user := getenv("PG_USER", "app")
+	user := getenv("PG_USER", "vote_app")
 	password := os.Getenv("PG_PASSWORD")
 	database := getenv("PG_DATABASE", "app")
 	sslmode := getenv("PG_SSLMODE", "disable")
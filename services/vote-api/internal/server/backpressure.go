@@ -0,0 +1,143 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/yoyo1025/k8s-vote-platform/pkg/queue"
+)
+
+const (
+	defaultBackpressureInterval   = time.Second
+	defaultBackpressureRetryAfter = 5 * time.Second
+)
+
+// BackpressureConfig controls when vote-api stops accepting votes because
+// the workers are not keeping up, and how acknowledged entries are trimmed.
+type BackpressureConfig struct {
+	// Group is the consumer group whose lag and acknowledgements are tracked.
+	Group string
+	// MaxLen rejects votes once XLEN exceeds it. Zero disables the check.
+	MaxLen int64
+	// MaxLag rejects votes once undelivered plus pending entries for Group
+	// exceed it. Zero disables the check.
+	MaxLag int64
+	// Interval is how often the stream is inspected.
+	Interval time.Duration
+	// RetryAfter is advertised to clients while votes are rejected.
+	RetryAfter time.Duration
+
+	// TrimInterval enables periodic MINID trimming when positive.
+	TrimInterval time.Duration
+	// TrimRetention keeps acknowledged entries younger than this around.
+	TrimRetention time.Duration
+}
+
+type streamStats struct {
+	length  int64
+	backlog int64
+}
+
+// backpressure tracks the latest stream statistics and whether vote
+// ingestion should currently be rejected.
+type backpressure struct {
+	cfg        BackpressureConfig
+	overloaded atomic.Bool
+}
+
+func (b *backpressure) enabled() bool {
+	return b.cfg.MaxLen > 0 || b.cfg.MaxLag > 0
+}
+
+func (b *backpressure) update(stats streamStats) bool {
+	over := (b.cfg.MaxLen > 0 && stats.length > b.cfg.MaxLen) ||
+		(b.cfg.MaxLag > 0 && stats.backlog > b.cfg.MaxLag)
+	return b.overloaded.Swap(over) != over
+}
+
+func (s *Server) runBackpressure(ctx context.Context) {
+	ticker := time.NewTicker(s.backpressure.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		stats, err := s.streamStats(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("backpressure: inspect stream: %v", err)
+		} else if s.backpressure.update(stats) {
+			log.Printf("backpressure: overloaded=%t len=%d backlog=%d",
+				s.backpressure.overloaded.Load(), stats.length, stats.backlog)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Server) streamStats(ctx context.Context) (streamStats, error) {
+	var stats streamStats
+
+	length, err := s.redis.XLen(ctx, s.stream).Result()
+	if err != nil {
+		return stats, fmt.Errorf("xlen: %w", err)
+	}
+	stats.length = length
+
+	group, err := s.consumerGroup(ctx)
+	if err != nil {
+		return stats, err
+	}
+	if group != nil {
+		stats.backlog = group.Pending
+		if group.Lag > 0 {
+			stats.backlog += group.Lag
+		}
+	} else {
+		// No workers have joined yet; everything in the stream is backlog.
+		stats.backlog = length
+	}
+	return stats, nil
+}
+
+func (s *Server) consumerGroup(ctx context.Context) (*redis.XInfoGroup, error) {
+	return queue.RedisGroupInfo(ctx, s.redis, s.stream, s.backpressure.cfg.Group)
+}
+
+func (s *Server) runTrim(ctx context.Context) {
+	ticker := time.NewTicker(s.backpressure.cfg.TrimInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		trimmed, err := s.trimAcknowledged(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("trim: %v", err)
+			continue
+		}
+		if trimmed > 0 {
+			log.Printf("trim: removed %d acknowledged entries from %s", trimmed, s.stream)
+		}
+	}
+}
+
+// trimAcknowledged removes entries every consumer group has both received
+// and acknowledged, keeping anything newer than the retention window.
+func (s *Server) trimAcknowledged(ctx context.Context) (int64, error) {
+	return queue.TrimRedisStream(ctx, s.redis, s.stream, s.backpressure.cfg.TrimRetention)
+}
@@ -0,0 +1,69 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestBackpressureUpdate(t *testing.T) {
+	b := &backpressure{cfg: BackpressureConfig{MaxLen: 100, MaxLag: 10}}
+
+	if changed := b.update(streamStats{length: 50, backlog: 5}); changed || b.overloaded.Load() {
+		t.Fatal("expected healthy stream not to be overloaded")
+	}
+	if changed := b.update(streamStats{length: 50, backlog: 11}); !changed || !b.overloaded.Load() {
+		t.Fatal("expected backlog above MaxLag to overload")
+	}
+	if changed := b.update(streamStats{length: 101, backlog: 0}); changed || !b.overloaded.Load() {
+		t.Fatal("expected length above MaxLen to stay overloaded")
+	}
+	if changed := b.update(streamStats{length: 1, backlog: 0}); !changed || b.overloaded.Load() {
+		t.Fatal("expected recovery once below thresholds")
+	}
+}
+
+func TestTrimAcknowledgedKeepsPending(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = rdb.Close() })
+
+	const stream = "stream:votes"
+	for _, id := range []string{"1-0", "2-0", "3-0", "4-0", "5-0"} {
+		if err := rdb.XAdd(ctx, &redis.XAddArgs{Stream: stream, ID: id, Values: map[string]any{"k": "v"}}).Err(); err != nil {
+			t.Fatalf("xadd: %v", err)
+		}
+	}
+	if err := rdb.XGroupCreate(ctx, stream, "tally", "0").Err(); err != nil {
+		t.Fatalf("group create: %v", err)
+	}
+	if err := rdb.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group: "tally", Consumer: "c1", Streams: []string{stream, ">"}, Count: 3,
+	}).Err(); err != nil {
+		t.Fatalf("xreadgroup: %v", err)
+	}
+	// 2-0 stays pending, so nothing from 2-0 onwards may be trimmed.
+	if err := rdb.XAck(ctx, stream, "tally", "1-0", "3-0").Err(); err != nil {
+		t.Fatalf("xack: %v", err)
+	}
+
+	s := &Server{
+		redis:        rdb,
+		stream:       stream,
+		backpressure: &backpressure{cfg: BackpressureConfig{Group: "tally"}},
+	}
+	if _, err := s.trimAcknowledged(ctx); err != nil {
+		t.Fatalf("trim: %v", err)
+	}
+
+	msgs, err := rdb.XRange(ctx, stream, "-", "+").Result()
+	if err != nil {
+		t.Fatalf("xrange: %v", err)
+	}
+	if len(msgs) != 4 || msgs[0].ID != "2-0" {
+		t.Fatalf("expected entries from 2-0 to remain, got %v", msgs)
+	}
+}
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const defaultMaxBatchSize = 500
+
+const (
+	batchStatusAccepted = "accepted"
+	batchStatusInvalid  = "invalid"
+	batchStatusFailed   = "failed"
+)
+
+type batchVoteRequest struct {
+	Votes []voteRequest `json:"votes"`
+}
+
+type batchVoteResponse struct {
+	Accepted int               `json:"accepted"`
+	Rejected int               `json:"rejected"`
+	Results  []batchItemResult `json:"results"`
+}
+
+// batchItemResult reports the outcome of one ballot, keyed by its position in
+// the request so clients can correlate and retry individual entries.
+type batchItemResult struct {
+	Index  int    `json:"index"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+func (s *Server) handleVoteBatch(c echo.Context) error {
+	var req batchVoteRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]any{"error": "invalid payload"})
+	}
+	if len(req.Votes) == 0 {
+		return c.JSON(http.StatusBadRequest, map[string]any{"error": "votes must not be empty"})
+	}
+	if len(req.Votes) > s.maxBatchSize {
+		return c.JSON(http.StatusRequestEntityTooLarge, map[string]any{
+			"error": fmt.Sprintf("at most %d votes per batch", s.maxBatchSize),
+		})
+	}
+	// Each ballot counts against the /votes limit as well, so batching does
+	// not multiply a client's voting rate.
+	if rule, ok := s.rateLimits["/votes"]; ok && rule.Limit > 0 && len(req.Votes) > rule.Limit {
+		return c.JSON(http.StatusRequestEntityTooLarge, map[string]any{
+			"error": fmt.Sprintf("at most %d votes per %s", rule.Limit, rule.Window),
+		})
+	}
+	if ok, err := s.allowRequest(c, "/votes", len(req.Votes)); !ok {
+		return err
+	}
+	tenant := claimsFrom(c).Tenant
+	if s.backpressure.overloaded.Load() {
+		return s.rejectOverloaded(c)
+	}
+
+	results := make([]batchItemResult, len(req.Votes))
+	valid := make([]int, 0, len(req.Votes))
+	for i, vote := range req.Votes {
+		results[i] = batchItemResult{Index: i, Status: batchStatusAccepted}
+		if err := validateVoteRequest(vote); err != nil {
+			results[i].Status = batchStatusInvalid
+			results[i].Error = err.Error()
+			continue
+		}
+		valid = append(valid, i)
+	}
+
+	if len(valid) > 0 {
+		now := time.Now()
+		entries := make([]map[string]any, 0, len(valid))
+		encoded := make([]int, 0, len(valid))
+		for _, i := range valid {
+			values, err := s.voteValues(tenant, req.Votes[i], now)
+			if err != nil {
+				results[i].Status = batchStatusFailed
+				results[i].Error = "failed to encode vote"
+				continue
+			}
+			entries = append(entries, values)
+			encoded = append(encoded, i)
+		}
+		for j, err := range s.enqueue(c.Request().Context(), entries) {
+			if err != nil {
+				results[encoded[j]].Status = batchStatusFailed
+				results[encoded[j]].Error = "failed to enqueue vote"
+			}
+		}
+	}
+
+	resp := batchVoteResponse{Results: results}
+	for _, r := range results {
+		if r.Status == batchStatusAccepted {
+			resp.Accepted++
+		} else {
+			resp.Rejected++
+		}
+	}
+	return c.JSON(http.StatusMultiStatus, resp)
+}
@@ -0,0 +1,131 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/labstack/echo/v4"
+	"github.com/redis/go-redis/v9"
+	"github.com/yoyo1025/k8s-vote-platform/pkg/authn/authntest"
+	"github.com/yoyo1025/k8s-vote-platform/pkg/queue"
+)
+
+func TestHandleVoteBatch(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = rdb.Close() })
+
+	issuer := authntest.NewIssuer(t)
+	token := issuer.Token("alice", "acme")
+	s := &Server{
+		e:            echo.New(),
+		verifier:     issuer.Verifier(),
+		redis:        rdb,
+		stream:       "stream:votes",
+		producer:     queue.NewRedisProducer(rdb, "stream:votes"),
+		backpressure: &backpressure{},
+		maxBatchSize: 3,
+	}
+	s.routes()
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/votes:batch", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		s.e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := post(`{"votes":[{"user_id":1,"candidate_id":2},{"user_id":0,"candidate_id":2},{"user_id":3,"candidate_id":4}]}`)
+	if rec.Code != http.StatusMultiStatus {
+		t.Fatalf("expected 207, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp batchVoteResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Accepted != 2 || resp.Rejected != 1 {
+		t.Fatalf("unexpected counts: %+v", resp)
+	}
+	if got := resp.Results[1]; got.Status != batchStatusInvalid || got.Error == "" {
+		t.Fatalf("expected second item invalid, got %+v", got)
+	}
+
+	n, err := rdb.XLen(context.Background(), "stream:votes").Result()
+	if err != nil {
+		t.Fatalf("xlen: %v", err)
+	}
+	if n != 2 {
+		t.Fatalf("expected 2 enqueued votes, got %d", n)
+	}
+
+	if rec := post(`{"votes":[]}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for empty batch, got %d", rec.Code)
+	}
+	if rec := post(`{"votes":[{},{},{},{}]}`); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413 for oversized batch, got %d", rec.Code)
+	}
+}
+
+func TestHandleVoteBatchChargesPerBallot(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = rdb.Close() })
+
+	issuer := authntest.NewIssuer(t)
+	token := issuer.Token("alice", "acme")
+	s := &Server{
+		e:            echo.New(),
+		verifier:     issuer.Verifier(),
+		redis:        rdb,
+		stream:       "stream:votes",
+		producer:     queue.NewRedisProducer(rdb, "stream:votes"),
+		backpressure: &backpressure{},
+		maxBatchSize: 10,
+		rateLimits: map[string]RateLimit{
+			"/votes":       {Limit: 3, Window: time.Minute},
+			"/votes:batch": {Limit: 10, Window: time.Minute},
+		},
+	}
+	s.routes()
+
+	post := func(n int) int {
+		votes := make([]string, n)
+		for i := range votes {
+			votes[i] = fmt.Sprintf(`{"user_id":%d,"candidate_id":1}`, i+1)
+		}
+		body := `{"votes":[` + strings.Join(votes, ",") + `]}`
+		req := httptest.NewRequest(http.MethodPost, "/votes:batch", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		s.e.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// A batch larger than the ballot limit can never pass.
+	if code := post(4); code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("batch of 4: got %d, want 413", code)
+	}
+	if code := post(2); code != http.StatusMultiStatus {
+		t.Fatalf("batch of 2: got %d, want 207", code)
+	}
+	// Two more ballots would exceed the three allowed, even in one request.
+	if code := post(2); code != http.StatusTooManyRequests {
+		t.Fatalf("second batch of 2: got %d, want 429", code)
+	}
+	if code := post(1); code != http.StatusMultiStatus {
+		t.Fatalf("batch of 1: got %d, want 207", code)
+	}
+	if code := post(1); code != http.StatusTooManyRequests {
+		t.Fatalf("batch over the ballot limit: got %d, want 429", code)
+	}
+}
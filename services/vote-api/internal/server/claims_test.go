@@ -0,0 +1,47 @@
+package server
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/yoyo1025/k8s-vote-platform/pkg/authn/authntest"
+)
+
+func TestAuthenticate(t *testing.T) {
+	issuer := authntest.NewIssuer(t)
+	s := &Server{e: echo.New(), verifier: issuer.Verifier()}
+	handler := s.authenticate(func(c echo.Context) error {
+		return c.String(http.StatusOK, claimsFrom(c).Tenant)
+	})
+
+	do := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/votes", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		if err := handler(s.e.NewContext(req, rec)); err != nil {
+			t.Fatalf("handler error: %v", err)
+		}
+		return rec
+	}
+
+	if rec := do(issuer.Token("alice", "acme")); rec.Code != http.StatusOK || rec.Body.String() != "acme" {
+		t.Fatalf("valid token: got %d %q, want 200 acme", rec.Code, rec.Body.String())
+	}
+	// No token no longer falls back to the default tenant.
+	if rec := do(""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("missing token: got %d, want 401", rec.Code)
+	}
+	// An unsigned token naming another tenant must not be trusted.
+	unsigned := "e30." + base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"a","tenant":"acme"}`)) + ".sig"
+	if rec := do(unsigned); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("unsigned token: got %d, want 401", rec.Code)
+	}
+	if rec := do(authntest.NewIssuer(t).Token("mallory", "acme")); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("token from another key: got %d, want 401", rec.Code)
+	}
+}
@@ -0,0 +1,103 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	votev1 "github.com/yoyo1025/k8s-vote-platform/gen/go/vote/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Stream entry formats understood by the worker.
+const (
+	// EventFormatEnvelope writes a vote.v1.VoteEvent encoded as JSON.
+	EventFormatEnvelope = "envelope"
+	// EventFormatLegacy writes the flat user_id/candidate_id/ts fields that
+	// workers predating the envelope expect, plus tenant_id and election_id
+	// when they are not the defaults.
+	EventFormatLegacy = "legacy"
+)
+
+const (
+	eventTypeVoteCast    = "vote.cast"
+	voteEventSchema      = 1
+	defaultElectionID    = "default"
+	eventField           = "event"
+	contentTypeField     = "content_type"
+	contentTypeVoteEvent = "application/vnd.vote.v1.VoteEvent+json"
+
+	// legacyEventIDField lets legacy entries that may be published more than
+	// once, such as outbox rows, name a stable ID for deduplication.
+	legacyEventIDField = "event_id"
+)
+
+var producerName = func() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "unknown"
+	}
+	return "vote-api/" + host
+}()
+
+// voteValues encodes a vote cast within tenant as a stream entry in the
+// configured format.
+func (s *Server) voteValues(tenant string, req voteRequest, ts time.Time) (map[string]any, error) {
+	if s.eventFormat == EventFormatLegacy {
+		values := map[string]any{
+			"user_id":      strconv.FormatInt(req.UserID, 10),
+			"candidate_id": strconv.FormatInt(req.CandidateID, 10),
+			"ts":           ts.UTC().Format(time.RFC3339Nano),
+		}
+		// Omitted for the default tenant and election so workers that
+		// predate them keep accepting the entries.
+		if tenant != defaultTenantID {
+			values["tenant_id"] = tenant
+		}
+		if req.ElectionID != "" && req.ElectionID != defaultElectionID {
+			values["election_id"] = req.ElectionID
+		}
+		return values, nil
+	}
+
+	eventID, err := newEventID()
+	if err != nil {
+		return nil, err
+	}
+	electionID := req.ElectionID
+	if electionID == "" {
+		electionID = defaultElectionID
+	}
+	b, err := protojson.Marshal(&votev1.VoteEvent{
+		EventId:       eventID,
+		EventType:     eventTypeVoteCast,
+		SchemaVersion: voteEventSchema,
+		ElectionId:    electionID,
+		TenantId:      tenant,
+		Producer:      producerName,
+		OccurredAt:    timestamppb.New(ts),
+		Payload: &votev1.VoteEvent_VoteCast{VoteCast: &votev1.VoteCast{
+			UserId:      req.UserID,
+			CandidateId: req.CandidateID,
+		}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("encode vote event: %w", err)
+	}
+	return map[string]any{
+		contentTypeField: contentTypeVoteEvent,
+		eventField:       string(b),
+	}, nil
+}
+
+func newEventID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("generate event id: %w", err)
+	}
+	return hex.EncodeToString(b[:]), nil
+}
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	votev1 "github.com/yoyo1025/k8s-vote-platform/gen/go/vote/v1"
+	"github.com/yoyo1025/k8s-vote-platform/pkg/queue"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// TestVoteValuesCarryElection writes votes through the Redis producer and
+// reads back the fields the worker parses, in both formats. The worker side
+// of the contract is covered by TestParseMessage.
+func TestVoteValuesCarryElection(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = rdb.Close() })
+
+	publish := func(format, tenant string, req voteRequest) map[string]any {
+		t.Helper()
+		mr.FlushAll()
+		s := &Server{eventFormat: format}
+		values, err := s.voteValues(tenant, req, time.Date(2025, 2, 15, 9, 0, 0, 0, time.UTC))
+		if err != nil {
+			t.Fatalf("voteValues: %v", err)
+		}
+		if errs := queue.NewRedisProducer(rdb, "stream:votes").Publish(ctx, []map[string]any{values}); errs[0] != nil {
+			t.Fatalf("publish: %v", errs[0])
+		}
+		msgs, err := rdb.XRange(ctx, "stream:votes", "-", "+").Result()
+		if err != nil || len(msgs) != 1 {
+			t.Fatalf("xrange = %v, %v", msgs, err)
+		}
+		return msgs[0].Values
+	}
+
+	t.Run("legacy", func(t *testing.T) {
+		got := publish(EventFormatLegacy, "acme", voteRequest{UserID: 7, CandidateID: 3, ElectionID: "e1"})
+		if got["election_id"] != "e1" || got["tenant_id"] != "acme" || got["user_id"] != "7" || got["candidate_id"] != "3" {
+			t.Fatalf("legacy entry = %v", got)
+		}
+
+		// The defaults stay implicit for workers that predate them.
+		got = publish(EventFormatLegacy, defaultTenantID, voteRequest{UserID: 7, CandidateID: 3, ElectionID: defaultElectionID})
+		if _, ok := got["election_id"]; ok {
+			t.Fatalf("default election written: %v", got)
+		}
+		if _, ok := got["tenant_id"]; ok {
+			t.Fatalf("default tenant written: %v", got)
+		}
+	})
+
+	t.Run("envelope", func(t *testing.T) {
+		for req, want := range map[voteRequest]string{
+			{UserID: 7, CandidateID: 3, ElectionID: "e1"}: "e1",
+			{UserID: 7, CandidateID: 3}:                   defaultElectionID,
+		} {
+			got := publish(EventFormatEnvelope, "acme", req)
+			var ev votev1.VoteEvent
+			if err := protojson.Unmarshal([]byte(got[eventField].(string)), &ev); err != nil {
+				t.Fatalf("decode event: %v", err)
+			}
+			if ev.GetElectionId() != want || ev.GetTenantId() != "acme" || ev.GetVoteCast().GetUserId() != 7 {
+				t.Fatalf("event = %v, want election %s", &ev, want)
+			}
+		}
+	})
+}
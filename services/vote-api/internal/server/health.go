@@ -0,0 +1,34 @@
+package server
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/yoyo1025/k8s-vote-platform/pkg/health"
+)
+
+func (s *Server) healthChecks() []health.Check {
+	return []health.Check{
+		// In outbox mode votes are accepted into Postgres while Redis is
+		// down, so Redis must not take the replica out of rotation.
+		{Name: "redis", Optional: s.outbox.Enabled, Fn: func(ctx context.Context) error {
+			return s.redis.Ping(ctx).Err()
+		}},
+		{Name: "postgres", Fn: func(ctx context.Context) error {
+			return s.pgpool.Ping(ctx)
+		}},
+	}
+}
+
+func (s *Server) handleLivez(c echo.Context) error {
+	return c.NoContent(http.StatusOK)
+}
+
+func (s *Server) handleReadyz(c echo.Context) error {
+	resp, ready := health.Run(c.Request().Context(), s.readinessTimeout, s.healthChecks())
+	if !ready {
+		return c.JSON(http.StatusServiceUnavailable, resp)
+	}
+	return c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,14 @@
+package server
+
+import "testing"
+
+func TestRedisCheckOptionalWithOutbox(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		s := &Server{outbox: OutboxConfig{Enabled: enabled}}
+		for _, chk := range s.healthChecks() {
+			if want := chk.Name == "redis" && enabled; chk.Optional != want {
+				t.Fatalf("outbox %v: %s optional = %v, want %v", enabled, chk.Name, chk.Optional, want)
+			}
+		}
+	}
+}
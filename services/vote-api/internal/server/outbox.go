@@ -0,0 +1,176 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	defaultOutboxPollInterval = time.Second
+	defaultOutboxBatchSize    = 500
+)
+
+// txBeginner is the part of pgxpool.Pool used by the outbox; tests
+// substitute an in-memory implementation.
+type txBeginner interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// OutboxConfig enables the transactional outbox: votes are committed to
+// Postgres first and relayed to the stream asynchronously, so ingestion keeps
+// working while Redis is unavailable.
+type OutboxConfig struct {
+	Enabled      bool
+	PollInterval time.Duration
+	BatchSize    int
+}
+
+// enqueue publishes entries to the vote queue or, in outbox mode, stores them
+// in the vote_outbox table. It returns one error slot per entry.
+func (s *Server) enqueue(ctx context.Context, entries []map[string]any) []error {
+	if s.outbox.Enabled {
+		return s.enqueueOutbox(ctx, entries)
+	}
+	return s.producer.Publish(ctx, entries)
+}
+
+func (s *Server) enqueueOutbox(ctx context.Context, entries []map[string]any) []error {
+	errs := make([]error, len(entries))
+	fail := func(err error) []error {
+		for i := range errs {
+			errs[i] = err
+		}
+		return errs
+	}
+
+	tx, err := s.outboxDB.Begin(ctx)
+	if err != nil {
+		return fail(err)
+	}
+	defer tx.Rollback(ctx)
+
+	batch := &pgx.Batch{}
+	for _, values := range entries {
+		batch.Queue(`INSERT INTO vote_outbox (payload) VALUES ($1)`, values)
+	}
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return fail(fmt.Errorf("insert outbox: %w", err))
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fail(fmt.Errorf("commit outbox: %w", err))
+	}
+
+	// Nudge the relay so accepted votes reach the stream without waiting for
+	// the next poll.
+	select {
+	case s.outboxWake <- struct{}{}:
+	default:
+	}
+	return errs
+}
+
+func (s *Server) runOutboxRelay(ctx context.Context) {
+	ticker := time.NewTicker(s.outbox.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			n, err := s.relayOutbox(ctx)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				log.Printf("outbox relay: %v", err)
+				break
+			}
+			// Keep draining while full batches are coming back.
+			if n < s.outbox.BatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.outboxWake:
+		}
+	}
+}
+
+// relayOutbox publishes one batch of outbox rows to the queue and deletes
+// the rows that were published. A crash between XADD and commit re-publishes
+// those rows, giving at-least-once delivery. The worker deduplicates on the
+// event ID: envelopes carry their own, and legacy entries get one derived
+// from the outbox row, which stays the same when the row is re-published.
+func (s *Server) relayOutbox(ctx context.Context) (int, error) {
+	tx, err := s.outboxDB.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		SELECT id, payload
+		FROM vote_outbox
+		ORDER BY id
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED`, s.outbox.BatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("select outbox: %w", err)
+	}
+	var (
+		ids     []int64
+		entries []map[string]any
+	)
+	for rows.Next() {
+		var (
+			id      int64
+			payload map[string]any
+		)
+		if err := rows.Scan(&id, &payload); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("scan outbox: %w", err)
+		}
+		if _, ok := payload[eventField]; !ok {
+			payload[legacyEventIDField] = "outbox:" + strconv.FormatInt(id, 10)
+		}
+		ids = append(ids, id)
+		entries = append(entries, payload)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("read outbox: %w", err)
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	published := make([]int64, 0, len(ids))
+	var publishErr error
+	for i, err := range s.producer.Publish(ctx, entries) {
+		if err != nil {
+			publishErr = err
+			continue
+		}
+		published = append(published, ids[i])
+	}
+
+	if len(published) > 0 {
+		if _, err := tx.Exec(ctx, `DELETE FROM vote_outbox WHERE id = ANY($1)`, published); err != nil {
+			return 0, fmt.Errorf("delete outbox: %w", err)
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return 0, fmt.Errorf("commit outbox: %w", err)
+		}
+	}
+	if publishErr != nil {
+		return len(published), fmt.Errorf("publish outbox: %w", publishErr)
+	}
+	return len(published), nil
+}
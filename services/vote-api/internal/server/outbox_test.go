@@ -0,0 +1,275 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"maps"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/yoyo1025/k8s-vote-platform/pkg/queue"
+)
+
+// fakeOutbox keeps vote_outbox rows in memory. Writes made in a transaction
+// become visible when it commits.
+type fakeOutbox struct {
+	mu     sync.Mutex
+	nextID int64
+	rows   map[int64]map[string]any
+	// failCommit makes commits that delete rows fail.
+	failCommit bool
+}
+
+func newFakeOutbox() *fakeOutbox {
+	return &fakeOutbox{rows: make(map[int64]map[string]any)}
+}
+
+func (db *fakeOutbox) Begin(context.Context) (pgx.Tx, error) {
+	return &fakeOutboxTx{db: db}, nil
+}
+
+func (db *fakeOutbox) len() int {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return len(db.rows)
+}
+
+type fakeOutboxTx struct {
+	pgx.Tx
+	db      *fakeOutbox
+	inserts []map[string]any
+	deletes []int64
+}
+
+// SendBatch takes the INSERT INTO vote_outbox statements of enqueueOutbox.
+// Payloads go through JSON as they would through the jsonb column.
+func (tx *fakeOutboxTx) SendBatch(_ context.Context, b *pgx.Batch) pgx.BatchResults {
+	for _, q := range b.QueuedQueries {
+		raw, _ := json.Marshal(q.Arguments[0])
+		var payload map[string]any
+		_ = json.Unmarshal(raw, &payload)
+		tx.inserts = append(tx.inserts, payload)
+	}
+	return fakeBatchResults{}
+}
+
+type fakeBatchResults struct{ pgx.BatchResults }
+
+func (fakeBatchResults) Close() error { return nil }
+
+// Query answers the relay's SELECT ... ORDER BY id LIMIT $1.
+func (tx *fakeOutboxTx) Query(_ context.Context, _ string, args ...any) (pgx.Rows, error) {
+	tx.db.mu.Lock()
+	defer tx.db.mu.Unlock()
+	rows := &fakeOutboxRows{}
+	for _, id := range slices.Sorted(maps.Keys(tx.db.rows)) {
+		if len(rows.ids) == args[0].(int) {
+			break
+		}
+		rows.ids = append(rows.ids, id)
+		rows.payloads = append(rows.payloads, tx.db.rows[id])
+	}
+	return rows, nil
+}
+
+// Exec answers the relay's DELETE ... WHERE id = ANY($1).
+func (tx *fakeOutboxTx) Exec(_ context.Context, _ string, args ...any) (pgconn.CommandTag, error) {
+	tx.deletes = append(tx.deletes, args[0].([]int64)...)
+	return pgconn.NewCommandTag("DELETE"), nil
+}
+
+func (tx *fakeOutboxTx) Commit(context.Context) error {
+	tx.db.mu.Lock()
+	defer tx.db.mu.Unlock()
+	if tx.db.failCommit && len(tx.deletes) > 0 {
+		return errors.New("connection lost")
+	}
+	for _, payload := range tx.inserts {
+		tx.db.nextID++
+		tx.db.rows[tx.db.nextID] = payload
+	}
+	for _, id := range tx.deletes {
+		delete(tx.db.rows, id)
+	}
+	tx.inserts, tx.deletes = nil, nil
+	return nil
+}
+
+func (tx *fakeOutboxTx) Rollback(context.Context) error { return nil }
+
+type fakeOutboxRows struct {
+	pgx.Rows
+	ids      []int64
+	payloads []map[string]any
+	next     int
+}
+
+func (r *fakeOutboxRows) Next() bool {
+	r.next++
+	return r.next <= len(r.ids)
+}
+
+func (r *fakeOutboxRows) Scan(dest ...any) error {
+	*dest[0].(*int64) = r.ids[r.next-1]
+	*dest[1].(*map[string]any) = r.payloads[r.next-1]
+	return nil
+}
+
+func (r *fakeOutboxRows) Close()     {}
+func (r *fakeOutboxRows) Err() error { return nil }
+
+// flakyProducer fails every entry whose candidate_id is in fail.
+type flakyProducer struct {
+	*queue.Memory
+	fail string
+}
+
+func (p *flakyProducer) Publish(ctx context.Context, entries []map[string]any) []error {
+	errs := make([]error, len(entries))
+	var ok []map[string]any
+	for i, values := range entries {
+		if values["candidate_id"] == p.fail {
+			errs[i] = errors.New("redis unavailable")
+			continue
+		}
+		ok = append(ok, values)
+	}
+	p.Memory.Publish(ctx, ok)
+	return errs
+}
+
+func newOutboxTestServer(producer queue.Producer) (*Server, *fakeOutbox) {
+	db := newFakeOutbox()
+	return &Server{
+		producer:   producer,
+		outbox:     OutboxConfig{Enabled: true, PollInterval: time.Hour, BatchSize: 2},
+		outboxDB:   db,
+		outboxWake: make(chan struct{}, 1),
+	}, db
+}
+
+func outboxVotes(candidates ...string) []map[string]any {
+	entries := make([]map[string]any, 0, len(candidates))
+	for _, c := range candidates {
+		entries = append(entries, map[string]any{"user_id": "1", "candidate_id": c})
+	}
+	return entries
+}
+
+func TestOutboxEnqueueStoresVotes(t *testing.T) {
+	mem := queue.NewMemory()
+	s, db := newOutboxTestServer(mem)
+
+	for i, err := range s.enqueue(context.Background(), outboxVotes("1", "2", "3")) {
+		if err != nil {
+			t.Fatalf("entry %d: %v", i, err)
+		}
+	}
+	if n := db.len(); n != 3 {
+		t.Fatalf("outbox holds %d rows, want 3", n)
+	}
+	// Nothing reaches the queue until the relay runs, but it is woken.
+	if msgs, _ := mem.Read(context.Background(), 10, 0); len(msgs) != 0 {
+		t.Fatalf("%d votes published before the relay ran", len(msgs))
+	}
+	select {
+	case <-s.outboxWake:
+	default:
+		t.Fatal("relay was not woken")
+	}
+}
+
+func TestOutboxRelayDrainsToQueue(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mem := queue.NewMemory()
+	s, db := newOutboxTestServer(mem)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.runOutboxRelay(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// Five rows take three batches of two; the relay keeps going without
+	// waiting for the hour-long poll interval.
+	s.enqueue(ctx, outboxVotes("1", "2", "3", "4", "5"))
+	var got []string
+	for len(got) < 5 {
+		msgs, err := mem.Read(ctx, 10, time.Second)
+		if err != nil || len(msgs) == 0 {
+			t.Fatalf("relayed %v before stalling: %v", got, err)
+		}
+		for _, msg := range msgs {
+			got = append(got, msg.Values["candidate_id"].(string))
+		}
+	}
+	if !slices.Equal(got, []string{"1", "2", "3", "4", "5"}) {
+		t.Fatalf("relayed %v, want votes in outbox order", got)
+	}
+	deadline := time.Now().Add(time.Second)
+	for db.len() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d rows left in the outbox", db.len())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestOutboxRelayKeepsUnpublishedRows(t *testing.T) {
+	ctx := context.Background()
+	s, db := newOutboxTestServer(&flakyProducer{Memory: queue.NewMemory(), fail: "2"})
+	s.enqueue(ctx, outboxVotes("1", "2"))
+
+	n, err := s.relayOutbox(ctx)
+	if n != 1 || err == nil {
+		t.Fatalf("relayOutbox = %d, %v; want 1 and the publish error", n, err)
+	}
+	if db.len() != 1 || db.rows[2]["candidate_id"] != "2" {
+		t.Fatalf("outbox rows = %v, want only the unpublished vote", db.rows)
+	}
+}
+
+func TestOutboxRelayGivesLegacyEntriesStableIDs(t *testing.T) {
+	ctx := context.Background()
+	mem := queue.NewMemory()
+	s, db := newOutboxTestServer(mem)
+	s.enqueue(ctx, outboxVotes("1"))
+	s.enqueue(ctx, []map[string]any{{contentTypeField: contentTypeVoteEvent, eventField: `{"eventId":"abc"}`}})
+
+	// The first relay publishes but loses its commit, as after a crash.
+	db.failCommit = true
+	if _, err := s.relayOutbox(ctx); err == nil {
+		t.Fatal("expected the commit error")
+	}
+	db.failCommit = false
+	if _, err := s.relayOutbox(ctx); err != nil {
+		t.Fatalf("relayOutbox: %v", err)
+	}
+
+	msgs, _ := mem.Read(ctx, 10, 0)
+	if len(msgs) != 4 {
+		t.Fatalf("published %d entries, want both rows twice", len(msgs))
+	}
+	for i, msg := range msgs {
+		if i%2 == 0 {
+			if msg.Values[legacyEventIDField] != "outbox:1" {
+				t.Fatalf("legacy entry %d = %v, want event_id outbox:1", i, msg.Values)
+			}
+			continue
+		}
+		// Envelopes carry their own event ID and are relayed unchanged.
+		if _, ok := msg.Values[legacyEventIDField]; ok {
+			t.Fatalf("envelope entry %d = %v, want no event_id field", i, msg.Values)
+		}
+	}
+}
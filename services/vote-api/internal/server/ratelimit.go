@@ -0,0 +1,161 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/redis/go-redis/v9"
+)
+
+const rateLimitKeyPrefix = "ratelimit"
+
+// RateLimit caps how many requests a single key may make within Window.
+type RateLimit struct {
+	Limit  int
+	Window time.Duration
+}
+
+// ParseRateLimit parses a "<limit>/<window>" expression such as "30/1m".
+func ParseRateLimit(v string) (RateLimit, error) {
+	limitStr, windowStr, ok := strings.Cut(strings.TrimSpace(v), "/")
+	if !ok {
+		return RateLimit{}, fmt.Errorf("rate limit %q must be <limit>/<window>", v)
+	}
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit <= 0 {
+		return RateLimit{}, fmt.Errorf("rate limit %q: limit must be a positive integer", v)
+	}
+	window, err := time.ParseDuration(windowStr)
+	if err != nil || window <= 0 {
+		return RateLimit{}, fmt.Errorf("rate limit %q: window must be a positive duration", v)
+	}
+	return RateLimit{Limit: limit, Window: window}, nil
+}
+
+// slidingWindowScript implements a sliding-window log on one sorted set per
+// key. A request costs one entry per unit and is only recorded when every key
+// has room for all of them, so a request rejected by one key does not use up
+// the others. It returns {allowed} then {count, reset_ms} per key, where
+// reset_ms is the time until the oldest entry in that key's window expires.
+var slidingWindowScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+local member = ARGV[4]
+local cost = tonumber(ARGV[5])
+
+local counts = {}
+local allowed = 1
+for i, key in ipairs(KEYS) do
+  redis.call('ZREMRANGEBYSCORE', key, '-inf', now - window)
+  counts[i] = redis.call('ZCARD', key)
+  if counts[i] + cost > limit then
+    allowed = 0
+  end
+end
+
+local reply = {allowed}
+for i, key in ipairs(KEYS) do
+  if allowed == 1 then
+    for j = 1, cost do
+      redis.call('ZADD', key, now, member .. ':' .. j)
+    end
+    counts[i] = counts[i] + cost
+  end
+  redis.call('PEXPIRE', key, window)
+
+  local reset = window
+  local oldest = redis.call('ZRANGE', key, 0, 0, 'WITHSCORES')
+  if oldest[2] then
+    reset = tonumber(oldest[2]) + window - now
+  end
+  table.insert(reply, counts[i])
+  table.insert(reply, reset)
+end
+return reply
+`)
+
+type rateLimitResult struct {
+	allowed   bool
+	remaining int
+	reset     time.Duration
+}
+
+// takeTokens records cost requests against every key if all of them have room
+// under rule's limit. The result reports the tightest remaining count and the
+// longest reset across the keys.
+func (s *Server) takeTokens(ctx context.Context, keys []string, rule RateLimit, cost int) (rateLimitResult, error) {
+	now := time.Now()
+	member := strconv.FormatInt(now.UnixNano(), 10)
+	res, err := slidingWindowScript.Run(ctx, s.redis, keys,
+		now.UnixMilli(), rule.Window.Milliseconds(), rule.Limit, member, cost).Int64Slice()
+	if err != nil {
+		return rateLimitResult{}, err
+	}
+	if len(res) != 1+2*len(keys) {
+		return rateLimitResult{}, errors.New("unexpected rate limit script reply")
+	}
+
+	result := rateLimitResult{allowed: res[0] == 1, remaining: rule.Limit}
+	for i := range keys {
+		count, reset := res[1+2*i], res[2+2*i]
+		result.remaining = min(result.remaining, max(rule.Limit-int(count), 0))
+		result.reset = max(result.reset, time.Duration(reset)*time.Millisecond)
+	}
+	return result, nil
+}
+
+// rateLimit returns middleware enforcing the rule configured for route. Each
+// request is checked against both the client IP and, when a bearer token is
+// present, the token subject, and is only counted when both allow it.
+func (s *Server) rateLimit(route string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if ok, err := s.allowRequest(c, route, 1); !ok {
+				return err
+			}
+			return next(c)
+		}
+	}
+}
+
+// allowRequest charges cost requests to the rule configured for route and
+// sets the RateLimit headers. When the limit is exceeded it writes the 429
+// response and returns false. Routes without a rule are always allowed.
+func (s *Server) allowRequest(c echo.Context, route string, cost int) (bool, error) {
+	rule, ok := s.rateLimits[route]
+	if !ok || rule.Limit <= 0 || rule.Window <= 0 {
+		return true, nil
+	}
+
+	keys := []string{fmt.Sprintf("%s:%s:ip:%s", rateLimitKeyPrefix, route, c.RealIP())}
+	if claims := claimsFrom(c); claims.Subject != "" {
+		keys = append(keys, fmt.Sprintf("%s:%s:sub:%s", rateLimitKeyPrefix, route, claims.Subject))
+	}
+
+	result, err := s.takeTokens(c.Request().Context(), keys, rule, cost)
+	if err != nil {
+		// Fail open: a Redis outage should not stop voting.
+		c.Logger().Warnf("rate limit check failed for %v: %v", keys, err)
+		return true, nil
+	}
+
+	resetSeconds := strconv.Itoa(int(math.Ceil(result.reset.Seconds())))
+	h := c.Response().Header()
+	h.Set("RateLimit-Limit", strconv.Itoa(rule.Limit))
+	h.Set("RateLimit-Remaining", strconv.Itoa(result.remaining))
+	h.Set("RateLimit-Reset", resetSeconds)
+
+	if !result.allowed {
+		h.Set("Retry-After", resetSeconds)
+		return false, c.JSON(http.StatusTooManyRequests, map[string]any{"error": "rate limit exceeded"})
+	}
+	return true, nil
+}
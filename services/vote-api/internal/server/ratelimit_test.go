@@ -0,0 +1,196 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/labstack/echo/v4"
+	"github.com/redis/go-redis/v9"
+	"github.com/yoyo1025/k8s-vote-platform/pkg/authn"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	rl, err := ParseRateLimit("30/1m")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rl.Limit != 30 || rl.Window != time.Minute {
+		t.Fatalf("unexpected rate limit: %+v", rl)
+	}
+
+	for _, v := range []string{"", "30", "0/1m", "x/1m", "30/abc", "30/-1s"} {
+		if _, err := ParseRateLimit(v); err == nil {
+			t.Errorf("expected error for %q", v)
+		}
+	}
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = rdb.Close() })
+
+	s := &Server{
+		e:          echo.New(),
+		redis:      rdb,
+		rateLimits: map[string]RateLimit{"/votes": {Limit: 2, Window: time.Minute}},
+	}
+	handler := s.rateLimit("/votes")(func(c echo.Context) error {
+		return c.NoContent(http.StatusAccepted)
+	})
+
+	do := func(subject string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/votes", nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		rec := httptest.NewRecorder()
+		c := s.e.NewContext(req, rec)
+		c.Set(claimsContextKey, authn.Claims{Subject: subject, Tenant: defaultTenantID})
+		if err := handler(c); err != nil {
+			t.Fatalf("handler error: %v", err)
+		}
+		return rec
+	}
+
+	for i := 0; i < 2; i++ {
+		rec := do("")
+		if rec.Code != http.StatusAccepted {
+			t.Fatalf("request %d: expected 202, got %d", i, rec.Code)
+		}
+		if got := rec.Header().Get("RateLimit-Limit"); got != "2" {
+			t.Fatalf("expected RateLimit-Limit 2, got %q", got)
+		}
+	}
+
+	rec := do("")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Fatal("expected Retry-After header on 429")
+	}
+	if got := rec.Header().Get("RateLimit-Remaining"); got != "0" {
+		t.Fatalf("expected RateLimit-Remaining 0, got %q", got)
+	}
+}
+
+func TestRateLimitChecksAllKeysBeforeCounting(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = rdb.Close() })
+
+	s := &Server{
+		e:          echo.New(),
+		redis:      rdb,
+		rateLimits: map[string]RateLimit{"/votes": {Limit: 2, Window: time.Minute}},
+	}
+	handler := s.rateLimit("/votes")(func(c echo.Context) error {
+		return c.NoContent(http.StatusAccepted)
+	})
+
+	do := func(ip, subject string) int {
+		req := httptest.NewRequest(http.MethodPost, "/votes", nil)
+		req.RemoteAddr = ip + ":1234"
+		rec := httptest.NewRecorder()
+		c := s.e.NewContext(req, rec)
+		c.Set(claimsContextKey, authn.Claims{Subject: subject, Tenant: defaultTenantID})
+		if err := handler(c); err != nil {
+			t.Fatalf("handler error: %v", err)
+		}
+		return rec.Code
+	}
+
+	// alice uses up the shared IP's allowance.
+	for i := 0; i < 2; i++ {
+		if code := do("10.0.0.1", "alice"); code != http.StatusAccepted {
+			t.Fatalf("alice request %d: got %d, want 202", i, code)
+		}
+	}
+	// bob is rejected by the IP key, which must not cost him a token.
+	if code := do("10.0.0.1", "bob"); code != http.StatusTooManyRequests {
+		t.Fatalf("bob on shared IP: got %d, want 429", code)
+	}
+	for i := 0; i < 2; i++ {
+		if code := do("10.0.0.2", "bob"); code != http.StatusAccepted {
+			t.Fatalf("bob request %d from own IP: got %d, want 202", i, code)
+		}
+	}
+}
+
+func TestRateLimitIgnoresSpoofedForwardedFor(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = rdb.Close() })
+
+	for _, tc := range []struct {
+		name    string
+		trusted []string
+	}{
+		{name: "no gateway configured"},
+		{name: "peer outside the gateway range", trusted: []string{"10.1.0.0/16"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mr.FlushAll()
+			extractor, err := newIPExtractor(tc.trusted)
+			if err != nil {
+				t.Fatalf("newIPExtractor: %v", err)
+			}
+			e := echo.New()
+			e.IPExtractor = extractor
+			s := &Server{
+				e:          e,
+				redis:      rdb,
+				rateLimits: map[string]RateLimit{"/votes": {Limit: 1, Window: time.Minute}},
+			}
+			handler := s.rateLimit("/votes")(func(c echo.Context) error {
+				return c.NoContent(http.StatusAccepted)
+			})
+
+			// A client on the cluster network rotating X-Forwarded-For values
+			// still shares one key.
+			for i, want := range []int{http.StatusAccepted, http.StatusTooManyRequests} {
+				req := httptest.NewRequest(http.MethodPost, "/votes", nil)
+				req.RemoteAddr = "10.0.0.7:1234"
+				req.Header.Set(echo.HeaderXForwardedFor, fmt.Sprintf("203.0.113.%d", i+1))
+				rec := httptest.NewRecorder()
+				if err := handler(e.NewContext(req, rec)); err != nil {
+					t.Fatalf("handler error: %v", err)
+				}
+				if rec.Code != want {
+					t.Fatalf("request %d: got %d, want %d", i, rec.Code, want)
+				}
+			}
+		})
+	}
+}
+
+func TestNewIPExtractor(t *testing.T) {
+	extract, err := newIPExtractor([]string{"10.1.0.0/16"})
+	if err != nil {
+		t.Fatalf("newIPExtractor: %v", err)
+	}
+	for _, tc := range []struct {
+		peer, xff, want string
+	}{
+		{peer: "10.1.2.3", xff: "203.0.113.9", want: "203.0.113.9"},
+		{peer: "10.0.0.7", xff: "203.0.113.9", want: "10.0.0.7"},
+		{peer: "127.0.0.1", xff: "203.0.113.9", want: "127.0.0.1"},
+		{peer: "10.1.2.3", want: "10.1.2.3"},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = tc.peer + ":1234"
+		if tc.xff != "" {
+			req.Header.Set(echo.HeaderXForwardedFor, tc.xff)
+		}
+		if got := extract(req); got != tc.want {
+			t.Errorf("peer %s xff %q: got %s, want %s", tc.peer, tc.xff, got, tc.want)
+		}
+	}
+
+	if _, err := newIPExtractor([]string{"not-a-cidr"}); err == nil {
+		t.Fatal("expected error for invalid CIDR")
+	}
+}
@@ -0,0 +1,176 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v4"
+	resultv1 "github.com/yoyo1025/k8s-vote-platform/gen/go/result/v1"
+	"github.com/yoyo1025/k8s-vote-platform/pkg/totals"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+const defaultResultsTimeout = 2 * time.Second
+
+// statusClientClosedRequest is the nginx convention for a client that went
+// away before the response; nothing is sent, but it keeps access logs from
+// recording an error.
+const statusClientClosedRequest = 499
+
+// ResultsConfig points GET /results at result-query. Without an address, or
+// when result-query is unavailable or times out, totals are read from
+// Postgres with the same queries result-query uses.
+type ResultsConfig struct {
+	QueryAddr string
+	// Timeout bounds each GetTotals call before falling back.
+	Timeout time.Duration
+}
+
+type resultsProxy struct {
+	conn    *grpc.ClientConn
+	client  resultv1.ResultServiceClient
+	timeout time.Duration
+}
+
+func newResultsProxy(cfg ResultsConfig) (*resultsProxy, error) {
+	if cfg.QueryAddr == "" {
+		return nil, nil
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultResultsTimeout
+	}
+	conn, err := grpc.NewClient(cfg.QueryAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("result-query client: %w", err)
+	}
+	return &resultsProxy{
+		conn:    conn,
+		client:  resultv1.NewResultServiceClient(conn),
+		timeout: cfg.Timeout,
+	}, nil
+}
+
+func (p *resultsProxy) Close() error {
+	if p == nil {
+		return nil
+	}
+	return p.conn.Close()
+}
+
+type totalsResponse struct {
+	Totals []candidateTotal `json:"totals"`
+	// UpdatedAt is the time of the latest vote, null if there is none.
+	UpdatedAt *time.Time `json:"updated_at"`
+}
+
+type candidateTotal struct {
+	CandidateID int64 `json:"candidate_id"`
+	Count       int64 `json:"count"`
+}
+
+func (s *Server) handleResults(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	tenant := claimsFrom(c).Tenant
+	election := c.QueryParam("election_id")
+	if election == "" {
+		election = defaultElectionID
+	}
+	if len(election) > maxElectionIDLen {
+		return c.JSON(http.StatusBadRequest, map[string]any{
+			"error": fmt.Sprintf("election_id must be at most %d characters", maxElectionIDLen),
+		})
+	}
+
+	if s.results != nil {
+		resp, err := s.results.totals(ctx, tenant, election)
+		switch {
+		case err == nil:
+			return c.JSON(http.StatusOK, resp)
+		case status.Code(err) == codes.InvalidArgument:
+			return c.JSON(http.StatusBadRequest, map[string]any{"error": status.Convert(err).Message()})
+		case ctx.Err() != nil || status.Code(err) == codes.Canceled:
+			// The client went away; reading Postgres instead would be wasted.
+			return c.NoContent(statusClientClosedRequest)
+		case !resultsUnavailable(err):
+			log.Printf("results: result-query failed: %v", err)
+			return c.JSON(http.StatusBadGateway, map[string]any{"error": "failed to query totals"})
+		}
+		log.Printf("results: result-query unavailable, reading totals locally: %v", err)
+	}
+
+	resp, err := s.localTotals(ctx, tenant, election)
+	if err != nil {
+		return c.JSON(http.StatusBadGateway, map[string]any{"error": "failed to query totals"})
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// resultsUnavailable reports whether err means result-query could not be
+// reached in time, the only case where Postgres is read instead. Any other
+// failure would most likely repeat on the local read.
+func resultsUnavailable(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return true
+	default:
+		return false
+	}
+}
+
+func (p *resultsProxy) totals(ctx context.Context, tenant, election string) (totalsResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	res, err := p.client.GetTotals(ctx, &resultv1.GetTotalsRequest{Tenant: tenant, ElectionId: election})
+	if err != nil {
+		return totalsResponse{}, err
+	}
+	resp := totalsResponse{Totals: make([]candidateTotal, 0, len(res.GetTotals()))}
+	for _, t := range res.GetTotals() {
+		resp.Totals = append(resp.Totals, candidateTotal{
+			CandidateID: int64(t.GetCandidateId()),
+			Count:       int64(t.GetCount()),
+		})
+	}
+	if res.GetUpdatedAt() != "" {
+		updatedAt, err := time.Parse(time.RFC3339, res.GetUpdatedAt())
+		if err != nil {
+			return totalsResponse{}, fmt.Errorf("parse updated_at: %w", err)
+		}
+		updatedAt = updatedAt.UTC()
+		resp.UpdatedAt = &updatedAt
+	}
+	return resp, nil
+}
+
+// localTotals reads totals like result-query does, rounding updated_at to
+// the second as result-query's RFC 3339 timestamps are.
+func (s *Server) localTotals(ctx context.Context, tenant, election string) (totalsResponse, error) {
+	var live totals.Election
+	err := totals.WithTenant(ctx, s.pgpool, tenant, func(tx pgx.Tx) error {
+		var err error
+		live, err = totals.Live(ctx, tx, tenant, election)
+		return err
+	})
+	if err != nil {
+		return totalsResponse{}, err
+	}
+
+	resp := totalsResponse{Totals: make([]candidateTotal, 0, len(live.Candidates))}
+	for _, c := range live.Candidates {
+		resp.Totals = append(resp.Totals, candidateTotal{CandidateID: c.ID, Count: c.Count})
+	}
+	if !live.UpdatedAt.IsZero() {
+		updatedAt := live.UpdatedAt.UTC().Truncate(time.Second)
+		resp.UpdatedAt = &updatedAt
+	}
+	return resp, nil
+}
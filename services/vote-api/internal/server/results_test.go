@@ -0,0 +1,143 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	resultv1 "github.com/yoyo1025/k8s-vote-platform/gen/go/result/v1"
+	"github.com/yoyo1025/k8s-vote-platform/pkg/authn"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type fakeResultClient struct {
+	resultv1.ResultServiceClient
+	req  *resultv1.GetTotalsRequest
+	resp *resultv1.GetTotalsResponse
+	err  error
+}
+
+func (f *fakeResultClient) GetTotals(_ context.Context, req *resultv1.GetTotalsRequest, _ ...grpc.CallOption) (*resultv1.GetTotalsResponse, error) {
+	f.req = req
+	return f.resp, f.err
+}
+
+func TestHandleResultsProxiesToResultQuery(t *testing.T) {
+	client := &fakeResultClient{resp: &resultv1.GetTotalsResponse{
+		Totals: []*resultv1.Totals{
+			{CandidateId: 2, Count: 5, Name: "B", Percentage: 62.5},
+			{CandidateId: 1, Count: 3, Name: "A", Percentage: 37.5},
+		},
+		UpdatedAt: "2025-04-20T09:00:00Z",
+	}}
+	s := &Server{results: &resultsProxy{client: client, timeout: time.Second}}
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/results?election_id=e1", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set(claimsContextKey, authn.Claims{Subject: "alice", Tenant: defaultTenantID})
+	if err := s.handleResults(c); err != nil {
+		t.Fatalf("handleResults: %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	if client.req.GetTenant() != defaultTenantID || client.req.GetElectionId() != "e1" {
+		t.Fatalf("unexpected request %v", client.req)
+	}
+
+	// The shape must match what localTotals produces.
+	want := `{"totals":[{"candidate_id":2,"count":5},{"candidate_id":1,"count":3}],"updated_at":"2025-04-20T09:00:00Z"}`
+	var got, exp any
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+	_ = json.Unmarshal([]byte(want), &exp)
+	gotJSON, _ := json.Marshal(got)
+	expJSON, _ := json.Marshal(exp)
+	if string(gotJSON) != string(expJSON) {
+		t.Fatalf("body = %s, want %s", gotJSON, expJSON)
+	}
+}
+
+func TestResultsProxyEmptyElection(t *testing.T) {
+	p := &resultsProxy{client: &fakeResultClient{resp: &resultv1.GetTotalsResponse{}}, timeout: time.Second}
+	resp, err := p.totals(context.Background(), defaultTenantID, defaultElectionID)
+	if err != nil {
+		t.Fatalf("totals: %v", err)
+	}
+	b, _ := json.Marshal(resp)
+	if string(b) != `{"totals":[],"updated_at":null}` {
+		t.Fatalf("body = %s", b)
+	}
+}
+
+// resultsWithError calls handleResults with result-query failing with err.
+// pgpool is nil: falling back to Postgres would panic.
+func resultsWithError(ctx context.Context, err error) (*httptest.ResponseRecorder, error) {
+	s := &Server{results: &resultsProxy{client: &fakeResultClient{err: err}, timeout: time.Second}}
+	req := httptest.NewRequest(http.MethodGet, "/results", nil).WithContext(ctx)
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(req, rec)
+	c.Set(claimsContextKey, authn.Claims{Subject: "alice", Tenant: defaultTenantID})
+	return rec, s.handleResults(c)
+}
+
+func TestHandleResultsReturnsClientErrors(t *testing.T) {
+	rec, err := resultsWithError(context.Background(), status.Error(codes.InvalidArgument, "election_id too long"))
+	if err != nil || rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "election_id too long") {
+		t.Fatalf("invalid argument: %d %s, %v; want 400", rec.Code, rec.Body.String(), err)
+	}
+
+	rec, err = resultsWithError(context.Background(), status.Error(codes.PermissionDenied, "nope"))
+	if err != nil || rec.Code != http.StatusBadGateway {
+		t.Fatalf("permission denied: %d, %v; want 502", rec.Code, err)
+	}
+
+	rec, err = resultsWithError(context.Background(), status.Error(codes.Internal, "boom"))
+	if err != nil || rec.Code != http.StatusBadGateway {
+		t.Fatalf("internal: %d, %v; want 502", rec.Code, err)
+	}
+}
+
+func TestHandleResultsCancelledRequest(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	// Whether the client's context is seen as done or only the upstream
+	// reports Canceled, the handler must not return an error Echo would
+	// render as 500.
+	for name, tc := range map[string]struct {
+		ctx context.Context
+		err error
+	}{
+		"client context done": {ctx, status.FromContextError(ctx.Err()).Err()},
+		"upstream canceled":   {context.Background(), status.Error(codes.Canceled, "context canceled")},
+	} {
+		rec, err := resultsWithError(tc.ctx, tc.err)
+		if err != nil || rec.Code != statusClientClosedRequest {
+			t.Fatalf("%s: %d, %v; want %d and no error", name, rec.Code, err, statusClientClosedRequest)
+		}
+	}
+}
+
+func TestResultsUnavailable(t *testing.T) {
+	for code, want := range map[codes.Code]bool{
+		codes.Unavailable:      true,
+		codes.DeadlineExceeded: true,
+		codes.Canceled:         false,
+		codes.InvalidArgument:  false,
+		codes.Internal:         false,
+	} {
+		if got := resultsUnavailable(status.Error(code, "x")); got != want {
+			t.Errorf("resultsUnavailable(%s) = %v, want %v", code, got, want)
+		}
+	}
+}
@@ -4,15 +4,24 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math"
+	"net"
 	"net/http"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/labstack/echo/v4"
 	"github.com/redis/go-redis/v9"
+	"github.com/yoyo1025/k8s-vote-platform/pkg/authn"
+	"github.com/yoyo1025/k8s-vote-platform/pkg/health"
+	"github.com/yoyo1025/k8s-vote-platform/pkg/queue"
 )
 
+const maxElectionIDLen = 64
+
 // Config bundles external dependencies required to run the vote API.
 type Config struct {
 	RedisAddr     string
@@ -20,6 +29,48 @@ type Config struct {
 	RedisPassword string
 	RedisStream   string
 	PGConnString  string
+
+	// QueueBackend selects the vote transport: "redis" (default), "nats" or
+	// "memory". "memory" keeps votes in this process and is only useful
+	// in tests. Rate limiting always uses Redis; backpressure and trimming
+	// only apply to the Redis backend.
+	QueueBackend string
+	NATS         queue.NATSConfig
+
+	// EventFormat selects how votes are written to the queue: "legacy"
+	// (default) or "envelope". Roll out workers that read both formats before
+	// switching producers to the envelope.
+	EventFormat string
+
+	// ReadinessTimeout bounds each dependency check performed by /readyz.
+	ReadinessTimeout time.Duration
+
+	// RateLimits maps a route path (e.g. "/votes") to the limit applied per
+	// client IP and per token subject. Routes without an entry are unlimited.
+	// Ballots in a batch also count against the "/votes" limit.
+	RateLimits map[string]RateLimit
+
+	// MaxBatchSize caps the number of ballots accepted by POST /votes:batch.
+	MaxBatchSize int
+
+	// Outbox routes votes through Postgres before they reach the stream.
+	Outbox OutboxConfig
+
+	// Backpressure rejects votes while the stream backlog is too large and
+	// trims entries the worker group has already acknowledged.
+	Backpressure BackpressureConfig
+
+	// Results forwards GET /results to result-query.
+	Results ResultsConfig
+
+	// Auth locates the auth service's signing keys. Every vote and results
+	// request must carry a token they verify.
+	Auth authn.Config
+
+	// TrustedProxies lists the CIDRs of the gateway in front of vote-api.
+	// X-Forwarded-For is only honoured from these peers; without any, the
+	// client IP is always the connection's peer address.
+	TrustedProxies []string
 }
 
 // Server exposes REST endpoints to accept votes and read aggregates.
@@ -28,6 +79,22 @@ type Server struct {
 	redis  *redis.Client
 	pgpool *pgxpool.Pool
 	stream string
+
+	producer    queue.Producer
+	eventFormat string
+
+	readinessTimeout time.Duration
+	rateLimits       map[string]RateLimit
+	backpressure     *backpressure
+	maxBatchSize     int
+	outbox           OutboxConfig
+	outboxDB         txBeginner
+	outboxWake       chan struct{}
+	results          *resultsProxy
+	verifier         *authn.Verifier
+
+	bgCancel context.CancelFunc
+	bgWG     sync.WaitGroup
 }
 
 // New wires dependencies and returns a configured Server.
@@ -41,6 +108,37 @@ func New(ctx context.Context, cfg Config) (*Server, error) {
 	if cfg.PGConnString == "" {
 		return nil, errors.New("postgres connection string is required")
 	}
+	if cfg.QueueBackend == "" {
+		cfg.QueueBackend = queue.BackendRedis
+	}
+	switch cfg.EventFormat {
+	case "":
+		cfg.EventFormat = EventFormatLegacy
+	case EventFormatEnvelope, EventFormatLegacy:
+	default:
+		return nil, fmt.Errorf("unknown event format %q", cfg.EventFormat)
+	}
+	if cfg.ReadinessTimeout <= 0 {
+		cfg.ReadinessTimeout = health.DefaultTimeout
+	}
+	if cfg.MaxBatchSize <= 0 {
+		cfg.MaxBatchSize = defaultMaxBatchSize
+	}
+	if cfg.Outbox.PollInterval <= 0 {
+		cfg.Outbox.PollInterval = defaultOutboxPollInterval
+	}
+	if cfg.Outbox.BatchSize <= 0 {
+		cfg.Outbox.BatchSize = defaultOutboxBatchSize
+	}
+	if cfg.Backpressure.Group == "" {
+		cfg.Backpressure.Group = "tally"
+	}
+	if cfg.Backpressure.Interval <= 0 {
+		cfg.Backpressure.Interval = defaultBackpressureInterval
+	}
+	if cfg.Backpressure.RetryAfter <= 0 {
+		cfg.Backpressure.RetryAfter = defaultBackpressureRetryAfter
+	}
 
 	rdb := redis.NewClient(&redis.Options{
 		Addr:     cfg.RedisAddr,
@@ -60,17 +158,105 @@ func New(ctx context.Context, cfg Config) (*Server, error) {
 		return nil, fmt.Errorf("pg ping: %w", err)
 	}
 
+	producer, err := newProducer(ctx, cfg, rdb)
+	if err != nil {
+		pool.Close()
+		return nil, err
+	}
+	if cfg.QueueBackend != queue.BackendRedis {
+		// XLEN/XINFO based checks only make sense for Redis Streams.
+		cfg.Backpressure.MaxLen, cfg.Backpressure.MaxLag, cfg.Backpressure.TrimInterval = 0, 0, 0
+	}
+
+	results, err := newResultsProxy(cfg.Results)
+	if err != nil {
+		pool.Close()
+		producer.Close()
+		return nil, err
+	}
+
+	verifier, err := authn.New(ctx, cfg.Auth)
+	if err != nil {
+		pool.Close()
+		producer.Close()
+		results.Close()
+		return nil, fmt.Errorf("auth: %w", err)
+	}
+
+	ipExtractor, err := newIPExtractor(cfg.TrustedProxies)
+	if err != nil {
+		pool.Close()
+		producer.Close()
+		results.Close()
+		return nil, err
+	}
+
 	e := echo.New()
+	e.IPExtractor = ipExtractor
 	s := &Server{
 		e:      e,
 		redis:  rdb,
 		pgpool: pool,
 		stream: cfg.RedisStream,
+
+		producer:    producer,
+		eventFormat: cfg.EventFormat,
+
+		readinessTimeout: cfg.ReadinessTimeout,
+		rateLimits:       cfg.RateLimits,
+		backpressure:     &backpressure{cfg: cfg.Backpressure},
+		maxBatchSize:     cfg.MaxBatchSize,
+		outbox:           cfg.Outbox,
+		outboxDB:         pool,
+		outboxWake:       make(chan struct{}, 1),
+		results:          results,
+		verifier:         verifier,
 	}
 	s.routes()
+	s.startBackground()
 	return s, nil
 }
 
+func newProducer(ctx context.Context, cfg Config, rdb *redis.Client) (queue.Producer, error) {
+	switch cfg.QueueBackend {
+	case queue.BackendRedis:
+		return queue.NewRedisProducer(rdb, cfg.RedisStream), nil
+	case queue.BackendNATS:
+		return queue.NewNATSProducer(ctx, cfg.NATS)
+	case queue.BackendMemory:
+		return queue.NewMemory(), nil
+	default:
+		return nil, fmt.Errorf("unknown queue backend %q", cfg.QueueBackend)
+	}
+}
+
+func (s *Server) startBackground() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.bgCancel = cancel
+
+	if s.backpressure.enabled() {
+		s.bgWG.Add(1)
+		go func() {
+			defer s.bgWG.Done()
+			s.runBackpressure(ctx)
+		}()
+	}
+	if s.outbox.Enabled {
+		s.bgWG.Add(1)
+		go func() {
+			defer s.bgWG.Done()
+			s.runOutboxRelay(ctx)
+		}()
+	}
+	if s.backpressure.cfg.TrimInterval > 0 {
+		s.bgWG.Add(1)
+		go func() {
+			defer s.bgWG.Done()
+			s.runTrim(ctx)
+		}()
+	}
+}
+
 // Start begins serving HTTP traffic on the provided address.
 func (s *Server) Start(addr string) error {
 	return s.e.Start(addr)
@@ -82,6 +268,16 @@ func (s *Server) Shutdown(ctx context.Context) error {
 	if err := s.e.Shutdown(ctx); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		errs = append(errs, err)
 	}
+	if s.bgCancel != nil {
+		s.bgCancel()
+	}
+	s.bgWG.Wait()
+	if err := s.producer.Close(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := s.results.Close(); err != nil {
+		errs = append(errs, err)
+	}
 	s.pgpool.Close()
 	if err := s.redis.Close(); err != nil {
 		errs = append(errs, err)
@@ -90,16 +286,19 @@ func (s *Server) Shutdown(ctx context.Context) error {
 }
 
 func (s *Server) routes() {
-	s.e.GET("/healthz", func(c echo.Context) error {
-		return c.NoContent(http.StatusOK)
-	})
-	s.e.POST("/votes", s.handleVote)
-	s.e.GET("/results", s.handleResults)
+	s.e.GET("/healthz", s.handleLivez)
+	s.e.GET("/livez", s.handleLivez)
+	s.e.GET("/readyz", s.handleReadyz)
+	s.e.POST("/votes", s.handleVote, s.authenticate, s.rateLimit("/votes"))
+	// The colon is escaped so echo does not treat ":batch" as a path parameter.
+	s.e.POST(`/votes\:batch`, s.handleVoteBatch, s.authenticate, s.rateLimit("/votes:batch"))
+	s.e.GET("/results", s.handleResults, s.authenticate, s.rateLimit("/results"))
 }
 
 type voteRequest struct {
-	UserID      int64 `json:"user_id"`
-	CandidateID int64 `json:"candidate_id"`
+	UserID      int64  `json:"user_id"`
+	CandidateID int64  `json:"candidate_id"`
+	ElectionID  string `json:"election_id,omitempty"`
 }
 
 type voteResponse struct {
@@ -114,63 +313,27 @@ func (s *Server) handleVote(c echo.Context) error {
 	if err := validateVoteRequest(req); err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]any{"error": err.Error()})
 	}
-
-	entry := &redis.XAddArgs{
-		Stream: s.stream,
-		Values: map[string]any{
-			"user_id":      strconv.FormatInt(req.UserID, 10),
-			"candidate_id": strconv.FormatInt(req.CandidateID, 10),
-			"ts":           time.Now().UTC().Format(time.RFC3339Nano),
-		},
+	tenant := claimsFrom(c).Tenant
+	if s.backpressure.overloaded.Load() {
+		return s.rejectOverloaded(c)
 	}
 
-	if _, err := s.redis.XAdd(c.Request().Context(), entry).Result(); err != nil {
+	values, err := s.voteValues(tenant, req, time.Now())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]any{"error": "failed to encode vote"})
+	}
+	if err := s.enqueue(c.Request().Context(), []map[string]any{values})[0]; err != nil {
 		return c.JSON(http.StatusBadGateway, map[string]any{"error": "failed to enqueue vote"})
 	}
 
 	return c.JSON(http.StatusAccepted, voteResponse{Status: "accepted"})
 }
 
-type totalsResponse struct {
-	Totals    []candidateTotal `json:"totals"`
-	UpdatedAt time.Time        `json:"updated_at"`
-}
-
-type candidateTotal struct {
-	CandidateID int64 `json:"candidate_id"`
-	Count       int64 `json:"count"`
-}
-
-func (s *Server) handleResults(c echo.Context) error {
-	ctx := c.Request().Context()
-
-	rows, err := s.pgpool.Query(ctx, `SELECT candidate_id, count FROM totals ORDER BY candidate_id`)
-	if err != nil {
-		return c.JSON(http.StatusBadGateway, map[string]any{"error": "failed to query totals"})
-	}
-	defer rows.Close()
-
-	var totals []candidateTotal
-	for rows.Next() {
-		var ct candidateTotal
-		if err := rows.Scan(&ct.CandidateID, &ct.Count); err != nil {
-			return c.JSON(http.StatusInternalServerError, map[string]any{"error": "failed to parse totals"})
-		}
-		totals = append(totals, ct)
-	}
-	if err := rows.Err(); err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]any{"error": "failed to read totals"})
-	}
-
-	var updatedAt time.Time
-	if err := s.pgpool.QueryRow(ctx, `SELECT COALESCE(MAX(voted_at), NOW()) FROM votes`).Scan(&updatedAt); err != nil {
-		return c.JSON(http.StatusBadGateway, map[string]any{"error": "failed to determine updated time"})
-	}
-
-	return c.JSON(http.StatusOK, totalsResponse{
-		Totals:    totals,
-		UpdatedAt: updatedAt.UTC(),
-	})
+// rejectOverloaded tells the client to back off while workers catch up.
+func (s *Server) rejectOverloaded(c echo.Context) error {
+	retryAfter := int(math.Ceil(s.backpressure.cfg.RetryAfter.Seconds()))
+	c.Response().Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	return c.JSON(http.StatusServiceUnavailable, map[string]any{"error": "vote queue is overloaded"})
 }
 
 func validateVoteRequest(req voteRequest) error {
@@ -180,5 +343,31 @@ func validateVoteRequest(req voteRequest) error {
 	if req.CandidateID <= 0 {
 		return errors.New("candidate_id must be positive")
 	}
+	if len(req.ElectionID) > maxElectionIDLen {
+		return fmt.Errorf("election_id must be at most %d characters", maxElectionIDLen)
+	}
 	return nil
 }
+
+// newIPExtractor returns the client IP extractor used for per-IP rate
+// limits. Echo trusts X-Forwarded-For from every private address by default,
+// which would let clients on the cluster network pick their own key, so only
+// the configured gateway ranges are trusted.
+func newIPExtractor(trustedProxies []string) (echo.IPExtractor, error) {
+	if len(trustedProxies) == 0 {
+		return echo.ExtractIPDirect(), nil
+	}
+	opts := []echo.TrustOption{
+		echo.TrustLoopback(false),
+		echo.TrustLinkLocal(false),
+		echo.TrustPrivateNet(false),
+	}
+	for _, cidr := range trustedProxies {
+		_, ipNet, err := net.ParseCIDR(strings.TrimSpace(cidr))
+		if err != nil {
+			return nil, fmt.Errorf("trusted proxy %q: %w", cidr, err)
+		}
+		opts = append(opts, echo.TrustIPRange(ipNet))
+	}
+	return echo.ExtractIPFromXFFHeader(opts...), nil
+}
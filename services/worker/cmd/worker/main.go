@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"net/url"
 	"os"
 	"os/signal"
@@ -12,25 +13,24 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/yoyo1025/k8s-vote-platform/pkg/queue"
 	"github.com/yoyo1025/k8s-vote-platform/services/worker/internal/worker"
 )
 
 func main() {
-	cfg := worker.Config{
-		RedisAddr:      getenv("REDIS_ADDR", "localhost:6379"),
-		RedisUsername:  os.Getenv("REDIS_USERNAME"),
-		RedisPassword:  os.Getenv("REDIS_PASSWORD"),
-		RedisStream:    getenv("REDIS_STREAM", "stream:votes"),
-		RedisGroup:     getenv("REDIS_GROUP", "tally"),
-		RedisConsumer:  getenv("REDIS_CONSUMER", worker.GenerateConsumerID()),
-		ResultsChannel: getenv("RESULTS_CHANNEL", "results:totals"),
-		BatchSize:      atoiDefault(os.Getenv("BATCH_SIZE"), 100),
-		BlockInterval:  durationDefault(os.Getenv("BLOCK_INTERVAL"), 5*time.Second),
-		IdleTimeout:    durationDefault(os.Getenv("IDLE_TIMEOUT"), 30*time.Second),
-		PGConnString:   buildPostgresDSN(),
-		TotalsBucketID: atoiDefault(os.Getenv("TOTALS_BUCKET_ID"), 0),
+	cfg := loadConfig()
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "replay":
+			os.Exit(runReplay(cfg, os.Args[2:]))
+		case "snapshot":
+			os.Exit(runSnapshot(cfg, os.Args[2:]))
+		}
 	}
 
+	healthAddr := getenv("HEALTH_ADDR", ":9090")
+	shutdownTimeout := durationDefault(os.Getenv("SHUTDOWN_TIMEOUT"), 30*time.Second)
+
 	initialCtx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
@@ -47,8 +47,19 @@ func main() {
 		errCh <- processor.Run(ctx)
 	}()
 
-	log.Printf("worker started: stream=%s group=%s consumer=%s batch=%d bucket=%d",
-		cfg.RedisStream, cfg.RedisGroup, cfg.RedisConsumer, cfg.BatchSize, cfg.TotalsBucketID)
+	healthSrv := &http.Server{Addr: healthAddr, Handler: processor.HealthHandler()}
+	go func() {
+		if err := healthSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("health server error: %v", err)
+		}
+	}()
+
+	group := cfg.RedisGroup
+	if cfg.Shadow.Enabled {
+		group = cfg.Shadow.Group
+	}
+	log.Printf("worker started: stream=%s group=%s consumer=%s batch=%d bucket=%d concurrency=%d partition=%s",
+		cfg.RedisStream, group, cfg.RedisConsumer, cfg.BatchSize, cfg.TotalsBucketID, cfg.Concurrency, cfg.PartitionBy)
 
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGTERM, syscall.SIGINT)
@@ -57,13 +68,83 @@ func main() {
 	cancelRun()
 	cancel()
 
-	if err := <-errCh; err != nil && !errors.Is(err, context.Canceled) {
-		log.Printf("worker stopped with error: %v", err)
+	ctxShutdown, cancelShutdown := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancelShutdown()
+
+	// Run returns once in-flight batches are committed and acknowledged.
+	select {
+	case err := <-errCh:
+		if err != nil && !errors.Is(err, context.Canceled) {
+			log.Printf("worker stopped with error: %v", err)
+		}
+		processor.Release(ctxShutdown)
+	case <-ctxShutdown.Done():
+		log.Printf("worker did not drain within %s", shutdownTimeout)
+	}
+
+	if err := healthSrv.Shutdown(ctxShutdown); err != nil {
+		log.Printf("health server shutdown error: %v", err)
 	}
 	processor.Close()
 	log.Println("worker stopped")
 }
 
+func loadConfig() worker.Config {
+	cfg := worker.Config{
+		RedisAddr:      getenv("REDIS_ADDR", "localhost:6379"),
+		RedisUsername:  os.Getenv("REDIS_USERNAME"),
+		RedisPassword:  os.Getenv("REDIS_PASSWORD"),
+		RedisStream:    getenv("REDIS_STREAM", "stream:votes"),
+		RedisGroup:     getenv("REDIS_GROUP", "tally"),
+		RedisConsumer:  getenv("REDIS_CONSUMER", worker.GenerateConsumerID()),
+		ResultsChannel: getenv("RESULTS_CHANNEL", "results:totals"),
+		QueueBackend:   getenv("QUEUE_BACKEND", "redis"),
+		NATS: queue.NATSConfig{
+			URL:     getenv("NATS_URL", "nats://localhost:4222"),
+			Stream:  getenv("NATS_STREAM", "VOTES"),
+			Subject: getenv("NATS_SUBJECT", "votes"),
+		},
+		BatchSize:      atoiDefault(os.Getenv("BATCH_SIZE"), 100),
+		BlockInterval:  durationDefault(os.Getenv("BLOCK_INTERVAL"), 5*time.Second),
+		IdleTimeout:    durationDefault(os.Getenv("IDLE_TIMEOUT"), 30*time.Second),
+		Concurrency:    atoiDefault(os.Getenv("CONCURRENCY"), 1),
+		PartitionBy:    getenv("PARTITION_BY", worker.PartitionByCandidate),
+		HandoffPending: boolDefault(os.Getenv("HANDOFF_PENDING"), true),
+		Housekeeping: worker.HousekeepingConfig{
+			Interval:           durationOrOff(os.Getenv("HOUSEKEEPING_INTERVAL"), time.Minute),
+			ConsumerIdle:       durationOrOff(os.Getenv("CONSUMER_IDLE_TIMEOUT"), time.Hour),
+			TrimRetention:      durationOrOff(os.Getenv("TRIM_RETENTION"), 24*time.Hour),
+			ProcessedRetention: durationOrOff(os.Getenv("PROCESSED_RETENTION"), 24*time.Hour),
+		},
+		SnapshotInterval: durationOrOff(os.Getenv("SNAPSHOT_INTERVAL"), 15*time.Minute),
+		Shadow: worker.ShadowConfig{
+			Enabled:        boolDefault(os.Getenv("SHADOW_MODE"), false),
+			Group:          os.Getenv("SHADOW_GROUP"),
+			Schema:         getenv("SHADOW_SCHEMA", "shadow"),
+			ReportInterval: durationDefault(os.Getenv("SHADOW_REPORT_INTERVAL"), time.Minute),
+			Grace:          durationDefault(os.Getenv("SHADOW_GRACE"), 5*time.Minute),
+		},
+		PGConnString:   buildPostgresDSN(),
+		TotalsBucketID: atoiDefault(os.Getenv("TOTALS_BUCKET_ID"), 0),
+
+		ReadinessTimeout: durationDefault(os.Getenv("READINESS_TIMEOUT"), 2*time.Second),
+	}
+
+	// A shadow worker must not share the primary consumer group; REDIS_GROUP
+	// names the primary group and SHADOW_GROUP the one the shadow reads.
+	if cfg.Shadow.Group == "" {
+		cfg.Shadow.Group = cfg.RedisGroup + "-shadow"
+	}
+	// The in-memory queue is only fed by a vote-api in the same process.
+	if cfg.QueueBackend == queue.BackendMemory {
+		log.Fatal("QUEUE_BACKEND=memory is not supported by the standalone worker; use redis or nats")
+	}
+	if cfg.Shadow.Enabled && cfg.Shadow.Group == cfg.RedisGroup {
+		log.Fatalf("SHADOW_GROUP must differ from REDIS_GROUP (%s)", cfg.RedisGroup)
+	}
+	return cfg
+}
+
 func getenv(key, def string) string {
 	if v := os.Getenv(key); v != "" {
 		return v
@@ -81,6 +162,17 @@ func atoiDefault(v string, def int) int {
 	return def
 }
 
+func boolDefault(v string, def bool) bool {
+	if v == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return def
+	}
+	return b
+}
+
 func durationDefault(v string, def time.Duration) time.Duration {
 	if v == "" {
 		return def
@@ -95,6 +187,15 @@ func durationDefault(v string, def time.Duration) time.Duration {
 	return d
 }
 
+// durationOrOff is durationDefault that also accepts "off", which disables
+// the setting by returning a negative duration.
+func durationOrOff(v string, def time.Duration) time.Duration {
+	if v == "off" {
+		return -1
+	}
+	return durationDefault(v, def)
+}
+
 func buildPostgresDSN() string {
 	if dsn := os.Getenv("PG_DSN"); dsn != "" {
 		return dsn
@@ -102,7 +203,7 @@ func buildPostgresDSN() string {
 
 	host := getenv("PG_HOST", "localhost")
 	port := getenv("PG_PORT", "5432")
-	user := getenv("PG_USER", "vote")
+	user := getenv("PG_USER", "vote_app")
 	password := os.Getenv("PG_PASSWORD")
 	database := getenv("PG_DATABASE", "vote")
 	sslmode := getenv("PG_SSLMODE", "disable")
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/yoyo1025/k8s-vote-platform/services/worker/internal/worker"
+)
+
+// runSnapshot implements "worker snapshot", recording the current totals,
+// e.g. when polls close.
+func runSnapshot(cfg worker.Config, args []string) int {
+	fs := flag.NewFlagSet("snapshot", flag.ContinueOnError)
+	reason := fs.String("reason", worker.SnapshotClose, "snapshot reason: close or manual")
+	label := fs.String("label", "", "free-form note stored with the snapshot")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	id, err := worker.TakeSnapshot(ctx, cfg, *reason, *label)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "snapshot: %v\n", err)
+		return 1
+	}
+	fmt.Printf("snapshot %d recorded (%s)\n", id, *reason)
+	return 0
+}
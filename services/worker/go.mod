@@ -2,20 +2,39 @@ module github.com/yoyo1025/k8s-vote-platform/services/worker
 
 go 1.25.1
 
+replace github.com/yoyo1025/k8s-vote-platform/gen/go => ../../gen/go
+
+replace github.com/yoyo1025/k8s-vote-platform/pkg/health => ../../pkg/health
+
+replace github.com/yoyo1025/k8s-vote-platform/pkg/queue => ../../pkg/queue
+
 require (
+	github.com/alicebob/miniredis/v2 v2.30.0
 	github.com/jackc/pgx/v5 v5.6.0
 	github.com/redis/go-redis/v9 v9.6.1
+	github.com/yoyo1025/k8s-vote-platform/gen/go v0.0.0-00010101000000-000000000000
+	github.com/yoyo1025/k8s-vote-platform/pkg/health v0.0.0-00010101000000-000000000000
+	github.com/yoyo1025/k8s-vote-platform/pkg/queue v0.0.0-00010101000000-000000000000
+	google.golang.org/protobuf v1.36.9
 )
 
 require (
-	github.com/cespare/xxhash/v2 v2.2.0 // indirect
+	github.com/alicebob/gopher-json v0.0.0-20200520072559-a9ecdc9d1d3a // indirect
+	github.com/cespare/xxhash/v2 v2.3.0 // indirect
 	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
 	github.com/jackc/pgpassfile v1.0.0 // indirect
 	github.com/jackc/pgservicefile v0.0.0-20221227161230-091c0ba34f0a // indirect
 	github.com/jackc/puddle/v2 v2.2.2 // indirect
+	github.com/klauspost/compress v1.18.0 // indirect
+	github.com/nats-io/nats.go v1.47.0 // indirect
+	github.com/nats-io/nkeys v0.4.11 // indirect
+	github.com/nats-io/nuid v1.0.1 // indirect
+	github.com/yuin/gopher-lua v0.0.0-20220504180219-658193537a64 // indirect
 	golang.org/x/crypto v0.39.0 // indirect
 	golang.org/x/net v0.41.0 // indirect
 	golang.org/x/sync v0.15.0 // indirect
 	golang.org/x/sys v0.33.0 // indirect
 	golang.org/x/text v0.26.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20250707201910-8d1bb00bc6a7 // indirect
+	google.golang.org/grpc v1.75.1 // indirect
 )
@@ -0,0 +1,72 @@
+package worker
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	votev1 "github.com/yoyo1025/k8s-vote-platform/gen/go/vote/v1"
+	"github.com/yoyo1025/k8s-vote-platform/pkg/queue"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const (
+	eventTypeVoteCast    = "vote.cast"
+	voteEventSchema      = 1
+	defaultElectionID    = "default"
+	eventField           = "event"
+	contentTypeField     = "content_type"
+	contentTypeVoteEvent = "application/vnd.vote.v1.VoteEvent+json"
+)
+
+func parseEnvelope(msg queue.Message) (voteEntry, error) {
+	entry := voteEntry{id: msg.ID}
+
+	if ct, ok := msg.Values[contentTypeField]; ok && ct != contentTypeVoteEvent {
+		return entry, fmt.Errorf("unsupported content type %v", ct)
+	}
+	raw, ok := msg.Values[eventField].(string)
+	if !ok {
+		return entry, fmt.Errorf("unsupported event type %T", msg.Values[eventField])
+	}
+
+	var ev votev1.VoteEvent
+	if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal([]byte(raw), &ev); err != nil {
+		return entry, fmt.Errorf("decode event: %w", err)
+	}
+	if ev.GetEventType() != eventTypeVoteCast {
+		return entry, fmt.Errorf("unsupported event type %q", ev.GetEventType())
+	}
+	// Fields added within a schema version are discarded above; another
+	// version may change the meaning of known fields, so it is not guessed at.
+	if ev.GetSchemaVersion() != voteEventSchema {
+		return entry, fmt.Errorf("unsupported schema version %d", ev.GetSchemaVersion())
+	}
+	cast := ev.GetVoteCast()
+	if cast == nil {
+		return entry, errors.New("missing vote_cast payload")
+	}
+	if ev.GetEventId() == "" {
+		return entry, errors.New("missing event_id")
+	}
+	if cast.GetUserId() <= 0 || cast.GetCandidateId() <= 0 {
+		return entry, errors.New("user_id and candidate_id must be positive")
+	}
+
+	entry.eventID = ev.GetEventId()
+	entry.tenantID = ev.GetTenantId()
+	if entry.tenantID == "" {
+		entry.tenantID = defaultTenantID
+	}
+	entry.electionID = ev.GetElectionId()
+	if entry.electionID == "" {
+		entry.electionID = defaultElectionID
+	}
+	entry.userID = cast.GetUserId()
+	entry.candidateID = cast.GetCandidateId()
+	entry.votedAt = time.Now().UTC()
+	if ts := ev.GetOccurredAt(); ts != nil {
+		entry.votedAt = ts.AsTime()
+	}
+	return entry, nil
+}
@@ -0,0 +1,104 @@
+package worker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/yoyo1025/k8s-vote-platform/pkg/queue"
+)
+
+func TestParseMessage(t *testing.T) {
+	t.Run("legacy fields", func(t *testing.T) {
+		entry, err := parseMessage(queue.Message{ID: "1-0", Values: map[string]any{
+			"user_id":      "7",
+			"candidate_id": "3",
+			"ts":           "2025-02-15T09:00:00Z",
+		}})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if entry.userID != 7 || entry.candidateID != 3 || entry.electionID != defaultElectionID {
+			t.Fatalf("unexpected entry: %+v", entry)
+		}
+		if !entry.votedAt.Equal(time.Date(2025, 2, 15, 9, 0, 0, 0, time.UTC)) {
+			t.Fatalf("unexpected votedAt: %v", entry.votedAt)
+		}
+	})
+
+	t.Run("legacy with tenant and election", func(t *testing.T) {
+		// The fields vote-api writes in legacy mode for a non-default
+		// tenant and election (see its TestVoteValuesCarryElection).
+		entry, err := parseMessage(queue.Message{ID: "1-1", Values: map[string]any{
+			"user_id":      "7",
+			"candidate_id": "3",
+			"ts":           "2025-02-15T09:00:00Z",
+			"tenant_id":    "acme",
+			"election_id":  "e1",
+		}})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if entry.tenantID != "acme" || entry.electionID != "e1" {
+			t.Fatalf("unexpected entry: %+v", entry)
+		}
+	})
+
+	t.Run("legacy with event id", func(t *testing.T) {
+		// Outbox rows re-published after a crash keep their event_id, so
+		// the copy is deduplicated although its stream ID differs.
+		for _, id := range []string{"1-2", "9-0"} {
+			entry, err := parseMessage(queue.Message{ID: id, Values: map[string]any{
+				"user_id":      "7",
+				"candidate_id": "3",
+				"event_id":     "outbox:42",
+			}})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if entry.dedupKey() != "outbox:42" {
+				t.Fatalf("dedupKey = %q, want outbox:42", entry.dedupKey())
+			}
+		}
+	})
+
+	t.Run("envelope", func(t *testing.T) {
+		entry, err := parseMessage(queue.Message{ID: "2-0", Values: map[string]any{
+			contentTypeField: contentTypeVoteEvent,
+			eventField: `{"eventId":"abc","eventType":"vote.cast","schemaVersion":1,"electionId":"e1",` +
+				`"producer":"vote-api/test","occurredAt":"2025-02-15T09:00:00Z","voteCast":{"userId":"7","candidateId":"3"}}`,
+		}})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if entry.eventID != "abc" || entry.electionID != "e1" || entry.userID != 7 || entry.candidateID != 3 {
+			t.Fatalf("unexpected entry: %+v", entry)
+		}
+	})
+
+	t.Run("envelope with unknown type", func(t *testing.T) {
+		_, err := parseMessage(queue.Message{ID: "3-0", Values: map[string]any{
+			eventField: `{"eventId":"abc","eventType":"vote.retracted","schemaVersion":1}`,
+		}})
+		if err == nil {
+			t.Fatal("expected error for unknown event type")
+		}
+	})
+
+	t.Run("envelope with unknown schema version", func(t *testing.T) {
+		for _, version := range []string{"0", "2"} {
+			_, err := parseMessage(queue.Message{ID: "3-0", Values: map[string]any{
+				eventField: `{"eventId":"abc","eventType":"vote.cast","schemaVersion":` + version +
+					`,"voteCast":{"userId":"7","candidateId":"3"}}`,
+			}})
+			if err == nil {
+				t.Fatalf("expected error for schema version %s", version)
+			}
+		}
+	})
+
+	t.Run("legacy missing candidate", func(t *testing.T) {
+		if _, err := parseMessage(queue.Message{ID: "4-0", Values: map[string]any{"user_id": "1"}}); err == nil {
+			t.Fatal("expected error for missing candidate_id")
+		}
+	})
+}
@@ -0,0 +1,61 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/yoyo1025/k8s-vote-platform/pkg/health"
+	"github.com/yoyo1025/k8s-vote-platform/pkg/queue"
+)
+
+func (p *Processor) healthChecks() []health.Check {
+	checks := []health.Check{
+		{Name: "redis", Fn: func(ctx context.Context) error {
+			return p.redis.Ping(ctx).Err()
+		}},
+		{Name: "postgres", Fn: func(ctx context.Context) error {
+			return p.pg.Ping(ctx)
+		}},
+	}
+	if p.cfg.QueueBackend == queue.BackendRedis && p.cfg.Queue == nil {
+		checks = append(checks, health.Check{Name: "consumer-group", Fn: p.checkConsumerGroup})
+	}
+	return checks
+}
+
+func (p *Processor) checkConsumerGroup(ctx context.Context) error {
+	groups, err := p.redis.XInfoGroups(ctx, p.cfg.RedisStream).Result()
+	if err != nil {
+		return err
+	}
+	for _, g := range groups {
+		if g.Name == p.cfg.RedisGroup {
+			return nil
+		}
+	}
+	return fmt.Errorf("group %s not found on %s", p.cfg.RedisGroup, p.cfg.RedisStream)
+}
+
+// HealthHandler serves /livez and /readyz for the processor.
+func (p *Processor) HealthHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /livez", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, r *http.Request) {
+		resp, ready := health.Run(r.Context(), p.cfg.ReadinessTimeout, p.healthChecks())
+		status := http.StatusOK
+		if !ready {
+			status = http.StatusServiceUnavailable
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(resp)
+	})
+	if p.shadow != nil {
+		mux.HandleFunc("GET /shadow", p.handleShadowReport)
+	}
+	return mux
+}
@@ -0,0 +1,135 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/yoyo1025/k8s-vote-platform/pkg/queue"
+)
+
+// HousekeepingConfig controls periodic cleanup of the Redis consumer group
+// and of processed_events. Every worker may run it; the operations are
+// idempotent.
+type HousekeepingConfig struct {
+	// Interval between runs; zero disables housekeeping.
+	Interval time.Duration
+	// ConsumerIdle is how long a consumer with no pending entries must have
+	// been idle before it is removed from the group. Zero keeps consumers.
+	ConsumerIdle time.Duration
+	// TrimRetention keeps acknowledged entries newer than this in the stream
+	// so they can still be replayed. Negative disables trimming.
+	TrimRetention time.Duration
+	// ProcessedRetention keeps processed_events rows at least this long.
+	// Rows for entries still in the stream are kept regardless, since those
+	// entries can be redelivered or replayed. Negative disables the cleanup.
+	ProcessedRetention time.Duration
+}
+
+// purgeBatchSize bounds each DELETE so the cleanup never holds locks on a
+// large part of processed_events at once.
+const purgeBatchSize = 5000
+
+func (p *Processor) housekeepingEnabled() bool {
+	return p.cfg.Housekeeping.Interval > 0 &&
+		p.cfg.QueueBackend == queue.BackendRedis && p.cfg.Queue == nil
+}
+
+func (p *Processor) runHousekeeping(ctx context.Context) {
+	ticker := time.NewTicker(p.cfg.Housekeeping.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		p.housekeep(ctx)
+	}
+}
+
+func (p *Processor) housekeep(ctx context.Context) {
+	hk := p.cfg.Housekeeping
+
+	if hk.ConsumerIdle > 0 {
+		removed, err := queue.RemoveIdleRedisConsumers(ctx, p.redis,
+			p.cfg.RedisStream, p.cfg.RedisGroup, hk.ConsumerIdle, p.cfg.RedisConsumer)
+		if len(removed) > 0 {
+			p.log.Printf("housekeeping: removed idle consumers %v", removed)
+		}
+		if err != nil && ctx.Err() == nil {
+			p.log.Printf("housekeeping: consumer cleanup error: %v", err)
+		}
+	}
+
+	// Trimming follows the slowest group on the stream, so shadow workers
+	// may trim as well.
+	if hk.TrimRetention >= 0 {
+		trimmed, err := queue.TrimRedisStream(ctx, p.redis, p.cfg.RedisStream, hk.TrimRetention)
+		if err != nil {
+			if ctx.Err() == nil {
+				p.log.Printf("housekeeping: trim error: %v", err)
+			}
+			return
+		}
+		if trimmed > 0 {
+			p.log.Printf("housekeeping: trimmed %d acknowledged entries from %s", trimmed, p.cfg.RedisStream)
+		}
+	}
+
+	if hk.ProcessedRetention >= 0 {
+		purged, err := p.purgeProcessed(ctx, hk.ProcessedRetention)
+		if err != nil && ctx.Err() == nil {
+			p.log.Printf("housekeeping: processed_events cleanup error: %v", err)
+		}
+		if purged > 0 {
+			p.log.Printf("housekeeping: purged %d processed_events rows", purged)
+		}
+	}
+}
+
+// purgeProcessed deletes processed_events rows older than retention and
+// older than the oldest entry left in the stream, the furthest back a
+// redelivery or replay can reach. A shadow worker's search_path points at
+// the shadow schema, so it cleans up its own copy.
+func (p *Processor) purgeProcessed(ctx context.Context, retention time.Duration) (int64, error) {
+	cutoff := time.Now().Add(-retention)
+	oldest, err := p.redis.XRangeN(ctx, p.cfg.RedisStream, "-", "+", 1).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return 0, fmt.Errorf("read oldest entry: %w", err)
+	}
+	if len(oldest) > 0 {
+		msStr, _, _ := strings.Cut(oldest[0].ID, "-")
+		ms, err := strconv.ParseInt(msStr, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("parse entry id %q: %w", oldest[0].ID, err)
+		}
+		if entry := time.UnixMilli(ms); entry.Before(cutoff) {
+			cutoff = entry
+		}
+	}
+
+	var purged int64
+	for {
+		tag, err := p.pg.Exec(ctx, `
+			DELETE FROM processed_events
+			WHERE event_id IN (
+				SELECT event_id FROM processed_events
+				WHERE processed_at < $1
+				LIMIT $2
+			)
+		`, cutoff, purgeBatchSize)
+		if err != nil {
+			return purged, fmt.Errorf("delete processed_events: %w", err)
+		}
+		purged += tag.RowsAffected()
+		if tag.RowsAffected() < purgeBatchSize {
+			return purged, nil
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package worker
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// electionMeta accumulates a batch's changes to one totals_meta row.
+type electionMeta struct {
+	votes        int64
+	ballots      int64
+	lastVotedAt  time.Time
+	lastStreamID string
+}
+
+func (m *electionMeta) add(e voteEntry) {
+	m.votes++
+	if e.votedAt.After(m.lastVotedAt) {
+		m.lastVotedAt = e.votedAt
+	}
+	// Entries are applied in stream order.
+	m.lastStreamID = e.id
+}
+
+// ballotKey identifies one voter in an election of the current tenant.
+type ballotKey struct {
+	electionID string
+	userID     int64
+}
+
+func (k ballotKey) compare(o ballotKey) int {
+	return cmp.Or(cmp.Compare(k.electionID, o.electionID), cmp.Compare(k.userID, o.userID))
+}
+
+// recordBallots records a ballot for each voter with a vote applied in this
+// batch and counts the new ones into meta. The unique key on ballots decides
+// which transaction casts a voter's first ballot, so concurrent partitions
+// cannot both count it.
+func recordBallots(ctx context.Context, tx pgx.Tx, tenantID string, voters map[ballotKey]struct{}, meta map[string]*electionMeta) error {
+	for _, key := range slices.SortedFunc(maps.Keys(voters), ballotKey.compare) {
+		tag, err := tx.Exec(ctx, `
+			INSERT INTO ballots (tenant_id, election_id, user_id)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (tenant_id, election_id, user_id) DO NOTHING
+		`, tenantID, key.electionID, key.userID)
+		if err != nil {
+			return fmt.Errorf("record ballot election %s user %d: %w", key.electionID, key.userID, err)
+		}
+		if tag.RowsAffected() > 0 {
+			meta[key.electionID].ballots++
+		}
+	}
+	return nil
+}
+
+// upsertMeta folds m into the election's totals_meta row. last_stream_id only
+// moves forward, since concurrent partitions may commit out of stream order.
+func upsertMeta(ctx context.Context, tx pgx.Tx, tenantID, electionID string, m *electionMeta) error {
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO totals_meta AS m
+			(tenant_id, election_id, last_updated_at, total_votes, total_ballots, last_stream_id, version)
+		VALUES ($1, $2, $3, $4, $5, $6, 1)
+		ON CONFLICT (tenant_id, election_id) DO UPDATE SET
+			last_updated_at = GREATEST(m.last_updated_at, EXCLUDED.last_updated_at),
+			total_votes = m.total_votes + EXCLUDED.total_votes,
+			total_ballots = m.total_ballots + EXCLUDED.total_ballots,
+			last_stream_id = CASE
+				WHEN m.last_stream_id = ''
+				  OR (split_part(EXCLUDED.last_stream_id, '-', 1)::numeric,
+				      split_part(EXCLUDED.last_stream_id, '-', 2)::numeric)
+				   > (split_part(m.last_stream_id, '-', 1)::numeric,
+				      split_part(m.last_stream_id, '-', 2)::numeric)
+				THEN EXCLUDED.last_stream_id
+				ELSE m.last_stream_id
+			END,
+			version = m.version + 1
+	`, tenantID, electionID, m.lastVotedAt, m.votes, m.ballots, m.lastStreamID); err != nil {
+		return fmt.Errorf("update totals meta election %s: %w", electionID, err)
+	}
+	return nil
+}
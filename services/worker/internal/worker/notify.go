@@ -0,0 +1,55 @@
+package worker
+
+import (
+	"context"
+	"slices"
+
+	resultv1 "github.com/yoyo1025/k8s-vote-platform/gen/go/result/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// publishChanged tells result readers which elections' totals changed, one
+// result.v1.TotalsChanged message per tenant, so subscribers of other
+// elections are not woken. Failures are logged: readers also refresh on
+// their own heartbeat.
+func (p *Processor) publishChanged(ctx context.Context, increments map[TotalsKey]int64) {
+	if len(increments) == 0 || p.cfg.ResultsChannel == "" {
+		return
+	}
+	for _, msg := range changedElections(increments) {
+		b, err := protojson.Marshal(msg)
+		if err != nil {
+			p.log.Printf("encode totals update error: %v", err)
+			continue
+		}
+		if err := p.redis.Publish(ctx, p.cfg.ResultsChannel, string(b)).Err(); err != nil {
+			p.log.Printf("publish totals update error: %v", err)
+		}
+	}
+}
+
+// changedElections groups the elections in increments by tenant, sorted for
+// stable output.
+func changedElections(increments map[TotalsKey]int64) []*resultv1.TotalsChanged {
+	byTenant := make(map[string][]string)
+	for key, inc := range increments {
+		if inc == 0 || slices.Contains(byTenant[key.Tenant], key.Election) {
+			continue
+		}
+		byTenant[key.Tenant] = append(byTenant[key.Tenant], key.Election)
+	}
+
+	tenants := make([]string, 0, len(byTenant))
+	for tenant := range byTenant {
+		tenants = append(tenants, tenant)
+	}
+	slices.Sort(tenants)
+
+	msgs := make([]*resultv1.TotalsChanged, 0, len(tenants))
+	for _, tenant := range tenants {
+		elections := byTenant[tenant]
+		slices.Sort(elections)
+		msgs = append(msgs, &resultv1.TotalsChanged{Tenant: tenant, ElectionIds: elections})
+	}
+	return msgs
+}
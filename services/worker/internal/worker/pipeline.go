@@ -0,0 +1,258 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Partition keys for Config.PartitionBy.
+const (
+	PartitionByCandidate = "candidate"
+	PartitionByElection  = "election"
+)
+
+// pipelineJob is the share of one read batch assigned to a processor.
+type pipelineJob struct {
+	seq     uint64
+	entries []voteEntry
+}
+
+// pipelineRegistration tells the acker how many shares a batch was split into.
+type pipelineRegistration struct {
+	seq   uint64
+	parts int
+}
+
+type pipelineResult struct {
+	seq    uint64
+	result batchResult
+	err    error
+}
+
+// pipelineBatch tracks a read batch until every partition has committed.
+type pipelineBatch struct {
+	parts      int
+	registered bool
+	done       int
+	result     batchResult
+}
+
+func (b *pipelineBatch) complete() bool {
+	return b.registered && b.done == b.parts
+}
+
+// Run blocks until the context is cancelled or a fatal error occurs.
+//
+// A reader goroutine pulls batches from the queue and splits each one across
+// Concurrency processors by partition key; each processor commits its share
+// in its own transaction. An acker acknowledges batches strictly in read
+// order once all of their shares have committed. When ctx is cancelled the
+// reader stops and Run returns only after in-flight batches have been
+// committed and acknowledged, so Close can follow immediately.
+func (p *Processor) Run(ctx context.Context) error {
+	n := p.cfg.Concurrency
+	if n <= 0 {
+		n = 1
+	}
+	// In-flight work must finish even though ctx is cancelled on shutdown.
+	workCtx := context.WithoutCancel(ctx)
+
+	jobs := make([]chan pipelineJob, n)
+	for i := range jobs {
+		jobs[i] = make(chan pipelineJob, 1)
+	}
+	registrations := make(chan pipelineRegistration, n)
+	results := make(chan pipelineResult, n)
+	// Bounds the number of batches read but not yet acknowledged.
+	slots := make(chan struct{}, 2*n)
+
+	if p.housekeepingEnabled() {
+		go p.runHousekeeping(ctx)
+	}
+	if p.shadow != nil {
+		go p.runShadowReport(ctx)
+	} else if p.cfg.SnapshotInterval > 0 {
+		go p.runSnapshots(ctx)
+	}
+
+	var workers sync.WaitGroup
+	for i := range jobs {
+		workers.Add(1)
+		go func(jobs <-chan pipelineJob) {
+			defer workers.Done()
+			for job := range jobs {
+				result, err := p.commitBatch(workCtx, job.entries)
+				if err != nil {
+					p.log.Printf("process batch error: %v", err)
+					time.Sleep(time.Second)
+				}
+				results <- pipelineResult{seq: job.seq, result: result, err: err}
+			}
+		}(jobs[i])
+	}
+
+	go func() {
+		defer func() {
+			for _, ch := range jobs {
+				close(ch)
+			}
+			close(registrations)
+		}()
+		p.readLoop(ctx, func(entries []voteEntry, seq uint64) bool {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return false
+			}
+			parts := p.partition(entries, n)
+			sent := 0
+			for _, part := range parts {
+				if len(part) > 0 {
+					sent++
+				}
+			}
+			registrations <- pipelineRegistration{seq: seq, parts: sent}
+			for i, part := range parts {
+				if len(part) > 0 {
+					jobs[i] <- pipelineJob{seq: seq, entries: part}
+				}
+			}
+			return true
+		})
+	}()
+
+	go func() {
+		workers.Wait()
+		close(results)
+	}()
+
+	p.ackInOrder(workCtx, registrations, results, slots)
+	return nil
+}
+
+// readLoop feeds read and claimed batches to dispatch until ctx is cancelled
+// or dispatch refuses a batch.
+func (p *Processor) readLoop(ctx context.Context, dispatch func([]voteEntry, uint64) bool) {
+	var seq uint64
+	for ctx.Err() == nil {
+		entries, err := p.readBatch(ctx)
+		if err != nil {
+			if errors.Is(err, context.Canceled) || ctx.Err() != nil {
+				return
+			}
+			p.log.Printf("read batch error: %v", err)
+			time.Sleep(time.Second)
+			continue
+		}
+
+		if len(entries) == 0 {
+			if p.cfg.IdleTimeout <= 0 || time.Since(p.lastClaim) < p.cfg.IdleTimeout {
+				continue
+			}
+			entries, err = p.claimIdle(ctx)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				p.log.Printf("claim idle error: %v", err)
+				time.Sleep(time.Second)
+				continue
+			}
+			if len(entries) == 0 {
+				continue
+			}
+		}
+
+		if !dispatch(entries, seq) {
+			return
+		}
+		seq++
+	}
+}
+
+// partition splits entries into n shares by partition key, preserving the
+// read order within each share.
+func (p *Processor) partition(entries []voteEntry, n int) [][]voteEntry {
+	parts := make([][]voteEntry, n)
+	if n == 1 {
+		parts[0] = entries
+		return parts
+	}
+	for _, e := range entries {
+		i := p.partitionKey(e) % uint32(n)
+		parts[i] = append(parts[i], e)
+	}
+	return parts
+}
+
+func (p *Processor) partitionKey(e voteEntry) uint32 {
+	h := fnv.New32a()
+	// Keys are only unique within a tenant.
+	h.Write([]byte(e.tenantID))
+	h.Write([]byte{0})
+	if p.cfg.PartitionBy == PartitionByElection {
+		h.Write([]byte(e.electionID))
+	} else {
+		h.Write([]byte(strconv.FormatInt(e.candidateID, 10)))
+	}
+	return h.Sum32()
+}
+
+// ackInOrder acknowledges batches in the order they were read once all of
+// their shares have been committed. Shares that failed are left pending and
+// are redelivered after IdleTimeout.
+func (p *Processor) ackInOrder(ctx context.Context, registrations <-chan pipelineRegistration, results <-chan pipelineResult, slots <-chan struct{}) {
+	batches := make(map[uint64]*pipelineBatch)
+	get := func(seq uint64) *pipelineBatch {
+		b, ok := batches[seq]
+		if !ok {
+			b = &pipelineBatch{result: batchResult{increments: make(map[TotalsKey]int64)}}
+			batches[seq] = b
+		}
+		return b
+	}
+
+	var next uint64
+	for registrations != nil || results != nil {
+		select {
+		case reg, ok := <-registrations:
+			if !ok {
+				registrations = nil
+				continue
+			}
+			b := get(reg.seq)
+			b.registered = true
+			b.parts = reg.parts
+		case res, ok := <-results:
+			if !ok {
+				results = nil
+				continue
+			}
+			b := get(res.seq)
+			b.done++
+			if res.err == nil {
+				b.result.ackIDs = append(b.result.ackIDs, res.result.ackIDs...)
+				for key, inc := range res.result.increments {
+					b.result.increments[key] += inc
+				}
+			}
+		}
+
+		for {
+			b, ok := batches[next]
+			if !ok || !b.complete() {
+				break
+			}
+			if err := p.finishBatch(ctx, b.result); err != nil {
+				p.log.Printf("finish batch error: %v", err)
+			}
+			delete(batches, next)
+			<-slots
+			next++
+		}
+	}
+}
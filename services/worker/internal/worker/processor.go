@@ -1,18 +1,22 @@
 package worker
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
 	"log"
+	"maps"
 	"os"
+	"slices"
 	"strconv"
-	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/redis/go-redis/v9"
+	"github.com/yoyo1025/k8s-vote-platform/pkg/health"
+	"github.com/yoyo1025/k8s-vote-platform/pkg/queue"
 )
 
 // Config describes the external dependencies and runtime configuration of the worker.
@@ -25,12 +29,45 @@ type Config struct {
 	RedisConsumer  string
 	ResultsChannel string
 
+	// QueueBackend selects the vote transport: "redis" (default), "nats" or
+	// "memory". "memory" only makes sense together with Queue. Redis is
+	// still required for publishing totals updates.
+	QueueBackend string
+	NATS         queue.NATSConfig
+	// Queue overrides QueueBackend with a ready consumer, e.g. a shared
+	// in-memory queue when running alongside vote-api in one process.
+	Queue queue.Consumer
+
 	BatchSize     int
 	BlockInterval time.Duration
 	IdleTimeout   time.Duration
 
+	// Concurrency is the number of batch processors fed by the reader.
+	// Entries are partitioned across them by PartitionBy ("candidate" or
+	// "election") so that votes for the same key are applied in order.
+	Concurrency int
+	PartitionBy string
+
+	// HandoffPending moves entries still pending on this consumer to a peer
+	// on shutdown so the consumer can be removed from the group.
+	HandoffPending bool
+
+	// Housekeeping prunes the Redis consumer group and stream.
+	Housekeeping HousekeepingConfig
+
+	// SnapshotInterval is how often totals are copied into
+	// totals_snapshots; zero disables periodic snapshots.
+	SnapshotInterval time.Duration
+
+	// Shadow runs the processor against a shadow schema and compares its
+	// outcomes with the primary worker instead of updating live totals.
+	Shadow ShadowConfig
+
 	PGConnString   string
 	TotalsBucketID int
+
+	// ReadinessTimeout bounds each dependency check performed by /readyz.
+	ReadinessTimeout time.Duration
 }
 
 // GenerateConsumerID returns a best-effort unique consumer identifier.
@@ -42,13 +79,25 @@ func GenerateConsumerID() string {
 	return fmt.Sprintf("%s-%d", host, time.Now().UnixNano())
 }
 
+// txBeginner is the part of pgxpool.Pool used to run batches; tests
+// substitute an in-memory implementation.
+type txBeginner interface {
+	BeginTx(ctx context.Context, opts pgx.TxOptions) (pgx.Tx, error)
+}
+
 // Processor consumes votes from Redis Streams and persists them to PostgreSQL.
 type Processor struct {
 	cfg       Config
 	log       *log.Logger
 	redis     *redis.Client
+	queue     queue.Consumer
 	pg        *pgxpool.Pool
+	db        txBeginner
+	shadow    *shadow
 	lastClaim time.Time
+	// recompute is set by Replay in recompute mode: entries already recorded
+	// in processed_events are applied again unless their vote exists.
+	recompute bool
 }
 
 // NewProcessor validates connectivity and ensures the consumer group exists.
@@ -80,6 +129,33 @@ func NewProcessor(ctx context.Context, cfg Config) (*Processor, error) {
 	if cfg.ResultsChannel == "" {
 		cfg.ResultsChannel = "results:totals"
 	}
+	if cfg.ReadinessTimeout <= 0 {
+		cfg.ReadinessTimeout = health.DefaultTimeout
+	}
+	if cfg.QueueBackend == "" {
+		cfg.QueueBackend = queue.BackendRedis
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 1
+	}
+	switch cfg.PartitionBy {
+	case "":
+		cfg.PartitionBy = PartitionByCandidate
+	case PartitionByCandidate, PartitionByElection:
+	default:
+		return nil, fmt.Errorf("unknown partition key %q", cfg.PartitionBy)
+	}
+	if cfg.Shadow.Enabled {
+		cfg.Shadow.setDefaults(cfg.RedisGroup)
+		if cfg.Shadow.Group == cfg.RedisGroup {
+			return nil, fmt.Errorf("shadow group must differ from the primary group %q", cfg.RedisGroup)
+		}
+		// From here on the shadow worker reads, acknowledges and reports
+		// health in its own group only.
+		cfg.RedisGroup = cfg.Shadow.Group
+		// Shadow workers must not make result readers refresh.
+		cfg.ResultsChannel = ""
+	}
 
 	logger := log.New(os.Stdout, "[worker] ", log.LstdFlags|log.Lmicroseconds|log.Lmsgprefix)
 
@@ -93,77 +169,116 @@ func NewProcessor(ctx context.Context, cfg Config) (*Processor, error) {
 		return nil, fmt.Errorf("redis ping: %w", err)
 	}
 
-	// Ensure the consumer group exists.
-	if err := ensureConsumerGroup(ctx, rdb, cfg.RedisStream, cfg.RedisGroup); err != nil {
-		return nil, err
+	if cfg.Shadow.Enabled && cfg.QueueBackend == queue.BackendRedis && cfg.Queue == nil {
+		if err := ensureShadowGroup(ctx, rdb, cfg.RedisStream, cfg.RedisGroup); err != nil {
+			return nil, err
+		}
 	}
 
-	pool, err := pgxpool.New(ctx, cfg.PGConnString)
+	consumer, err := newConsumer(ctx, cfg, rdb)
 	if err != nil {
-		return nil, fmt.Errorf("pg connect: %w", err)
+		return nil, err
 	}
-	if err := pool.Ping(ctx); err != nil {
-		pool.Close()
-		return nil, fmt.Errorf("pg ping: %w", err)
+
+	pool, err := newPool(ctx, cfg)
+	if err != nil {
+		_ = consumer.Close()
+		return nil, err
 	}
 
-	return &Processor{
+	p := &Processor{
 		cfg:       cfg,
 		log:       logger,
 		redis:     rdb,
+		queue:     consumer,
 		pg:        pool,
+		db:        pool,
 		lastClaim: time.Now(),
-	}, nil
+	}
+	if cfg.Shadow.Enabled {
+		p.shadow = newShadow(cfg.Shadow)
+	}
+	return p, nil
 }
 
-// Run blocks until the context is cancelled or a fatal error occurs.
-func (p *Processor) Run(ctx context.Context) error {
-	for {
-		select {
-		case <-ctx.Done():
-			return nil
-		default:
-		}
+// newPool connects to Postgres. In shadow mode every connection resolves
+// unqualified table names in the shadow schema only.
+func newPool(ctx context.Context, cfg Config) (*pgxpool.Pool, error) {
+	pcfg, err := pgxpool.ParseConfig(cfg.PGConnString)
+	if err != nil {
+		return nil, fmt.Errorf("pg config: %w", err)
+	}
+	if cfg.Shadow.Enabled {
+		pcfg.ConnConfig.RuntimeParams["search_path"] = cfg.Shadow.Schema
+	}
 
-		entries, err := p.readBatch(ctx)
-		if err != nil {
-			if errors.Is(err, context.Canceled) {
-				return nil
-			}
-			p.log.Printf("read batch error: %v", err)
-			time.Sleep(time.Second)
-			continue
+	pool, err := pgxpool.NewWithConfig(ctx, pcfg)
+	if err != nil {
+		return nil, fmt.Errorf("pg connect: %w", err)
+	}
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("pg ping: %w", err)
+	}
+	if cfg.Shadow.Enabled {
+		if err := checkShadowSchema(ctx, pool, cfg.Shadow.Schema); err != nil {
+			pool.Close()
+			return nil, err
 		}
+	}
+	return pool, nil
+}
 
-		if len(entries) == 0 {
-			if p.cfg.IdleTimeout > 0 && time.Since(p.lastClaim) >= p.cfg.IdleTimeout {
-				claimed, err := p.claimIdle(ctx)
-				if err != nil {
-					p.log.Printf("claim idle error: %v", err)
-					time.Sleep(time.Second)
-					continue
-				}
-				if len(claimed) == 0 {
-					continue
-				}
-				if err := p.processBatch(ctx, claimed); err != nil {
-					p.log.Printf("process claimed batch error: %v", err)
-					time.Sleep(time.Second)
-				}
-			}
-			continue
+func newConsumer(ctx context.Context, cfg Config, rdb *redis.Client) (queue.Consumer, error) {
+	if cfg.Queue != nil {
+		return cfg.Queue, nil
+	}
+	switch cfg.QueueBackend {
+	case queue.BackendRedis:
+		// Ensures the consumer group exists.
+		return queue.NewRedisConsumer(ctx, rdb, cfg.RedisStream, cfg.RedisGroup, cfg.RedisConsumer)
+	case queue.BackendNATS:
+		// Unacknowledged messages are redelivered by JetStream after
+		// IdleTimeout, mirroring XAUTOCLAIM on Redis.
+		ackWait := cfg.IdleTimeout
+		if ackWait <= 0 {
+			ackWait = 30 * time.Second
 		}
+		return queue.NewNATSConsumer(ctx, cfg.NATS, cfg.RedisGroup, ackWait)
+	case queue.BackendMemory:
+		return queue.NewMemory(), nil
+	default:
+		return nil, fmt.Errorf("unknown queue backend %q", cfg.QueueBackend)
+	}
+}
 
-		if err := p.processBatch(ctx, entries); err != nil {
-			p.log.Printf("process batch error: %v", err)
-			time.Sleep(time.Second)
-			continue
-		}
+// Release removes the consumer from its group after Run has drained. When
+// entries are still pending they are handed to a peer if HandoffPending is
+// set; otherwise the consumer is kept so the entries can be reclaimed.
+func (p *Processor) Release(ctx context.Context) {
+	r, ok := p.queue.(queue.Releaser)
+	if !ok {
+		return
+	}
+	remaining, err := r.Release(ctx, p.cfg.HandoffPending)
+	if err != nil {
+		p.log.Printf("release consumer error: %v", err)
+		return
+	}
+	if remaining > 0 {
+		p.log.Printf("keeping consumer %s: %d entries still pending", p.cfg.RedisConsumer, remaining)
+		return
 	}
+	p.log.Printf("consumer %s removed from group %s", p.cfg.RedisConsumer, p.cfg.RedisGroup)
 }
 
 // Close releases external resources held by the processor.
 func (p *Processor) Close() {
+	if p.queue != nil {
+		if err := p.queue.Close(); err != nil {
+			p.log.Printf("queue close error: %v", err)
+		}
+	}
 	if p.pg != nil {
 		p.pg.Close()
 	}
@@ -176,59 +291,68 @@ func (p *Processor) Close() {
 
 type voteEntry struct {
 	id          string
+	eventID     string
+	tenantID    string
+	electionID  string
 	userID      int64
 	candidateID int64
 	votedAt     time.Time
 }
 
-func (p *Processor) readBatch(ctx context.Context) ([]voteEntry, error) {
-	args := &redis.XReadGroupArgs{
-		Group:    p.cfg.RedisGroup,
-		Consumer: p.cfg.RedisConsumer,
-		Streams:  []string{p.cfg.RedisStream, ">"},
-		Count:    int64(p.cfg.BatchSize),
-		Block:    p.cfg.BlockInterval,
+// dedupKey identifies the entry in processed_events. Legacy entries usually
+// carry no event ID, so their stream ID stands in.
+func (e voteEntry) dedupKey() string {
+	if e.eventID != "" {
+		return e.eventID
 	}
+	return "stream:" + e.id
+}
+
+func (e voteEntry) totalsKey() TotalsKey {
+	return TotalsKey{Tenant: e.tenantID, Election: e.electionID, CandidateID: e.candidateID}
+}
 
-	streams, err := p.redis.XReadGroup(ctx, args).Result()
+func (p *Processor) readBatch(ctx context.Context) ([]voteEntry, error) {
+	msgs, err := p.queue.Read(ctx, p.cfg.BatchSize, p.cfg.BlockInterval)
 	if err != nil {
-		if errors.Is(err, context.Canceled) {
-			return nil, err
-		}
-		if errors.Is(err, redis.Nil) {
-			return nil, nil
-		}
-		if strings.Contains(err.Error(), "NOGROUP") {
-			if err := ensureConsumerGroup(ctx, p.redis, p.cfg.RedisStream, p.cfg.RedisGroup); err != nil {
-				return nil, err
-			}
-			return nil, nil
-		}
 		return nil, err
 	}
+	return p.parseMessages(ctx, msgs, "message"), nil
+}
 
+// parseMessages converts queue messages into vote entries, acknowledging
+// malformed ones so they are not redelivered forever.
+func (p *Processor) parseMessages(ctx context.Context, msgs []queue.Message, kind string) []voteEntry {
 	var entries []voteEntry
-	for _, stream := range streams {
-		for _, msg := range stream.Messages {
-			entry, err := parseMessage(msg)
-			if err != nil {
-				p.log.Printf("skip malformed message %s: %v", msg.ID, err)
-				// acknowledge malformed message to avoid infinite loop
-				if ackErr := p.redis.XAck(ctx, p.cfg.RedisStream, p.cfg.RedisGroup, msg.ID).Err(); ackErr != nil {
-					p.log.Printf("failed to ack malformed message %s: %v", msg.ID, ackErr)
-				}
-				continue
+	for _, msg := range msgs {
+		entry, err := parseMessage(msg)
+		if err != nil {
+			p.log.Printf("skip malformed %s %s: %v", kind, msg.ID, err)
+			// acknowledge malformed message to avoid infinite loop
+			if ackErr := p.queue.Ack(ctx, msg.ID); ackErr != nil {
+				p.log.Printf("failed to ack malformed %s %s: %v", kind, msg.ID, ackErr)
 			}
-			entries = append(entries, entry)
+			continue
 		}
+		entries = append(entries, entry)
 	}
+	return entries
+}
 
-	return entries, nil
+// parseMessage decodes both the versioned envelope and the legacy flat
+// fields, so producers can be migrated independently of workers.
+func parseMessage(msg queue.Message) (voteEntry, error) {
+	if _, ok := msg.Values[eventField]; ok {
+		return parseEnvelope(msg)
+	}
+	return parseLegacyMessage(msg)
 }
 
-func parseMessage(msg redis.XMessage) (voteEntry, error) {
+func parseLegacyMessage(msg queue.Message) (voteEntry, error) {
 	var entry voteEntry
 	entry.id = msg.ID
+	entry.tenantID = defaultTenantID
+	entry.electionID = defaultElectionID
 
 	userStr, ok := msg.Values["user_id"]
 	if !ok {
@@ -252,6 +376,18 @@ func parseMessage(msg redis.XMessage) (voteEntry, error) {
 	entry.candidateID = candidateID
 	entry.votedAt = time.Now().UTC()
 
+	if tenant, ok := msg.Values["tenant_id"].(string); ok && tenant != "" {
+		entry.tenantID = tenant
+	}
+	if election, ok := msg.Values["election_id"].(string); ok && election != "" {
+		entry.electionID = election
+	}
+	// Set by producers that may publish the same vote twice, such as the
+	// vote-api outbox relay.
+	if eventID, ok := msg.Values["event_id"].(string); ok && eventID != "" {
+		entry.eventID = eventID
+	}
+
 	if tsVal, ok := msg.Values["ts"]; ok {
 		if tsStr, ok := tsVal.(string); ok {
 			if ts, err := time.Parse(time.RFC3339Nano, tsStr); err == nil {
@@ -279,116 +415,215 @@ func parseInt64(v any) (int64, error) {
 }
 
 func (p *Processor) processBatch(ctx context.Context, entries []voteEntry) error {
-	tx, err := p.pg.BeginTx(ctx, pgx.TxOptions{})
+	result, err := p.commitBatch(ctx, entries)
 	if err != nil {
 		return err
 	}
+	return p.finishBatch(ctx, result)
+}
+
+// commitBatch applies entries in a single transaction without acknowledging
+// them.
+func (p *Processor) commitBatch(ctx context.Context, entries []voteEntry) (batchResult, error) {
+	tx, err := p.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return batchResult{}, err
+	}
 	defer tx.Rollback(ctx)
 
-	increments := make(map[int64]int64)
-	ackIDs := make([]string, 0, len(entries))
+	result, err := p.applyBatch(ctx, tx, entries)
+	if err != nil {
+		return batchResult{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return batchResult{}, fmt.Errorf("commit batch: %w", err)
+	}
+	if p.shadow != nil {
+		p.shadow.record(entries, result, time.Now())
+	}
+	return result, nil
+}
+
+// finishBatch acknowledges a committed batch and notifies result readers.
+func (p *Processor) finishBatch(ctx context.Context, result batchResult) error {
+	// A crash here leaves the batch committed but unacknowledged. The
+	// redelivered entries are then skipped via processed_events.
+	if len(result.ackIDs) > 0 {
+		if err := p.queue.Ack(ctx, result.ackIDs...); err != nil {
+			return fmt.Errorf("ack messages: %w", err)
+		}
+	}
+
+	p.publishChanged(ctx, result.increments)
+	return nil
+}
+
+// timeSeriesResolutions are the bucket sizes maintained in totals_timeseries.
+var timeSeriesResolutions = []struct {
+	name string
+	size time.Duration
+}{
+	{"minute", time.Minute},
+	{"hour", time.Hour},
+}
+
+type timeSeriesKey struct {
+	resolution  string
+	electionID  string
+	candidateID int64
+	bucketStart time.Time
+}
+
+func (k timeSeriesKey) compare(o timeSeriesKey) int {
+	return cmp.Or(
+		cmp.Compare(k.electionID, o.electionID),
+		cmp.Compare(k.resolution, o.resolution),
+		cmp.Compare(k.candidateID, o.candidateID),
+		k.bucketStart.Compare(o.bucketStart),
+	)
+}
+
+type batchResult struct {
+	increments map[TotalsKey]int64
+	ackIDs     []string
+	// applied lists the dedup keys of entries that changed totals.
+	applied []string
+	// reapplied counts applied entries that were already recorded in
+	// processed_events (recompute only).
+	reapplied int
+}
+
+// applyBatch records each entry in processed_events and applies the ones not
+// seen before, all within tx. Entries already recorded by an earlier
+// (possibly crashed) delivery are acknowledged without touching totals.
+//
+// Entries are applied tenant by tenant so that every write happens with
+// app.tenant set to the row's tenant, as required by row-level security.
+// Rows shared between concurrent batches (tenants, ballots, totals, series
+// and meta) are written in key order, so two partitions touching the same
+// rows lock them in the same order instead of deadlocking.
+func (p *Processor) applyBatch(ctx context.Context, tx pgx.Tx, entries []voteEntry) (batchResult, error) {
+	result := batchResult{
+		increments: make(map[TotalsKey]int64),
+		ackIDs:     make([]string, 0, len(entries)),
+	}
+
+	for _, group := range groupByTenant(entries) {
+		if err := p.applyTenant(ctx, tx, group.tenantID, group.entries, &result); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+func (p *Processor) applyTenant(ctx context.Context, tx pgx.Tx, tenantID string, entries []voteEntry, result *batchResult) error {
+	if err := setTenant(ctx, tx, tenantID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO tenants (id) VALUES ($1)
+		ON CONFLICT (id) DO NOTHING
+	`, tenantID); err != nil {
+		return fmt.Errorf("register tenant %s: %w", tenantID, err)
+	}
+
+	increments := make(map[TotalsKey]int64)
+	series := make(map[timeSeriesKey]int64)
+	meta := make(map[string]*electionMeta)
+	voters := make(map[ballotKey]struct{})
 
 	for _, entry := range entries {
+		result.ackIDs = append(result.ackIDs, entry.id)
+
 		tag, err := tx.Exec(ctx, `
-			INSERT INTO votes (user_id, candidate_id, voted_at)
-			VALUES ($1, $2, $3)
-			ON CONFLICT (user_id, candidate_id) DO NOTHING
-		`, entry.userID, entry.candidateID, entry.votedAt)
+			INSERT INTO processed_events (event_id, stream_id)
+			VALUES ($1, $2)
+			ON CONFLICT (event_id) DO NOTHING
+		`, entry.dedupKey(), entry.id)
+		if err != nil {
+			return fmt.Errorf("record event %s: %w", entry.id, err)
+		}
+		recorded := tag.RowsAffected() == 0
+		if recorded && !p.recompute {
+			continue
+		}
+
+		tag, err = tx.Exec(ctx, `
+			INSERT INTO votes (tenant_id, election_id, user_id, candidate_id, voted_at)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (tenant_id, election_id, user_id, candidate_id) DO NOTHING
+		`, tenantID, entry.electionID, entry.userID, entry.candidateID, entry.votedAt)
 		if err != nil {
 			return fmt.Errorf("insert vote %s: %w", entry.id, err)
 		}
 		if tag.RowsAffected() == 0 {
-			ackIDs = append(ackIDs, entry.id)
 			continue
 		}
 
-		increments[entry.candidateID]++
-		ackIDs = append(ackIDs, entry.id)
-	}
-
-	for candidateID, inc := range increments {
 		if _, err := tx.Exec(ctx, `
-			INSERT INTO totals_sharded (candidate_id, bucket, cnt)
-			VALUES ($1, $2, $3)
-			ON CONFLICT (candidate_id, bucket)
-			DO UPDATE SET cnt = totals_sharded.cnt + EXCLUDED.cnt
-		`, candidateID, p.cfg.TotalsBucketID, inc); err != nil {
-			return fmt.Errorf("update totals candidate %d: %w", candidateID, err)
+			UPDATE processed_events SET applied = TRUE WHERE event_id = $1
+		`, entry.dedupKey()); err != nil {
+			return fmt.Errorf("mark event %s applied: %w", entry.id, err)
+		}
+		increments[entry.totalsKey()]++
+		result.increments[entry.totalsKey()]++
+		result.applied = append(result.applied, entry.dedupKey())
+		if recorded {
+			result.reapplied++
+		}
+		if meta[entry.electionID] == nil {
+			meta[entry.electionID] = &electionMeta{}
+		}
+		meta[entry.electionID].add(entry)
+		voters[ballotKey{entry.electionID, entry.userID}] = struct{}{}
+		for _, res := range timeSeriesResolutions {
+			series[timeSeriesKey{res.name, entry.electionID, entry.candidateID, entry.votedAt.UTC().Truncate(res.size)}]++
 		}
 	}
 
-	if err := tx.Commit(ctx); err != nil {
-		return fmt.Errorf("commit batch: %w", err)
+	if err := recordBallots(ctx, tx, tenantID, voters, meta); err != nil {
+		return err
 	}
 
-	if len(ackIDs) > 0 {
-		if err := p.redis.XAck(ctx, p.cfg.RedisStream, p.cfg.RedisGroup, ackIDs...).Err(); err != nil {
-			return fmt.Errorf("ack messages: %w", err)
+	for _, key := range slices.SortedFunc(maps.Keys(increments), TotalsKey.compare) {
+		inc := increments[key]
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO totals_sharded (tenant_id, election_id, candidate_id, bucket, cnt)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (tenant_id, election_id, candidate_id, bucket)
+			DO UPDATE SET cnt = totals_sharded.cnt + EXCLUDED.cnt
+		`, tenantID, key.Election, key.CandidateID, p.cfg.TotalsBucketID, inc); err != nil {
+			return fmt.Errorf("update totals election %s candidate %d: %w", key.Election, key.CandidateID, err)
 		}
 	}
 
-	if len(increments) > 0 && p.cfg.ResultsChannel != "" {
-		if err := p.redis.Publish(ctx, p.cfg.ResultsChannel, "refresh").Err(); err != nil {
-			p.log.Printf("publish totals update error: %v", err)
+	for _, key := range slices.SortedFunc(maps.Keys(series), timeSeriesKey.compare) {
+		inc := series[key]
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO totals_timeseries (tenant_id, election_id, resolution, candidate_id, bucket_start, cnt)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (tenant_id, election_id, resolution, candidate_id, bucket_start)
+			DO UPDATE SET cnt = totals_timeseries.cnt + EXCLUDED.cnt
+		`, tenantID, key.electionID, key.resolution, key.candidateID, key.bucketStart, inc); err != nil {
+			return fmt.Errorf("update %s series election %s candidate %d: %w", key.resolution, key.electionID, key.candidateID, err)
 		}
 	}
 
-	return nil
-}
-
-func ensureConsumerGroup(ctx context.Context, rdb *redis.Client, stream, group string) error {
-	if err := rdb.XGroupCreateMkStream(ctx, stream, group, "0").Err(); err != nil {
-		if strings.Contains(err.Error(), "BUSYGROUP") {
-			return nil
+	for _, electionID := range slices.Sorted(maps.Keys(meta)) {
+		if err := upsertMeta(ctx, tx, tenantID, electionID, meta[electionID]); err != nil {
+			return err
 		}
-		return fmt.Errorf("create group %s: %w", group, err)
 	}
+
 	return nil
 }
 
 func (p *Processor) claimIdle(ctx context.Context) ([]voteEntry, error) {
-	start := "0-0"
-	var claimed []voteEntry
-
-	for {
-		msgs, next, err := p.redis.XAutoClaim(ctx, &redis.XAutoClaimArgs{
-			Stream:   p.cfg.RedisStream,
-			Group:    p.cfg.RedisGroup,
-			Consumer: p.cfg.RedisConsumer,
-			MinIdle:  p.cfg.IdleTimeout,
-			Start:    start,
-			Count:    int64(p.cfg.BatchSize),
-		}).Result()
-		if err != nil {
-			if errors.Is(err, redis.Nil) {
-				break
-			}
-			return nil, err
-		}
-
-		start = next
-
-		if len(msgs) == 0 {
-			break
-		}
-
-		for _, msg := range msgs {
-			entry, err := parseMessage(msg)
-			if err != nil {
-				p.log.Printf("skip malformed claimed message %s: %v", msg.ID, err)
-				if ackErr := p.redis.XAck(ctx, p.cfg.RedisStream, p.cfg.RedisGroup, msg.ID).Err(); ackErr != nil {
-					p.log.Printf("failed to ack malformed claimed message %s: %v", msg.ID, ackErr)
-				}
-				continue
-			}
-			claimed = append(claimed, entry)
-		}
-
-		if next == "0-0" {
-			break
-		}
+	msgs, err := p.queue.Claim(ctx, p.cfg.IdleTimeout, p.cfg.BatchSize)
+	if err != nil {
+		return nil, err
 	}
-
 	p.lastClaim = time.Now()
-	return claimed, nil
+	return p.parseMessages(ctx, msgs, "claimed message"), nil
 }
@@ -0,0 +1,561 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/yoyo1025/k8s-vote-platform/pkg/queue"
+)
+
+// fakeDB models the tables touched by applyBatch closely enough to exercise
+// deduplication across redeliveries.
+type fakeDB struct {
+	// txMu serialises transactions, since each one works on a snapshot that
+	// replaces the committed state wholesale.
+	txMu      sync.Mutex
+	mu        sync.Mutex
+	processed map[string]bool
+	votes     map[fakeVote]bool
+	totals    map[TotalsKey]int64
+	// ballots mirrors totals_meta.total_ballots per "<tenant>/<election>".
+	ballots map[string]int64
+	// series sums totals_timeseries over all buckets.
+	series map[fakeSeries]int64
+	// voters mirrors the ballots table.
+	voters map[fakeBallot]bool
+	// locks lists the shared rows written by committed transactions, in
+	// write order.
+	locks []string
+	// voteInserts counts committed INSERT INTO votes statements.
+	voteInserts int
+}
+
+func newFakeDB() *fakeDB {
+	return &fakeDB{
+		processed: make(map[string]bool),
+		votes:     make(map[fakeVote]bool),
+		totals:    make(map[TotalsKey]int64),
+		ballots:   make(map[string]int64),
+		series:    make(map[fakeSeries]int64),
+		voters:    make(map[fakeBallot]bool),
+	}
+}
+
+func (db *fakeDB) BeginTx(context.Context, pgx.TxOptions) (pgx.Tx, error) {
+	db.txMu.Lock()
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	tx := &fakeTx{db: db, voteInserts: db.voteInserts,
+		processed: map[string]bool{}, votes: map[fakeVote]bool{}, totals: map[TotalsKey]int64{},
+		ballots: map[string]int64{}, series: map[fakeSeries]int64{},
+		voters: map[fakeBallot]bool{}}
+	for k, v := range db.processed {
+		tx.processed[k] = v
+	}
+	for k, v := range db.votes {
+		tx.votes[k] = v
+	}
+	for k, v := range db.totals {
+		tx.totals[k] = v
+	}
+	for k, v := range db.ballots {
+		tx.ballots[k] = v
+	}
+	for k, v := range db.series {
+		tx.series[k] = v
+	}
+	for k, v := range db.voters {
+		tx.voters[k] = v
+	}
+	return tx, nil
+}
+
+type fakeTx struct {
+	pgx.Tx
+	db          *fakeDB
+	processed   map[string]bool
+	votes       map[fakeVote]bool
+	totals      map[TotalsKey]int64
+	ballots     map[string]int64
+	series      map[fakeSeries]int64
+	voters      map[fakeBallot]bool
+	locks       []string
+	voteInserts int
+	done        bool
+	// tenant mirrors the app.tenant setting of the transaction.
+	tenant string
+}
+
+type fakeVote struct {
+	tenant, election  string
+	user, candidateID int64
+}
+
+type fakeBallot struct {
+	tenant, election string
+	user             int64
+}
+
+type fakeSeries struct {
+	tenant, election, resolution string
+	candidateID                  int64
+}
+
+// total returns the committed total for a candidate of the default tenant
+// and election.
+func (db *fakeDB) total(candidateID int64) int64 {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.totals[TotalsKey{Tenant: defaultTenantID, Election: defaultElectionID, CandidateID: candidateID}]
+}
+
+func (tx *fakeTx) end() {
+	if !tx.done {
+		tx.done = true
+		tx.db.txMu.Unlock()
+	}
+}
+
+func (tx *fakeTx) Exec(_ context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	affected := func(ok bool) (pgconn.CommandTag, error) {
+		if ok {
+			return pgconn.NewCommandTag("INSERT 0 1"), nil
+		}
+		return pgconn.NewCommandTag("INSERT 0 0"), nil
+	}
+	switch {
+	case strings.Contains(sql, "set_config('app.tenant'"):
+		tx.tenant = args[0].(string)
+		return pgconn.NewCommandTag("SELECT 1"), nil
+	case strings.Contains(sql, "INSERT INTO tenants"):
+		return affected(true)
+	case strings.Contains(sql, "INSERT INTO processed_events"):
+		id := args[0].(string)
+		if _, ok := tx.processed[id]; ok {
+			return affected(false)
+		}
+		tx.processed[id] = false
+		return affected(true)
+	case strings.Contains(sql, "UPDATE processed_events"):
+		tx.processed[args[0].(string)] = true
+		return pgconn.NewCommandTag("UPDATE 1"), nil
+	case strings.Contains(sql, "INSERT INTO votes"):
+		tx.voteInserts++
+		if args[0].(string) != tx.tenant {
+			return pgconn.CommandTag{}, errors.New("vote written outside its tenant")
+		}
+		key := fakeVote{args[0].(string), args[1].(string), args[2].(int64), args[3].(int64)}
+		if tx.votes[key] {
+			return affected(false)
+		}
+		tx.votes[key] = true
+		return affected(true)
+	case strings.Contains(sql, "INSERT INTO ballots"):
+		tx.locks = append(tx.locks, fmt.Sprintf("ballot %v/%v/%v", args...))
+		key := fakeBallot{args[0].(string), args[1].(string), args[2].(int64)}
+		if tx.voters[key] {
+			return affected(false)
+		}
+		tx.voters[key] = true
+		return affected(true)
+	case strings.Contains(sql, "INSERT INTO totals_sharded"):
+		tx.locks = append(tx.locks, fmt.Sprintf("totals %v/%v/%v", args[:3]...))
+		tx.totals[TotalsKey{args[0].(string), args[1].(string), args[2].(int64)}] += args[4].(int64)
+		return affected(true)
+	case strings.Contains(sql, "INSERT INTO totals_timeseries"):
+		tx.series[fakeSeries{args[0].(string), args[1].(string), args[2].(string), args[3].(int64)}] += args[5].(int64)
+		return affected(true)
+	case strings.Contains(sql, "INSERT INTO totals_meta"):
+		tx.locks = append(tx.locks, fmt.Sprintf("meta %v/%v", args[:2]...))
+		tx.ballots[args[0].(string)+"/"+args[1].(string)] += args[4].(int64)
+		return affected(true)
+	}
+	return pgconn.CommandTag{}, errors.New("unexpected statement: " + sql)
+}
+
+// QueryRow answers the read-only checks made by planBatch.
+func (tx *fakeTx) QueryRow(_ context.Context, sql string, args ...any) pgx.Row {
+	switch {
+	case strings.Contains(sql, "FROM processed_events"):
+		if _, ok := tx.processed[args[0].(string)]; ok {
+			return fakeRow{val: true}
+		}
+		return fakeRow{err: pgx.ErrNoRows}
+	case strings.Contains(sql, "FROM votes"):
+		return fakeRow{val: tx.votes[fakeVote{args[0].(string), args[1].(string), args[2].(int64), args[3].(int64)}]}
+	}
+	return fakeRow{err: errors.New("unexpected query: " + sql)}
+}
+
+type fakeRow struct {
+	val bool
+	err error
+}
+
+func (r fakeRow) Scan(dest ...any) error {
+	if r.err != nil {
+		return r.err
+	}
+	*dest[0].(*bool) = r.val
+	return nil
+}
+
+func (tx *fakeTx) Commit(context.Context) error {
+	defer tx.end()
+	tx.db.mu.Lock()
+	defer tx.db.mu.Unlock()
+	tx.db.processed, tx.db.votes, tx.db.totals, tx.db.ballots = tx.processed, tx.votes, tx.totals, tx.ballots
+	tx.db.series, tx.db.voters = tx.series, tx.voters
+	tx.db.locks = append(tx.db.locks, tx.locks...)
+	tx.db.voteInserts = tx.voteInserts
+	return nil
+}
+
+func (tx *fakeTx) Rollback(context.Context) error {
+	tx.end()
+	return nil
+}
+
+// crashingQueue fails the first Ack, standing in for a worker that dies
+// between committing a batch and acknowledging it.
+type crashingQueue struct {
+	*queue.Memory
+	crashed bool
+}
+
+func (q *crashingQueue) Ack(ctx context.Context, ids ...string) error {
+	if !q.crashed {
+		q.crashed = true
+		return errors.New("worker crashed before ack")
+	}
+	return q.Memory.Ack(ctx, ids...)
+}
+
+func newTestProcessor(q queue.Consumer, db txBeginner) *Processor {
+	return &Processor{
+		cfg:   Config{BatchSize: 10, BlockInterval: 10 * time.Millisecond},
+		log:   log.New(io.Discard, "", 0),
+		queue: q,
+		db:    db,
+	}
+}
+
+func TestProcessBatchSkipsRedeliveryAfterCrashBeforeAck(t *testing.T) {
+	ctx := context.Background()
+	mem := queue.NewMemory()
+	db := newFakeDB()
+
+	mem.Publish(ctx, []map[string]any{
+		{"user_id": "1", "candidate_id": "10"},
+		{"user_id": "2", "candidate_id": "10"},
+		{"user_id": "3", "candidate_id": "20"},
+	})
+
+	crashing := newTestProcessor(&crashingQueue{Memory: mem}, db)
+	entries, err := crashing.readBatch(ctx)
+	if err != nil || len(entries) != 3 {
+		t.Fatalf("read batch: %v (%d entries)", err, len(entries))
+	}
+	if err := crashing.processBatch(ctx, entries); err == nil {
+		t.Fatal("expected ack failure")
+	}
+	if got := db.total(10); got != 2 {
+		t.Fatalf("expected committed total 2 for candidate 10, got %d", got)
+	}
+	if mem.Pending() != 3 {
+		t.Fatalf("expected 3 unacknowledged entries, got %d", mem.Pending())
+	}
+
+	// A healthy worker reclaims the pending entries and must not re-apply them.
+	healthy := newTestProcessor(mem, db)
+	claimed, err := healthy.claimIdle(ctx)
+	if err != nil || len(claimed) != 3 {
+		t.Fatalf("claim idle: %v (%d entries)", err, len(claimed))
+	}
+	if err := healthy.processBatch(ctx, claimed); err != nil {
+		t.Fatalf("process claimed batch: %v", err)
+	}
+
+	// The redelivered events are skipped before the votes table is touched,
+	// so this holds even once votes stop being idempotent on their own.
+	if db.voteInserts != 3 {
+		t.Fatalf("expected no vote inserts on redelivery, got %d total", db.voteInserts)
+	}
+	if got := db.total(10); got != 2 {
+		t.Fatalf("candidate 10 re-applied: got %d, want 2", got)
+	}
+	if got := db.total(20); got != 1 {
+		t.Fatalf("candidate 20 re-applied: got %d, want 1", got)
+	}
+	if mem.Pending() != 0 {
+		t.Fatalf("expected redelivered entries to be acknowledged, %d pending", mem.Pending())
+	}
+	for _, e := range claimed {
+		if applied, ok := db.processed[e.dedupKey()]; !ok || !applied {
+			t.Fatalf("expected %s recorded as applied", e.dedupKey())
+		}
+	}
+}
+
+func TestProcessBatchDeduplicatesWithinBatch(t *testing.T) {
+	ctx := context.Background()
+	db := newFakeDB()
+	p := newTestProcessor(queue.NewMemory(), db)
+
+	entry := voteEntry{id: "1-0", eventID: "evt-1", tenantID: defaultTenantID, electionID: defaultElectionID, userID: 1, candidateID: 10, votedAt: time.Now()}
+	if err := p.processBatch(ctx, []voteEntry{entry, entry}); err != nil {
+		t.Fatalf("process batch: %v", err)
+	}
+	if got := db.total(10); got != 1 {
+		t.Fatalf("expected single increment, got %d", got)
+	}
+}
+
+func TestProcessBatchKeepsTenantsApart(t *testing.T) {
+	ctx := context.Background()
+	db := newFakeDB()
+	p := newTestProcessor(queue.NewMemory(), db)
+
+	// The same user and candidate IDs in two tenants are different votes.
+	entries := []voteEntry{
+		{id: "1-0", eventID: "evt-1", tenantID: "acme", electionID: "e1", userID: 1, candidateID: 10, votedAt: time.Now()},
+		{id: "2-0", eventID: "evt-2", tenantID: "globex", electionID: "e1", userID: 1, candidateID: 10, votedAt: time.Now()},
+		{id: "3-0", eventID: "evt-3", tenantID: "acme", electionID: "e1", userID: 1, candidateID: 10, votedAt: time.Now()},
+	}
+	if err := p.processBatch(ctx, entries); err != nil {
+		t.Fatalf("processBatch: %v", err)
+	}
+	for _, tenant := range []string{"acme", "globex"} {
+		if got := db.totals[TotalsKey{Tenant: tenant, Election: "e1", CandidateID: 10}]; got != 1 {
+			t.Fatalf("tenant %s total = %d, want 1", tenant, got)
+		}
+	}
+}
+
+func TestProcessBatchKeepsTimeSeriesPerElection(t *testing.T) {
+	ctx := context.Background()
+	db := newFakeDB()
+	p := newTestProcessor(queue.NewMemory(), db)
+
+	// The same candidate ID in two elections of a tenant is two series.
+	now := time.Now()
+	entries := []voteEntry{
+		{id: "1-0", eventID: "evt-1", tenantID: "acme", electionID: "e1", userID: 1, candidateID: 10, votedAt: now},
+		{id: "2-0", eventID: "evt-2", tenantID: "acme", electionID: "e2", userID: 1, candidateID: 10, votedAt: now},
+		{id: "3-0", eventID: "evt-3", tenantID: "acme", electionID: "e2", userID: 2, candidateID: 10, votedAt: now},
+	}
+	if err := p.processBatch(ctx, entries); err != nil {
+		t.Fatalf("processBatch: %v", err)
+	}
+	for election, want := range map[string]int64{"e1": 1, "e2": 2} {
+		for _, res := range []string{"minute", "hour"} {
+			if got := db.series[fakeSeries{"acme", election, res, 10}]; got != want {
+				t.Fatalf("%s %s series = %d, want %d", election, res, got, want)
+			}
+		}
+	}
+}
+
+func TestProcessBatchCountsBallotsOncePerVoter(t *testing.T) {
+	ctx := context.Background()
+	db := newFakeDB()
+	p := newTestProcessor(queue.NewMemory(), db)
+
+	vote := func(id string, user, candidate int64) voteEntry {
+		return voteEntry{id: id, eventID: "evt-" + id, tenantID: defaultTenantID, electionID: defaultElectionID,
+			userID: user, candidateID: candidate, votedAt: time.Now()}
+	}
+	// User 1 votes for two candidates, user 2 for one.
+	if err := p.processBatch(ctx, []voteEntry{vote("1-0", 1, 10), vote("2-0", 1, 20)}); err != nil {
+		t.Fatalf("processBatch: %v", err)
+	}
+	if err := p.processBatch(ctx, []voteEntry{vote("3-0", 2, 10), vote("4-0", 1, 30)}); err != nil {
+		t.Fatalf("processBatch: %v", err)
+	}
+	if got := db.ballots[defaultTenantID+"/"+defaultElectionID]; got != 2 {
+		t.Fatalf("ballots = %d, want 2", got)
+	}
+}
+
+func TestProcessBatchWritesSharedRowsInKeyOrder(t *testing.T) {
+	ctx := context.Background()
+	db := newFakeDB()
+	p := newTestProcessor(queue.NewMemory(), db)
+
+	vote := func(id, tenant, election string, user, candidate int64) voteEntry {
+		return voteEntry{id: id, eventID: "evt-" + id, tenantID: tenant, electionID: election,
+			userID: user, candidateID: candidate, votedAt: time.Now()}
+	}
+	err := p.processBatch(ctx, []voteEntry{
+		vote("1-0", "b", "e1", 1, 10),
+		vote("2-0", "a", "e2", 2, 20),
+		vote("3-0", "a", "e1", 3, 20),
+		vote("4-0", "a", "e1", 1, 10),
+	})
+	if err != nil {
+		t.Fatalf("processBatch: %v", err)
+	}
+	want := []string{
+		"ballot a/e1/1", "ballot a/e1/3", "ballot a/e2/2",
+		"totals a/e1/10", "totals a/e1/20", "totals a/e2/20",
+		"meta a/e1", "meta a/e2",
+		"ballot b/e1/1", "totals b/e1/10", "meta b/e1",
+	}
+	if !slices.Equal(db.locks, want) {
+		t.Fatalf("write order = %v, want %v", db.locks, want)
+	}
+}
+
+func TestRunPipelineAppliesAndAcksEveryEntry(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mem := queue.NewMemory()
+	db := newFakeDB()
+	p := newTestProcessor(mem, db)
+	p.cfg.Concurrency = 4
+	p.cfg.PartitionBy = PartitionByCandidate
+
+	const votes = 95
+	var entries []map[string]any
+	for i := 1; i <= votes; i++ {
+		entries = append(entries, map[string]any{
+			"user_id":      strconv.Itoa(i),
+			"candidate_id": strconv.Itoa(i%5 + 1),
+		})
+	}
+	mem.Publish(ctx, entries)
+
+	done := make(chan error, 1)
+	go func() { done <- p.Run(ctx) }()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		db.mu.Lock()
+		applied := db.voteInserts
+		db.mu.Unlock()
+		if applied == votes && mem.Pending() == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("pipeline stalled: %d applied, %d pending", applied, mem.Pending())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("run: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("run did not return after cancel")
+	}
+
+	for c := int64(1); c <= 5; c++ {
+		if got := db.total(c); got != votes/5 {
+			t.Fatalf("candidate %d: got %d, want %d", c, got, votes/5)
+		}
+	}
+}
+
+func TestPartitionKeepsKeyOrder(t *testing.T) {
+	p := newTestProcessor(queue.NewMemory(), newFakeDB())
+	p.cfg.PartitionBy = PartitionByElection
+
+	var entries []voteEntry
+	for i := 0; i < 20; i++ {
+		entries = append(entries, voteEntry{
+			id:         strconv.Itoa(i),
+			electionID: []string{"a", "b", "c"}[i%3],
+		})
+	}
+	seen := make(map[string]int)
+	for i, part := range p.partition(entries, 4) {
+		last := -1
+		for _, e := range part {
+			if prev, ok := seen[e.electionID]; ok && prev != i {
+				t.Fatalf("election %s split across partitions %d and %d", e.electionID, prev, i)
+			}
+			seen[e.electionID] = i
+			n, _ := strconv.Atoi(e.id)
+			if n < last {
+				t.Fatalf("partition %d out of order", i)
+			}
+			last = n
+		}
+	}
+}
+
+func TestShadowReconcile(t *testing.T) {
+	s := newShadow(ShadowConfig{Grace: time.Minute})
+	now := time.Now()
+
+	entries := []voteEntry{
+		{id: "1-0", eventID: "same", tenantID: "t1", electionID: "e1", candidateID: 1},
+		{id: "2-0", eventID: "extra", tenantID: "t1", electionID: "e1", candidateID: 1},
+		{id: "3-0", eventID: "pending", tenantID: "t1", electionID: "e1", candidateID: 2},
+		{id: "4-0", eventID: "lost", tenantID: "t1", electionID: "e1", candidateID: 2},
+	}
+	s.record(entries[:3], batchResult{applied: []string{"same", "extra", "pending"}}, now)
+	s.record(entries[3:], batchResult{applied: []string{"lost"}}, now.Add(-2*time.Minute))
+
+	primary := map[string]bool{"same": true, "extra": false}
+	mismatched := s.reconcile(primary, now)
+	if len(mismatched) != 1 || mismatched[0] != "extra" {
+		t.Fatalf("mismatched = %v, want [extra]", mismatched)
+	}
+
+	r := s.snapshot()
+	if r.Compared != 2 || r.Mismatched != 1 || r.Missing != 1 || r.Waiting != 1 {
+		t.Fatalf("unexpected report %+v", r)
+	}
+	if d := r.Candidates["t1/e1/1"]; d.Shadow != 2 || d.Primary != 1 {
+		t.Fatalf("candidate 1 diff = %+v, want shadow 2 primary 1", *d)
+	}
+	if d := r.Candidates["t1/e1/2"]; d.Shadow != 1 || d.Primary != 0 {
+		t.Fatalf("candidate 2 diff = %+v, want shadow 1 primary 0", *d)
+	}
+}
+
+func TestNewProcessorRejectsShadowInPrimaryGroup(t *testing.T) {
+	_, err := NewProcessor(context.Background(), Config{
+		RedisAddr:    "localhost:0",
+		RedisStream:  "stream:votes",
+		RedisGroup:   "tally",
+		PGConnString: "postgres://localhost/vote",
+		Shadow:       ShadowConfig{Enabled: true, Group: "tally"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "shadow group") {
+		t.Fatalf("err = %v, want shadow group error", err)
+	}
+}
+
+func TestChangedElectionsGroupsByTenant(t *testing.T) {
+	msgs := changedElections(map[TotalsKey]int64{
+		{Tenant: "b", Election: "x", CandidateID: 1}: 1,
+		{Tenant: "a", Election: "y", CandidateID: 1}: 2,
+		{Tenant: "a", Election: "x", CandidateID: 1}: 1,
+		{Tenant: "a", Election: "x", CandidateID: 2}: 1,
+	})
+	if len(msgs) != 2 {
+		t.Fatalf("got %d messages, want 2", len(msgs))
+	}
+	if msgs[0].GetTenant() != "a" || !slices.Equal(msgs[0].GetElectionIds(), []string{"x", "y"}) {
+		t.Fatalf("unexpected first message %v", msgs[0])
+	}
+	if msgs[1].GetTenant() != "b" || !slices.Equal(msgs[1].GetElectionIds(), []string{"x"}) {
+		t.Fatalf("unexpected second message %v", msgs[1])
+	}
+}
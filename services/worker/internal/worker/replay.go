@@ -0,0 +1,245 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+	"github.com/yoyo1025/k8s-vote-platform/pkg/queue"
+)
+
+// ReplayOptions selects the range of the vote stream to reprocess.
+type ReplayOptions struct {
+	// From and To bound the range inclusively. Each accepts a stream ID
+	// ("<ms>-<seq>" or "<ms>"), an RFC 3339 timestamp, or "" for the start
+	// or end of the stream.
+	From string
+	To   string
+	// DryRun reports what the replay would change without writing anything.
+	// Each batch is compared with the outcomes recorded in processed_events
+	// and votes in its own short read-only transaction, so no locks are held
+	// across the range.
+	DryRun bool
+	// Recompute applies entries that processed_events already records, as
+	// long as their vote is missing, instead of skipping them. Use it after
+	// votes and totals were restored from a backup older than the recorded
+	// outcomes.
+	Recompute bool
+}
+
+// ReplayReport summarises a replay.
+type ReplayReport struct {
+	Scanned   int
+	Malformed int
+	// Applied entries changed totals; Skipped ones were already recorded in
+	// processed_events or were duplicate votes.
+	Applied int
+	Skipped int
+	// Reapplied counts the applied entries whose recorded outcome in
+	// processed_events was overridden (Recompute only).
+	Reapplied int
+	// Increments holds the per-candidate change in totals.
+	Increments map[TotalsKey]int64
+}
+
+// Replay reads a range of the Redis stream with XRANGE and runs it through
+// the same dedup and apply logic as the live processor. It does not touch
+// any consumer group, so running workers are unaffected. Each XRANGE batch
+// is committed on its own. Entries that were applied before are skipped via
+// processed_events, which makes a replay safe to repeat.
+func Replay(ctx context.Context, cfg Config, opts ReplayOptions) (*ReplayReport, error) {
+	start, err := replayBound(opts.From, false)
+	if err != nil {
+		return nil, fmt.Errorf("invalid from: %w", err)
+	}
+	end, err := replayBound(opts.To, true)
+	if err != nil {
+		return nil, fmt.Errorf("invalid to: %w", err)
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+
+	rdb := redis.NewClient(&redis.Options{
+		Addr:     cfg.RedisAddr,
+		Username: cfg.RedisUsername,
+		Password: cfg.RedisPassword,
+	})
+	defer rdb.Close()
+
+	pool, err := pgxpool.New(ctx, cfg.PGConnString)
+	if err != nil {
+		return nil, fmt.Errorf("pg connect: %w", err)
+	}
+	defer pool.Close()
+
+	p := &Processor{
+		cfg:       cfg,
+		log:       log.New(os.Stdout, "[replay] ", log.LstdFlags|log.Lmsgprefix),
+		redis:     rdb,
+		pg:        pool,
+		db:        pool,
+		recompute: opts.Recompute,
+	}
+	return p.replay(ctx, start, end, opts.DryRun)
+}
+
+func (p *Processor) replay(ctx context.Context, start, end string, dryRun bool) (*ReplayReport, error) {
+	report := &ReplayReport{Increments: make(map[TotalsKey]int64)}
+	planned := newReplayPlan()
+
+	for {
+		msgs, err := p.redis.XRangeN(ctx, p.cfg.RedisStream, start, end, int64(p.cfg.BatchSize)).Result()
+		if err != nil {
+			return report, fmt.Errorf("xrange: %w", err)
+		}
+		if len(msgs) == 0 {
+			break
+		}
+
+		var entries []voteEntry
+		for _, msg := range msgs {
+			entry, err := parseMessage(queue.Message{ID: msg.ID, Values: msg.Values})
+			if err != nil {
+				p.log.Printf("skip malformed message %s: %v", msg.ID, err)
+				report.Malformed++
+				continue
+			}
+			entries = append(entries, entry)
+		}
+		report.Scanned += len(msgs)
+
+		var result batchResult
+		if dryRun {
+			result, err = p.planBatch(ctx, entries, planned)
+		} else {
+			result, err = p.commitBatch(ctx, entries)
+		}
+		if err != nil {
+			return report, err
+		}
+		for key, inc := range result.increments {
+			report.Increments[key] += inc
+		}
+		report.Applied += len(result.applied)
+		report.Skipped += len(entries) - len(result.applied)
+		report.Reapplied += result.reapplied
+
+		if len(msgs) < p.cfg.BatchSize {
+			break
+		}
+		start = "(" + msgs[len(msgs)-1].ID
+	}
+
+	if !dryRun {
+		p.publishChanged(ctx, report.Increments)
+	}
+	return report, nil
+}
+
+// replayPlan stands in for the writes a dry run skips, so an event or vote
+// repeated within the range is only counted once.
+type replayPlan struct {
+	events map[string]bool
+	votes  map[plannedVote]bool
+}
+
+type plannedVote struct {
+	tenant, election    string
+	userID, candidateID int64
+}
+
+func newReplayPlan() *replayPlan {
+	return &replayPlan{events: make(map[string]bool), votes: make(map[plannedVote]bool)}
+}
+
+// planBatch works out what applyBatch would do with entries by reading the
+// recorded outcomes in a read-only transaction.
+func (p *Processor) planBatch(ctx context.Context, entries []voteEntry, planned *replayPlan) (batchResult, error) {
+	result := batchResult{increments: make(map[TotalsKey]int64)}
+
+	tx, err := p.db.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return result, err
+	}
+	defer tx.Rollback(ctx)
+
+	for _, group := range groupByTenant(entries) {
+		if err := setTenant(ctx, tx, group.tenantID); err != nil {
+			return result, err
+		}
+		for _, entry := range group.entries {
+			if planned.events[entry.dedupKey()] {
+				continue
+			}
+			planned.events[entry.dedupKey()] = true
+
+			var recorded bool
+			err := tx.QueryRow(ctx, `
+				SELECT TRUE FROM processed_events WHERE event_id = $1
+			`, entry.dedupKey()).Scan(&recorded)
+			if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+				return result, fmt.Errorf("check event %s: %w", entry.id, err)
+			}
+			if recorded && !p.recompute {
+				continue
+			}
+
+			vote := plannedVote{group.tenantID, entry.electionID, entry.userID, entry.candidateID}
+			if planned.votes[vote] {
+				continue
+			}
+			var exists bool
+			if err := tx.QueryRow(ctx, `
+				SELECT EXISTS (
+					SELECT 1 FROM votes
+					WHERE tenant_id = $1 AND election_id = $2 AND user_id = $3 AND candidate_id = $4
+				)`, group.tenantID, entry.electionID, entry.userID, entry.candidateID).Scan(&exists); err != nil {
+				return result, fmt.Errorf("check vote %s: %w", entry.id, err)
+			}
+			if exists {
+				continue
+			}
+			planned.votes[vote] = true
+
+			result.increments[entry.totalsKey()]++
+			result.applied = append(result.applied, entry.dedupKey())
+			if recorded {
+				result.reapplied++
+			}
+		}
+	}
+	return result, nil
+}
+
+// replayBound converts a user supplied bound into an XRANGE argument.
+func replayBound(v string, end bool) (string, error) {
+	switch {
+	case v == "" && end:
+		return "+", nil
+	case v == "":
+		return "-", nil
+	}
+	if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+		// A bare millisecond ID covers every sequence number within it.
+		return strconv.FormatInt(t.UnixMilli(), 10), nil
+	}
+	ms, seq, _ := strings.Cut(v, "-")
+	if _, err := strconv.ParseUint(ms, 10, 64); err != nil {
+		return "", errors.New("expected a stream ID or RFC 3339 timestamp")
+	}
+	if seq != "" {
+		if _, err := strconv.ParseUint(seq, 10, 64); err != nil {
+			return "", errors.New("expected a stream ID or RFC 3339 timestamp")
+		}
+	}
+	return v, nil
+}
@@ -0,0 +1,98 @@
+package worker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/yoyo1025/k8s-vote-platform/pkg/queue"
+)
+
+func TestReplayBound(t *testing.T) {
+	cases := []struct {
+		in   string
+		end  bool
+		want string
+	}{
+		{"", false, "-"},
+		{"", true, "+"},
+		{"1700000000000-5", false, "1700000000000-5"},
+		{"1700000000000", true, "1700000000000"},
+		{"2023-11-14T22:13:20Z", false, "1700000000000"},
+	}
+	for _, tc := range cases {
+		got, err := replayBound(tc.in, tc.end)
+		if err != nil || got != tc.want {
+			t.Errorf("replayBound(%q, %v) = %q, %v; want %q", tc.in, tc.end, got, err, tc.want)
+		}
+	}
+	for _, bad := range []string{"yesterday", "17-x", "-1"} {
+		if _, err := replayBound(bad, false); err == nil {
+			t.Errorf("replayBound(%q) accepted an invalid bound", bad)
+		}
+	}
+}
+
+func TestReplay(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	votes := []struct {
+		id              string
+		user, candidate string
+	}{
+		{"1-0", "1", "10"},
+		{"2-0", "2", "10"},
+		{"3-0", "1", "10"}, // duplicate vote
+		{"4-0", "3", "20"},
+	}
+	for _, v := range votes {
+		if err := rdb.XAdd(ctx, &redis.XAddArgs{Stream: "stream:votes", ID: v.id,
+			Values: map[string]any{"user_id": v.user, "candidate_id": v.candidate}}).Err(); err != nil {
+			t.Fatalf("xadd: %v", err)
+		}
+	}
+
+	// 1-0 was applied. 4-0 was recorded, but its vote was lost when votes and
+	// totals were restored from an older backup.
+	db := newFakeDB()
+	db.processed["stream:1-0"] = true
+	db.processed["stream:4-0"] = true
+	db.votes[fakeVote{defaultTenantID, defaultElectionID, 1, 10}] = true
+	db.totals[TotalsKey{defaultTenantID, defaultElectionID, 10}] = 1
+
+	replay := func(dryRun, recompute bool) *ReplayReport {
+		t.Helper()
+		p := newTestProcessor(queue.NewMemory(), db)
+		p.cfg.RedisStream, p.cfg.BatchSize = "stream:votes", 2
+		p.redis, p.recompute = rdb, recompute
+		report, err := p.replay(ctx, "-", "+", dryRun)
+		if err != nil {
+			t.Fatalf("replay: %v", err)
+		}
+		return report
+	}
+	check := func(r *ReplayReport, applied, skipped, reapplied int) {
+		t.Helper()
+		if r.Scanned != 4 || r.Applied != applied || r.Skipped != skipped || r.Reapplied != reapplied {
+			t.Fatalf("report = %+v, want applied %d skipped %d reapplied %d", *r, applied, skipped, reapplied)
+		}
+	}
+
+	check(replay(true, false), 1, 3, 0)
+	check(replay(true, true), 2, 2, 1)
+	if db.voteInserts != 0 || db.total(10) != 1 || db.total(20) != 0 {
+		t.Fatalf("dry run wrote: inserts=%d totals=%d/%d", db.voteInserts, db.total(10), db.total(20))
+	}
+
+	check(replay(false, true), 2, 2, 1)
+	if db.total(10) != 2 || db.total(20) != 1 {
+		t.Fatalf("totals = %d/%d, want 2/1", db.total(10), db.total(20))
+	}
+	// Repeating the replay changes nothing.
+	check(replay(false, true), 0, 4, 0)
+	check(replay(false, false), 0, 4, 0)
+}
@@ -0,0 +1,288 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	defaultShadowSchema         = "shadow"
+	defaultShadowReportInterval = time.Minute
+	defaultShadowGrace          = 5 * time.Minute
+	// maxShadowMismatchLog bounds how many mismatching events are logged
+	// per report.
+	maxShadowMismatchLog = 10
+)
+
+// ShadowConfig enables shadow mode. A shadow worker consumes the stream in
+// its own consumer group and commits into a separate schema holding copies
+// of the worker tables, so it exercises the full validation and dedup path
+// without touching live totals. Its outcome for each event is then compared
+// with what the primary worker recorded in public.processed_events.
+//
+// The shadow group starts at the end of the stream, so votes cast before it
+// was created are unknown to the shadow schema; repeat votes by those users
+// show up as mismatches.
+type ShadowConfig struct {
+	Enabled bool
+	// Group is the shadow consumer group, "<RedisGroup>-shadow" by default.
+	// It must differ from the primary group, or the shadow worker would take
+	// events away from the primary.
+	Group string
+	// Schema holds the shadow copies of the worker tables.
+	Schema string
+	// ReportInterval is how often outcomes are compared and reported.
+	ReportInterval time.Duration
+	// Grace is how long to wait for the primary to record an event before
+	// reporting it as missing.
+	Grace time.Duration
+}
+
+func (c *ShadowConfig) setDefaults(primaryGroup string) {
+	if c.Group == "" {
+		c.Group = primaryGroup + "-shadow"
+	}
+	if c.Schema == "" {
+		c.Schema = defaultShadowSchema
+	}
+	if c.ReportInterval <= 0 {
+		c.ReportInterval = defaultShadowReportInterval
+	}
+	if c.Grace <= 0 {
+		c.Grace = defaultShadowGrace
+	}
+}
+
+// ShadowReport accumulates the comparison between shadow and primary.
+type ShadowReport struct {
+	UpdatedAt time.Time `json:"updated_at"`
+	// Compared events were recorded by both workers; Mismatched ones were
+	// applied by only one of them.
+	Compared   int64 `json:"compared"`
+	Mismatched int64 `json:"mismatched"`
+	// Missing events were processed by the shadow but never recorded by the
+	// primary within the grace period.
+	Missing int64 `json:"missing"`
+	// Waiting events have not been recorded by the primary yet.
+	Waiting int `json:"waiting"`
+	// Candidates is keyed by TotalsKey.String(),
+	// "<tenant>/<election>/<candidate>".
+	Candidates map[string]*ShadowCandidateDiff `json:"candidates"`
+}
+
+// ShadowCandidateDiff counts applied votes per candidate on each side.
+type ShadowCandidateDiff struct {
+	Shadow  int64 `json:"shadow"`
+	Primary int64 `json:"primary"`
+}
+
+type shadowOutcome struct {
+	key     TotalsKey
+	applied bool
+	seenAt  time.Time
+}
+
+type shadow struct {
+	cfg ShadowConfig
+
+	mu       sync.Mutex
+	outcomes map[string]shadowOutcome
+	report   ShadowReport
+}
+
+func newShadow(cfg ShadowConfig) *shadow {
+	return &shadow{
+		cfg:      cfg,
+		outcomes: make(map[string]shadowOutcome),
+		report:   ShadowReport{Candidates: make(map[string]*ShadowCandidateDiff)},
+	}
+}
+
+// record stores the outcome of a committed shadow batch for comparison.
+func (s *shadow) record(entries []voteEntry, result batchResult, now time.Time) {
+	applied := make(map[string]bool, len(result.applied))
+	for _, key := range result.applied {
+		applied[key] = true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, e := range entries {
+		key := e.dedupKey()
+		if _, ok := s.outcomes[key]; ok {
+			continue
+		}
+		s.outcomes[key] = shadowOutcome{key: e.totalsKey(), applied: applied[key], seenAt: now}
+	}
+}
+
+// waiting returns the keys still awaiting comparison.
+func (s *shadow) waiting() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := make([]string, 0, len(s.outcomes))
+	for key := range s.outcomes {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// reconcile compares recorded outcomes against the primary's applied flags
+// and returns the keys of mismatching events.
+func (s *shadow) reconcile(primary map[string]bool, now time.Time) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var mismatched []string
+	for key, out := range s.outcomes {
+		primaryApplied, found := primary[key]
+		if !found && now.Sub(out.seenAt) < s.cfg.Grace {
+			continue
+		}
+		delete(s.outcomes, key)
+
+		diff := s.report.Candidates[out.key.String()]
+		if diff == nil {
+			diff = &ShadowCandidateDiff{}
+			s.report.Candidates[out.key.String()] = diff
+		}
+		if out.applied {
+			diff.Shadow++
+		}
+		if !found {
+			s.report.Missing++
+			continue
+		}
+		s.report.Compared++
+		if primaryApplied {
+			diff.Primary++
+		}
+		if primaryApplied != out.applied {
+			s.report.Mismatched++
+			mismatched = append(mismatched, key)
+		}
+	}
+	s.report.Waiting = len(s.outcomes)
+	s.report.UpdatedAt = now
+	return mismatched
+}
+
+func (s *shadow) snapshot() ShadowReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := s.report
+	r.Candidates = make(map[string]*ShadowCandidateDiff, len(s.report.Candidates))
+	for id, d := range s.report.Candidates {
+		c := *d
+		r.Candidates[id] = &c
+	}
+	return r
+}
+
+func (p *Processor) runShadowReport(ctx context.Context) {
+	ticker := time.NewTicker(p.cfg.Shadow.ReportInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := p.compareShadow(ctx); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			p.log.Printf("shadow compare error: %v", err)
+		}
+	}
+}
+
+func (p *Processor) compareShadow(ctx context.Context) error {
+	keys := p.shadow.waiting()
+	primary := make(map[string]bool, len(keys))
+	if len(keys) > 0 {
+		// Qualified explicitly: the pool's search_path points at the shadow
+		// schema.
+		rows, err := p.pg.Query(ctx, `
+			SELECT event_id, applied
+			FROM public.processed_events
+			WHERE event_id = ANY($1)
+		`, keys)
+		if err != nil {
+			return fmt.Errorf("query primary events: %w", err)
+		}
+		for rows.Next() {
+			var (
+				key     string
+				applied bool
+			)
+			if err := rows.Scan(&key, &applied); err != nil {
+				rows.Close()
+				return fmt.Errorf("scan primary events: %w", err)
+			}
+			primary[key] = applied
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("read primary events: %w", err)
+		}
+	}
+
+	mismatched := p.shadow.reconcile(primary, time.Now())
+	for i, key := range mismatched {
+		if i == maxShadowMismatchLog {
+			p.log.Printf("shadow: %d more mismatches not shown", len(mismatched)-i)
+			break
+		}
+		p.log.Printf("shadow: event %s differs from primary", key)
+	}
+
+	r := p.shadow.snapshot()
+	p.log.Printf("shadow report: compared=%d mismatched=%d missing=%d waiting=%d",
+		r.Compared, r.Mismatched, r.Missing, r.Waiting)
+	for id, d := range r.Candidates {
+		if d.Shadow != d.Primary {
+			p.log.Printf("shadow report: candidate %s shadow=+%d primary=+%d", id, d.Shadow, d.Primary)
+		}
+	}
+	return nil
+}
+
+func (p *Processor) handleShadowReport(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(p.shadow.snapshot())
+}
+
+// ensureShadowGroup creates the shadow consumer group at the end of the
+// stream so it follows live traffic instead of replaying history.
+func ensureShadowGroup(ctx context.Context, rdb *redis.Client, stream, group string) error {
+	if err := rdb.XGroupCreateMkStream(ctx, stream, group, "$").Err(); err != nil {
+		if strings.Contains(err.Error(), "BUSYGROUP") {
+			return nil
+		}
+		return fmt.Errorf("create shadow group %s: %w", group, err)
+	}
+	return nil
+}
+
+func checkShadowSchema(ctx context.Context, pool *pgxpool.Pool, schema string) error {
+	for _, table := range []string{"tenants", "processed_events", "votes", "ballots", "totals_sharded", "totals_timeseries", "totals_meta"} {
+		var exists bool
+		if err := pool.QueryRow(ctx, `SELECT to_regclass($1) IS NOT NULL`, schema+"."+table).Scan(&exists); err != nil {
+			return fmt.Errorf("check shadow schema: %w", err)
+		}
+		if !exists {
+			return fmt.Errorf("shadow table %s.%s does not exist", schema, table)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Snapshot reasons stored in totals_snapshots.reason.
+const (
+	SnapshotPeriodic = "periodic"
+	SnapshotClose    = "close"
+	SnapshotManual   = "manual"
+)
+
+// snapshotLockKey serialises periodic snapshots across workers.
+const snapshotLockKey = 0x766f7465736e6170 // "votesnap"
+
+// errSnapshotNotDue reports that another worker took a recent snapshot.
+var errSnapshotNotDue = errors.New("snapshot not due")
+
+// TakeSnapshot copies the current totals into an immutable snapshot and
+// returns its ID. It connects with cfg.PGConnString and is meant for
+// on-demand snapshots such as the one taken when polls close.
+func TakeSnapshot(ctx context.Context, cfg Config, reason, label string) (int64, error) {
+	switch reason {
+	case SnapshotClose, SnapshotManual:
+	default:
+		return 0, fmt.Errorf("invalid snapshot reason %q", reason)
+	}
+	pool, err := pgxpool.New(ctx, cfg.PGConnString)
+	if err != nil {
+		return 0, fmt.Errorf("pg connect: %w", err)
+	}
+	defer pool.Close()
+	return takeSnapshot(ctx, pool, reason, label, 0)
+}
+
+// takeSnapshot writes a snapshot in one transaction. With minAge > 0 it is
+// skipped, returning errSnapshotNotDue, when a periodic snapshot newer than
+// minAge exists, so workers sharing a timer do not duplicate each other.
+func takeSnapshot(ctx context.Context, db txBeginner, reason, label string, minAge time.Duration) (int64, error) {
+	tx, err := db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	if minAge > 0 {
+		var locked bool
+		if err := tx.QueryRow(ctx, `SELECT pg_try_advisory_xact_lock($1)`, int64(snapshotLockKey)).Scan(&locked); err != nil {
+			return 0, fmt.Errorf("snapshot lock: %w", err)
+		}
+		if !locked {
+			return 0, errSnapshotNotDue
+		}
+		var recent bool
+		if err := tx.QueryRow(ctx, `
+			SELECT EXISTS (
+				SELECT 1 FROM totals_snapshots
+				WHERE reason = $1 AND taken_at > NOW() - make_interval(secs => $2)
+			)`, SnapshotPeriodic, minAge.Seconds()).Scan(&recent); err != nil {
+			return 0, fmt.Errorf("check recent snapshot: %w", err)
+		}
+		if recent {
+			return 0, errSnapshotNotDue
+		}
+	}
+
+	var id int64
+	if err := tx.QueryRow(ctx, `
+		INSERT INTO totals_snapshots (reason, label)
+		VALUES ($1, $2)
+		RETURNING id`, reason, label).Scan(&id); err != nil {
+		return 0, fmt.Errorf("insert snapshot: %w", err)
+	}
+	// Row-level security only shows one tenant's totals at a time, so the
+	// snapshot covers every tenant by switching app.tenant for each.
+	tenants, err := listTenants(ctx, tx)
+	if err != nil {
+		return 0, err
+	}
+	for _, tenantID := range tenants {
+		if err := setTenant(ctx, tx, tenantID); err != nil {
+			return 0, err
+		}
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO totals_snapshot_items (snapshot_id, tenant_id, election_id, candidate_id, count)
+			SELECT $1, tenant_id, election_id, candidate_id, count
+			FROM totals
+			WHERE tenant_id = $2`, id, tenantID); err != nil {
+			return 0, fmt.Errorf("copy totals for tenant %s: %w", tenantID, err)
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("commit snapshot: %w", err)
+	}
+	return id, nil
+}
+
+func (p *Processor) runSnapshots(ctx context.Context) {
+	interval := p.cfg.SnapshotInterval
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		// Leave some slack so timer jitter between workers does not skip a
+		// whole interval.
+		id, err := takeSnapshot(ctx, p.db, SnapshotPeriodic, "", interval*9/10)
+		switch {
+		case errors.Is(err, errSnapshotNotDue):
+		case err != nil:
+			if ctx.Err() != nil {
+				return
+			}
+			p.log.Printf("snapshot error: %v", err)
+		default:
+			p.log.Printf("took totals snapshot %d", id)
+		}
+	}
+}
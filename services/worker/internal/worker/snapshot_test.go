@@ -0,0 +1,283 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// fakeSnapshotDB models the tables read and written by takeSnapshot. Rows
+// written in a transaction become visible when it commits.
+type fakeSnapshotDB struct {
+	mu      sync.Mutex
+	tenants []string
+	totals  map[TotalsKey]int64
+	// lockHeld stands in for another worker holding the advisory lock.
+	lockHeld  bool
+	snapshots []fakeSnapshot
+	items     []fakeSnapshotItem
+}
+
+type fakeSnapshot struct {
+	id      int64
+	reason  string
+	label   string
+	takenAt time.Time
+}
+
+type fakeSnapshotItem struct {
+	snapshotID int64
+	key        TotalsKey
+	count      int64
+}
+
+func newFakeSnapshotDB() *fakeSnapshotDB {
+	return &fakeSnapshotDB{
+		tenants: []string{"acme", defaultTenantID},
+		totals: map[TotalsKey]int64{
+			{Tenant: "acme", Election: "e1", CandidateID: 1}:                       3,
+			{Tenant: "acme", Election: "e2", CandidateID: 2}:                       4,
+			{Tenant: defaultTenantID, Election: defaultElectionID, CandidateID: 1}: 5,
+		},
+	}
+}
+
+func (db *fakeSnapshotDB) BeginTx(context.Context, pgx.TxOptions) (pgx.Tx, error) {
+	return &fakeSnapshotTx{db: db}, nil
+}
+
+func (db *fakeSnapshotDB) committed() ([]fakeSnapshot, []fakeSnapshotItem) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return slices.Clone(db.snapshots), slices.Clone(db.items)
+}
+
+type fakeSnapshotTx struct {
+	pgx.Tx
+	db       *fakeSnapshotDB
+	tenant   string
+	snapshot *fakeSnapshot
+	items    []fakeSnapshotItem
+}
+
+// QueryRow answers the advisory lock, the recent snapshot check and the
+// snapshot insert.
+func (tx *fakeSnapshotTx) QueryRow(_ context.Context, sql string, args ...any) pgx.Row {
+	tx.db.mu.Lock()
+	defer tx.db.mu.Unlock()
+	switch {
+	case strings.Contains(sql, "pg_try_advisory_xact_lock"):
+		return fakeRow{val: !tx.db.lockHeld}
+	case strings.Contains(sql, "FROM totals_snapshots"):
+		since := time.Now().Add(-time.Duration(args[1].(float64) * float64(time.Second)))
+		recent := slices.ContainsFunc(tx.db.snapshots, func(s fakeSnapshot) bool {
+			return s.reason == args[0].(string) && s.takenAt.After(since)
+		})
+		return fakeRow{val: recent}
+	case strings.Contains(sql, "INSERT INTO totals_snapshots"):
+		tx.snapshot = &fakeSnapshot{
+			id:      int64(len(tx.db.snapshots)) + 1,
+			reason:  args[0].(string),
+			label:   args[1].(string),
+			takenAt: time.Now(),
+		}
+		return fakeIDRow{id: tx.snapshot.id}
+	}
+	return fakeRow{err: errors.New("unexpected query: " + sql)}
+}
+
+type fakeIDRow struct{ id int64 }
+
+func (r fakeIDRow) Scan(dest ...any) error {
+	*dest[0].(*int64) = r.id
+	return nil
+}
+
+// Query answers listTenants.
+func (tx *fakeSnapshotTx) Query(_ context.Context, sql string, _ ...any) (pgx.Rows, error) {
+	if !strings.Contains(sql, "FROM tenants") {
+		return nil, errors.New("unexpected query: " + sql)
+	}
+	tx.db.mu.Lock()
+	defer tx.db.mu.Unlock()
+	return &fakeTenantRows{ids: slices.Clone(tx.db.tenants)}, nil
+}
+
+type fakeTenantRows struct {
+	pgx.Rows
+	ids  []string
+	next int
+}
+
+func (r *fakeTenantRows) Next() bool {
+	r.next++
+	return r.next <= len(r.ids)
+}
+
+func (r *fakeTenantRows) Scan(dest ...any) error {
+	*dest[0].(*string) = r.ids[r.next-1]
+	return nil
+}
+
+func (r *fakeTenantRows) Close()     {}
+func (r *fakeTenantRows) Err() error { return nil }
+
+// Exec answers setTenant and the per-tenant copy of totals. Like row-level
+// security, the copy only sees the totals of the tenant set on tx.
+func (tx *fakeSnapshotTx) Exec(_ context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	switch {
+	case strings.Contains(sql, "set_config('app.tenant'"):
+		tx.tenant = args[0].(string)
+		return pgconn.NewCommandTag("SELECT 1"), nil
+	case strings.Contains(sql, "INSERT INTO totals_snapshot_items"):
+		tx.db.mu.Lock()
+		defer tx.db.mu.Unlock()
+		for key, count := range tx.db.totals {
+			if key.Tenant == tx.tenant && key.Tenant == args[1].(string) {
+				tx.items = append(tx.items, fakeSnapshotItem{snapshotID: args[0].(int64), key: key, count: count})
+			}
+		}
+		return pgconn.NewCommandTag("INSERT 0 1"), nil
+	}
+	return pgconn.CommandTag{}, errors.New("unexpected statement: " + sql)
+}
+
+func (tx *fakeSnapshotTx) Commit(context.Context) error {
+	tx.db.mu.Lock()
+	defer tx.db.mu.Unlock()
+	if tx.snapshot != nil {
+		tx.db.snapshots = append(tx.db.snapshots, *tx.snapshot)
+	}
+	tx.db.items = append(tx.db.items, tx.items...)
+	tx.snapshot, tx.items = nil, nil
+	return nil
+}
+
+func (tx *fakeSnapshotTx) Rollback(context.Context) error { return nil }
+
+func TestTakeSnapshotCopiesEveryTenant(t *testing.T) {
+	db := newFakeSnapshotDB()
+
+	id, err := takeSnapshot(context.Background(), db, SnapshotClose, "polls closed", 0)
+	if err != nil {
+		t.Fatalf("takeSnapshot: %v", err)
+	}
+	snapshots, items := db.committed()
+	if len(snapshots) != 1 || snapshots[0].id != id || snapshots[0].reason != SnapshotClose || snapshots[0].label != "polls closed" {
+		t.Fatalf("snapshots = %+v, want one close snapshot with ID %d", snapshots, id)
+	}
+	got := make(map[TotalsKey]int64)
+	for _, item := range items {
+		if item.snapshotID != id {
+			t.Fatalf("item %+v belongs to snapshot %d, want %d", item, item.snapshotID, id)
+		}
+		got[item.key] = item.count
+	}
+	if len(got) != len(db.totals) {
+		t.Fatalf("snapshot items = %v, want the totals of every tenant %v", got, db.totals)
+	}
+	for key, count := range db.totals {
+		if got[key] != count {
+			t.Fatalf("snapshot of %s = %d, want %d", key, got[key], count)
+		}
+	}
+}
+
+func TestTakeSnapshotSkipsWhenNotDue(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name     string
+		lockHeld bool
+		// lastAge is the age of an existing periodic snapshot; zero means none.
+		lastAge time.Duration
+		minAge  time.Duration
+		wantErr error
+	}{
+		{name: "no previous snapshot", minAge: time.Minute},
+		{name: "previous snapshot older than minAge", lastAge: 2 * time.Minute, minAge: time.Minute},
+		{name: "previous snapshot newer than minAge", lastAge: time.Second, minAge: time.Minute, wantErr: errSnapshotNotDue},
+		{name: "lock held by another worker", lockHeld: true, minAge: time.Minute, wantErr: errSnapshotNotDue},
+		{name: "on demand ignores recent snapshot and lock", lockHeld: true, lastAge: time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newFakeSnapshotDB()
+			db.lockHeld = tt.lockHeld
+			if tt.lastAge > 0 {
+				db.snapshots = append(db.snapshots, fakeSnapshot{id: 1, reason: SnapshotPeriodic, takenAt: time.Now().Add(-tt.lastAge)})
+			}
+			before, _ := db.committed()
+
+			_, err := takeSnapshot(ctx, db, SnapshotPeriodic, "", tt.minAge)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("takeSnapshot error = %v, want %v", err, tt.wantErr)
+			}
+			after, items := db.committed()
+			if tt.wantErr != nil {
+				if len(after) != len(before) || len(items) != 0 {
+					t.Fatalf("skipped snapshot wrote %d snapshots and %d items", len(after)-len(before), len(items))
+				}
+				return
+			}
+			if len(after) != len(before)+1 || len(items) == 0 {
+				t.Fatalf("snapshot not taken: %d snapshots, %d items", len(after), len(items))
+			}
+		})
+	}
+}
+
+func TestRunSnapshotsTakesPeriodicSnapshots(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	db := newFakeSnapshotDB()
+	p := &Processor{
+		cfg: Config{SnapshotInterval: 10 * time.Millisecond},
+		log: log.New(io.Discard, "", 0),
+		db:  db,
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		p.runSnapshots(ctx)
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		snapshots, _ := db.committed()
+		if len(snapshots) > 0 {
+			if snapshots[0].reason != SnapshotPeriodic {
+				t.Fatalf("snapshot reason = %q, want %q", snapshots[0].reason, SnapshotPeriodic)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("no periodic snapshot taken")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("runSnapshots did not stop on cancel")
+	}
+}
+
+func TestTakeSnapshotRejectsReason(t *testing.T) {
+	// The config has no connection string, so an accepted reason would fail
+	// to connect instead.
+	for _, reason := range []string{SnapshotPeriodic, "", "bogus"} {
+		_, err := TakeSnapshot(context.Background(), Config{}, reason, "")
+		if err == nil || !strings.Contains(err.Error(), "invalid snapshot reason") {
+			t.Fatalf("TakeSnapshot(%q) error = %v, want invalid snapshot reason", reason, err)
+		}
+	}
+}
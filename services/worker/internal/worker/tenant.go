@@ -0,0 +1,85 @@
+package worker
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// defaultTenantID is used for events produced before tenants existed.
+const defaultTenantID = "default"
+
+// TotalsKey identifies one candidate's totals within an election.
+type TotalsKey struct {
+	Tenant      string
+	Election    string
+	CandidateID int64
+}
+
+func (k TotalsKey) String() string {
+	return k.Tenant + "/" + k.Election + "/" + strconv.FormatInt(k.CandidateID, 10)
+}
+
+func (k TotalsKey) compare(o TotalsKey) int {
+	return cmp.Or(
+		cmp.Compare(k.Tenant, o.Tenant),
+		cmp.Compare(k.Election, o.Election),
+		cmp.Compare(k.CandidateID, o.CandidateID),
+	)
+}
+
+type tenantEntries struct {
+	tenantID string
+	entries  []voteEntry
+}
+
+// groupByTenant splits entries by tenant, sorting tenants by ID and keeping
+// entries in read order within each tenant.
+func groupByTenant(entries []voteEntry) []tenantEntries {
+	var groups []tenantEntries
+	index := make(map[string]int)
+	for _, e := range entries {
+		i, ok := index[e.tenantID]
+		if !ok {
+			i = len(groups)
+			index[e.tenantID] = i
+			groups = append(groups, tenantEntries{tenantID: e.tenantID})
+		}
+		groups[i].entries = append(groups[i].entries, e)
+	}
+	slices.SortFunc(groups, func(a, b tenantEntries) int { return cmp.Compare(a.tenantID, b.tenantID) })
+	return groups
+}
+
+// setTenant scopes the rest of tx to tenantID for row-level security.
+func setTenant(ctx context.Context, tx pgx.Tx, tenantID string) error {
+	if _, err := tx.Exec(ctx, `SELECT set_config('app.tenant', $1, true)`, tenantID); err != nil {
+		return fmt.Errorf("set tenant %s: %w", tenantID, err)
+	}
+	return nil
+}
+
+func listTenants(ctx context.Context, tx pgx.Tx) ([]string, error) {
+	rows, err := tx.Query(ctx, `SELECT id FROM tenants ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("list tenants: %w", err)
+	}
+	defer rows.Close()
+
+	var tenants []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan tenant: %w", err)
+		}
+		tenants = append(tenants, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list tenants: %w", err)
+	}
+	return tenants, nil
+}